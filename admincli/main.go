@@ -0,0 +1,318 @@
+// admincli/main.go
+//
+// 系統管理命令列工具，與 API 共用資料庫設定 (DATABASE_URL)、模型與密碼政策。
+//
+//	DATABASE_URL=postgres://... go run ./admincli <子命令> [參數]
+//
+// 子命令：
+//
+//	migrate             建立或補齊資料表
+//	seed                建立內建角色，並在尚無公司時建立根公司
+//	create-superadmin   建立第一個 superadmin 帳號
+//	reset-password      重設密碼（同時解除鎖定）
+//	unlock              解除登入失敗鎖定
+//	list-users          列出帳號
+//
+// 密碼可用 -password 指定，或以 -password-stdin 從標準輸入讀取，避免出現在 shell 歷史紀錄。
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wac0705/fastener-api/db"
+	"github.com/wac0705/fastener-api/models"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"migrate", "建立或補齊資料表", runMigrate},
+	{"seed", "建立內建角色，並在尚無公司時建立根公司", runSeed},
+	{"create-superadmin", "建立第一個 superadmin 帳號", runCreateSuperAdmin},
+	{"reset-password", "重設密碼（同時解除鎖定）", runResetPassword},
+	{"unlock", "解除登入失敗鎖定", runUnlock},
+	{"list-users", "列出帳號", runListUsers},
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			db.Init()
+			if err := cmd.run(os.Args[2:]); err != nil {
+				log.Fatalf("❌ %s: %v", cmd.name, err)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: admincli <子命令> [參數]")
+	fmt.Fprintln(os.Stderr, "\n子命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\n使用 admincli <子命令> -h 查看各子命令參數")
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Parse(args)
+
+	if err := db.Migrate(); err != nil {
+		return err
+	}
+	log.Println("✅ 資料表已更新")
+	return nil
+}
+
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	opts := seedFlags(fs)
+	fs.Parse(args)
+
+	if err := db.Seed(*opts); err != nil {
+		return err
+	}
+	log.Println("✅ 參考資料已建立")
+	return nil
+}
+
+func seedFlags(fs *flag.FlagSet) *db.SeedOptions {
+	opts := &db.SeedOptions{}
+	fs.StringVar(&opts.CompanyName, "company-name", "", "尚無公司時建立的根公司名稱")
+	fs.StringVar(&opts.Currency, "currency", "TWD", "根公司預設幣別")
+	fs.StringVar(&opts.Language, "language", "zh-TW", "根公司預設語言")
+	return opts
+}
+
+func runCreateSuperAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-superadmin", flag.ExitOnError)
+	username := fs.String("username", "", "帳號（必填）")
+	companyID := fs.Uint("company-id", 0, "所屬公司 ID，預設為根公司")
+	password, passwordStdin := passwordFlags(fs)
+	opts := seedFlags(fs)
+	fs.Parse(args)
+
+	if *username == "" {
+		return errors.New("請指定 -username")
+	}
+	pw, err := readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashed, err := models.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+
+	// 確保內建角色與根公司存在
+	if err := db.Seed(*opts); err != nil {
+		return err
+	}
+	var role models.Role
+	if err := db.DB.Where("name = ?", models.RoleSuperAdmin).First(&role).Error; err != nil {
+		return fmt.Errorf("找不到 superadmin 角色: %w", err)
+	}
+
+	var existing int64
+	db.DB.Model(&models.User{}).Where("role_id = ?", role.ID).Count(&existing)
+	if existing > 0 {
+		return errors.New("已存在 superadmin 帳號，請改用 reset-password")
+	}
+	db.DB.Model(&models.User{}).Where("username = ?", *username).Count(&existing)
+	if existing > 0 {
+		return fmt.Errorf("帳號 %s 已存在", *username)
+	}
+
+	var company models.Company
+	query := db.DB.Order("id")
+	if *companyID != 0 {
+		query = query.Where("id = ?", *companyID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	if err := query.First(&company).Error; err != nil {
+		return fmt.Errorf("找不到公司: %w", err)
+	}
+
+	user := models.User{
+		Username:     *username,
+		PasswordHash: hashed,
+		RoleID:       role.ID,
+		CompanyID:    company.ID,
+		IsActive:     true,
+	}
+	if err := db.DB.Create(&user).Error; err != nil {
+		return err
+	}
+	log.Printf("✅ 已建立 superadmin %s (ID %d，公司 %s)", user.Username, user.ID, company.Name)
+	return nil
+}
+
+func runResetPassword(args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	username, id := userFlags(fs)
+	password, passwordStdin := passwordFlags(fs)
+	fs.Parse(args)
+
+	user, err := findUser(*username, *id)
+	if err != nil {
+		return err
+	}
+	pw, err := readPassword(*password, *passwordStdin)
+	if err != nil {
+		return err
+	}
+	hashed, err := models.HashPassword(pw)
+	if err != nil {
+		return err
+	}
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"password_hash":      hashed,
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("✅ 已重設 %s (ID %d) 的密碼", user.Username, user.ID)
+	return nil
+}
+
+func runUnlock(args []string) error {
+	fs := flag.NewFlagSet("unlock", flag.ExitOnError)
+	username, id := userFlags(fs)
+	activate := fs.Bool("activate", false, "同時將停用的帳號重新啟用")
+	fs.Parse(args)
+
+	user, err := findUser(*username, *id)
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}
+	if *activate {
+		updates["is_active"] = true
+	}
+	if err := db.DB.Model(&user).Updates(updates).Error; err != nil {
+		return err
+	}
+	log.Printf("✅ 已解除 %s (ID %d) 的鎖定", user.Username, user.ID)
+	return nil
+}
+
+func runListUsers(args []string) error {
+	fs := flag.NewFlagSet("list-users", flag.ExitOnError)
+	companyID := fs.Uint("company-id", 0, "僅列出指定公司的帳號")
+	lockedOnly := fs.Bool("locked", false, "僅列出鎖定中的帳號")
+	fs.Parse(args)
+
+	type row struct {
+		ID          uint
+		Username    string
+		Role        string
+		CompanyID   uint
+		CompanyName string
+		IsActive    bool
+		TOTPEnabled bool
+		LockedUntil *time.Time
+	}
+	query := db.DB.Table("users u").
+		Select(`u.id, u.username, r.name AS role, u.tenant_id AS company_id, c.name AS company_name,
+			u.is_active, u.totp_enabled, u.locked_until`).
+		Joins("LEFT JOIN roles r ON u.role_id = r.id").
+		Joins("LEFT JOIN companies c ON u.tenant_id = c.id").
+		Order("u.id")
+	if *companyID != 0 {
+		query = query.Where("u.tenant_id = ?", *companyID)
+	}
+	if *lockedOnly {
+		query = query.Where("u.locked_until > ?", time.Now())
+	}
+	var rows []row
+	if err := query.Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t帳號\t角色\t公司\t啟用\t2FA\t鎖定至")
+	for _, r := range rows {
+		locked := "-"
+		if r.LockedUntil != nil && r.LockedUntil.After(time.Now()) {
+			locked = r.LockedUntil.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d %s\t%t\t%t\t%s\n",
+			r.ID, r.Username, r.Role, r.CompanyID, r.CompanyName, r.IsActive, r.TOTPEnabled, locked)
+	}
+	return w.Flush()
+}
+
+func userFlags(fs *flag.FlagSet) (*string, *uint) {
+	username := fs.String("username", "", "帳號")
+	id := fs.Uint("id", 0, "使用者 ID（與 -username 擇一）")
+	return username, id
+}
+
+func passwordFlags(fs *flag.FlagSet) (*string, *bool) {
+	password := fs.String("password", "", "新密碼")
+	passwordStdin := fs.Bool("password-stdin", false, "從標準輸入讀取密碼")
+	return password, passwordStdin
+}
+
+func findUser(username string, id uint) (models.User, error) {
+	var user models.User
+	switch {
+	case username != "" && id != 0:
+		return user, errors.New("-username 與 -id 請擇一指定")
+	case username != "":
+		if err := db.DB.Where("username = ?", username).First(&user).Error; err != nil {
+			return user, fmt.Errorf("找不到帳號 %s: %w", username, err)
+		}
+	case id != 0:
+		if err := db.DB.First(&user, id).Error; err != nil {
+			return user, fmt.Errorf("找不到使用者 ID %d: %w", id, err)
+		}
+	default:
+		return user, errors.New("請指定 -username 或 -id")
+	}
+	return user, nil
+}
+
+func readPassword(password string, fromStdin bool) (string, error) {
+	if fromStdin {
+		if password != "" {
+			return "", errors.New("-password 與 -password-stdin 請擇一指定")
+		}
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("請以 -password 或 -password-stdin 提供密碼")
+	}
+	return password, nil
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+
+	"github.com/wac0705/fastener-api/models"
+)
+
+// Models 由 Migrate 建立/更新資料表的所有模型
+var Models = []interface{}{
+	&models.Company{},
+	&models.Role{},
+	&models.User{},
+	&models.UserCompanyMembership{},
+	&models.UserRecoveryCode{},
+	&models.APIKey{},
+	&models.Menu{},
+	&models.RoleMenuRelation{},
+	&models.RoleMenuChangeLog{},
+	&models.Customer{},
+	&models.CustomerSite{},
+	&models.CustomerContact{},
+	&models.CustomerActivity{},
+	&models.ActivityAttachment{},
+	&models.CustomerTransactionTerm{},
+	&models.PriceList{},
+	&models.PriceListItem{},
+	&models.ProductCategory{},
+	&models.ProductShape{},
+	&models.ProductFunction{},
+	&models.ProductSpecification{},
+	&models.Quotation{},
+	&models.QuotationLine{},
+	&models.SalesOrder{},
+	&models.SalesOrderLine{},
+	&models.SalesOrderShipment{},
+	&models.SalesOrderShipmentLine{},
+	&models.ShipmentContainer{},
+	&models.ShipmentPackage{},
+	&models.ShipmentPackageLine{},
+	&models.SalesAgent{},
+	&models.Invoice{},
+	&models.InvoiceLine{},
+	&models.InvoicePayment{},
+	&models.DocumentSequence{},
+	&models.Commission{},
+	&models.ExchangeRate{},
+	&models.CreditLimit{},
+	&models.CreditHold{},
+	&models.DocumentTemplate{},
+	&models.RenderedDocument{},
+	&models.Warehouse{},
+	&models.WarehouseBin{},
+	&models.StockBalance{},
+	&models.StockMovement{},
+	&models.MaterialHeat{},
+	&models.ProductionOrder{},
+	&models.ProductionOrderMaterial{},
+	&models.ProcessBatch{},
+	&models.ProductionLot{},
+	&models.ProductionLotHeat{},
+	&models.ProductionLotBatch{},
+	&models.MillCertificate{},
+	&models.MillCertificateElement{},
+	&models.InspectionResult{},
+}
+
+// 改為依公司編號後不再使用的全域唯一索引（資料表, 索引名稱）；
+// 同名的索引若已改為一般索引，會在 AutoMigrate 時重新建立
+var obsoleteUniqueIndexes = [][2]string{
+	{"invoices", "idx_invoices_invoice_no"},
+	{"invoices", "idx_invoices_shipment_id"},
+	{"production_orders", "idx_production_orders_order_no"},
+	{"quotations", "idx_quotations_quote_no"},
+	{"sales_orders", "idx_sales_orders_order_no"},
+	{"sales_order_shipments", "idx_sales_order_shipments_shipment_no"},
+}
+
+// Migrate 依模型定義建立或補齊資料表欄位與索引（不會刪除既有欄位），
+// 並移除已被取代的唯一索引、補齊新增欄位的既有資料
+func Migrate() error {
+	for _, idx := range obsoleteUniqueIndexes {
+		var unique int64
+		if err := DB.Raw(`SELECT COUNT(*) FROM pg_indexes WHERE tablename = ? AND indexname = ? AND indexdef LIKE 'CREATE UNIQUE%'`,
+			idx[0], idx[1]).Scan(&unique).Error; err != nil {
+			return err
+		}
+		if unique > 0 {
+			if err := DB.Migrator().DropIndex(idx[0], idx[1]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := DB.AutoMigrate(Models...); err != nil {
+		return err
+	}
+	// 出貨單號改依公司編號，既有出貨的公司取自訂單
+	if err := DB.Exec(`UPDATE sales_order_shipments s SET company_id = o.company_id
+		FROM sales_orders o WHERE o.id = s.sales_order_id AND s.company_id = 0`).Error; err != nil {
+		return err
+	}
+	// 既有出貨出庫補上訂單明細：出貨中僅一筆相同產品規格的明細時才可判定
+	return DB.Exec(`UPDATE stock_movements m SET sales_order_line_id = sl.sales_order_line_id
+		FROM sales_order_shipment_lines sl JOIN sales_order_lines ol ON ol.id = sl.sales_order_line_id
+		WHERE m.type = ? AND m.sales_order_line_id IS NULL AND sl.shipment_id = m.shipment_id
+			AND ol.product_specification_id = m.product_specification_id
+			AND (SELECT COUNT(*) FROM sales_order_shipment_lines sl2 JOIN sales_order_lines ol2 ON ol2.id = sl2.sales_order_line_id
+				WHERE sl2.shipment_id = m.shipment_id AND ol2.product_specification_id = m.product_specification_id) = 1`,
+		models.StockIssue).Error
+}
+
+// 系統內建角色的預設權限，依版本累加；superadmin 一律擁有全部權限，不需列出。
+// 新增預設權限時請加一個新版本，不要修改既有版本：Seed 只補上角色尚未套用的版本，
+// 管理者自行移除的權限不會再被加回
+var seedRolePermissions = []map[string][]string{
+	// 版本 1
+	{models.RoleCompanyAdmin: {
+		models.PermCompaniesRead, models.PermCompaniesWrite,
+		models.PermRolesRead, models.PermRolesWrite,
+		models.PermMenusRead,
+		models.PermAccountsRead, models.PermAccountsWrite,
+		models.PermCustomersRead, models.PermCustomersWrite,
+		models.PermProductsRead,
+		models.PermSalesRead, models.PermSalesWrite,
+		models.PermInvoicesRead, models.PermInvoicesWrite,
+		models.PermCommissionsRead, models.PermCommissionsWrite,
+		models.PermCreditRead, models.PermCreditWrite, models.PermCreditApprove,
+		models.PermDocumentsRead, models.PermDocumentsWrite,
+		models.PermInventoryRead, models.PermInventoryWrite, models.PermInventoryAdjust,
+		models.PermTraceRead, models.PermTraceWrite,
+		models.PermQualityRead, models.PermQualityWrite,
+	}},
+}
+
+// seedPermissions 角色在 from 之後各版本的預設權限
+func seedPermissions(name string, from int) []string {
+	perms := []string{}
+	for _, version := range seedRolePermissions[from:] {
+		perms = mergePermissions(perms, version[name])
+	}
+	return perms
+}
+
+// SeedOptions 建立根公司時使用的預設值
+type SeedOptions struct {
+	CompanyName string
+	Currency    string
+	Language    string
+}
+
+// Seed 建立系統內建角色，並在尚無任何公司時建立根公司。
+// 已存在的內建角色只補上尚未套用版本的預設權限，不會移除既有權限；
+// 啟用權限檢查前建立、尚未設定權限（欄位為 NULL）的自訂角色設為空的權限清單，
+// 不自動授予任何權限，需由管理者逐一設定。可重複執行。
+func Seed(opts SeedOptions) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, name := range []string{models.RoleSuperAdmin, models.RoleCompanyAdmin} {
+			latest := len(seedRolePermissions)
+			role := models.Role{Name: name, Permissions: seedPermissions(name, 0), SeedVersion: latest}
+			if err := tx.Where("name = ?", name).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+			if role.SeedVersion < latest {
+				merged := mergePermissions(role.Permissions, seedPermissions(name, role.SeedVersion))
+				if err := tx.Model(&role).Select("permissions", "seed_version").
+					Updates(models.Role{Permissions: merged, SeedVersion: latest}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		var legacy []models.Role
+		if err := tx.Where("permissions IS NULL AND name NOT IN ?", []string{models.RoleSuperAdmin, models.RoleCompanyAdmin}).
+			Find(&legacy).Error; err != nil {
+			return err
+		}
+		for _, role := range legacy {
+			if err := tx.Model(&role).Select("permissions").Updates(models.Role{Permissions: []string{}}).Error; err != nil {
+				return err
+			}
+			log.Printf("⚠️ 角色 %s 尚未設定權限，請由管理者設定", role.Name)
+		}
+
+		var count int64
+		if err := tx.Model(&models.Company{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		if opts.CompanyName == "" {
+			return errors.New("尚無任何公司，請指定根公司名稱")
+		}
+		return tx.Create(&models.Company{
+			Name:     opts.CompanyName,
+			Currency: opts.Currency,
+			Language: opts.Language,
+		}).Error
+	})
+}
+
+// mergePermissions 將 defaults 中尚未擁有的權限加到 current 後面
+func mergePermissions(current, defaults []string) []string {
+	has := make(map[string]bool, len(current))
+	for _, p := range current {
+		has[p] = true
+	}
+	merged := append([]string{}, current...)
+	for _, p := range defaults {
+		if !has[p] {
+			merged = append(merged, p)
+			has[p] = true
+		}
+	}
+	return merged
+}
@@ -0,0 +1,207 @@
+package docrender
+
+// DefaultLanguage 未設定或不支援的語言一律以英文輸出
+const DefaultLanguage = "en"
+
+var labels = map[string]map[string]string{
+	"en": {
+		"quotation":          "QUOTATION",
+		"proforma_invoice":   "PROFORMA INVOICE",
+		"commercial_invoice": "COMMERCIAL INVOICE",
+		"packing_list":       "PACKING LIST",
+		"order_confirmation": "ORDER CONFIRMATION",
+		"doc_no":             "No.",
+		"date":               "Date",
+		"valid_until":        "Valid Until",
+		"due_date":           "Due Date",
+		"order_no":           "Order No.",
+		"customer_po":        "Customer PO",
+		"shipment_no":        "Shipment No.",
+		"incoterm":           "Incoterm",
+		"currency":           "Currency",
+		"port":               "Port of Loading",
+		"destination":        "Destination",
+		"buyer":              "Buyer",
+		"bill_to":            "Bill To",
+		"ship_to":            "Ship To",
+		"tax_id":             "Tax ID",
+		"line_no":            "#",
+		"description":        "Description",
+		"quantity":           "Quantity",
+		"unit":               "Unit",
+		"unit_price":         "Unit Price",
+		"amount":             "Amount",
+		"delivery_date":      "Delivery",
+		"subtotal":           "Subtotal",
+		"tax":                "Tax",
+		"total":              "Total",
+		"total_quantity":     "Total Quantity",
+		"remarks":            "Remarks",
+		"page":               "Page",
+		"carton_no":          "C/No.",
+		"net_weight":         "N.W. (KG)",
+		"gross_weight":       "G.W. (KG)",
+		"measurement":        "Meas. (CBM)",
+		"total_cartons":      "Total Cartons",
+		"total_pallets":      "Total Pallets",
+		"port_of_discharge":  "Port of Discharge",
+		"vessel":             "Vessel / Voyage",
+		"etd":                "ETD",
+		"eta":                "ETA",
+		"container":          "Container",
+		"seal_no":            "Seal No.",
+		"inspection_cert":    "INSPECTION CERTIFICATE EN 10204 3.1",
+		"certificate_no":     "Certificate No.",
+		"product":            "Product",
+		"lot_no":             "Lot No.",
+		"heat_no":            "Heat No.",
+		"test_item":          "Test Item",
+		"requirement":        "Requirement",
+		"result":             "Result",
+		"judgment":           "Judgment",
+		"pass":               "OK",
+		"chemistry":          "Chemical Composition (%)",
+		"mill_cert":          "Mill Cert.",
+		"inspector":          "Authorized Inspection Representative",
+		"declaration_3_1":    "We hereby certify that the products described above comply with the requirements of the order. The test results are based on specific inspection of the delivered lots (EN 10204 type 3.1).",
+	},
+	"zh-TW": {
+		"quotation":          "報價單",
+		"proforma_invoice":   "形式發票",
+		"commercial_invoice": "商業發票",
+		"packing_list":       "裝箱單",
+		"order_confirmation": "訂單確認書",
+		"doc_no":             "單號",
+		"date":               "日期",
+		"valid_until":        "有效期限",
+		"due_date":           "付款期限",
+		"order_no":           "訂單號碼",
+		"customer_po":        "客戶訂單號碼",
+		"shipment_no":        "出貨單號",
+		"incoterm":           "貿易條件",
+		"currency":           "幣別",
+		"port":               "出口港",
+		"destination":        "目的地",
+		"buyer":              "買方",
+		"bill_to":            "請款對象",
+		"ship_to":            "送貨地址",
+		"tax_id":             "統一編號",
+		"line_no":            "項次",
+		"description":        "品名規格",
+		"quantity":           "數量",
+		"unit":               "單位",
+		"unit_price":         "單價",
+		"amount":             "金額",
+		"delivery_date":      "交期",
+		"subtotal":           "小計",
+		"tax":                "稅額",
+		"total":              "總計",
+		"total_quantity":     "總數量",
+		"remarks":            "備註",
+		"page":               "頁次",
+		"carton_no":          "箱號",
+		"net_weight":         "淨重 (KG)",
+		"gross_weight":       "毛重 (KG)",
+		"measurement":        "材積 (CBM)",
+		"total_cartons":      "總箱數",
+		"total_pallets":      "總棧板數",
+		"port_of_discharge":  "卸貨港",
+		"vessel":             "船名 / 航次",
+		"etd":                "預計開航日",
+		"eta":                "預計抵達日",
+		"container":          "貨櫃",
+		"seal_no":            "封條號碼",
+		"inspection_cert":    "檢驗證明書 EN 10204 3.1",
+		"certificate_no":     "證明書號碼",
+		"product":            "產品",
+		"lot_no":             "批號",
+		"heat_no":            "爐號",
+		"test_item":          "檢驗項目",
+		"requirement":        "規格",
+		"result":             "實測值",
+		"judgment":           "判定",
+		"pass":               "合格",
+		"chemistry":          "化學成分 (%)",
+		"mill_cert":          "材質證明",
+		"inspector":          "授權檢驗代表",
+		"declaration_3_1":    "茲證明上述產品符合訂單要求，檢驗結果係依交貨批號之特定檢驗 (EN 10204 3.1)。",
+	},
+	"zh-CN": {
+		"quotation":          "报价单",
+		"proforma_invoice":   "形式发票",
+		"commercial_invoice": "商业发票",
+		"packing_list":       "装箱单",
+		"order_confirmation": "订单确认书",
+		"doc_no":             "单号",
+		"date":               "日期",
+		"valid_until":        "有效期限",
+		"due_date":           "付款期限",
+		"order_no":           "订单号码",
+		"customer_po":        "客户订单号码",
+		"shipment_no":        "出货单号",
+		"incoterm":           "贸易条件",
+		"currency":           "币别",
+		"port":               "出口港",
+		"destination":        "目的地",
+		"buyer":              "买方",
+		"bill_to":            "收票方",
+		"ship_to":            "收货地址",
+		"tax_id":             "税号",
+		"line_no":            "项次",
+		"description":        "品名规格",
+		"quantity":           "数量",
+		"unit":               "单位",
+		"unit_price":         "单价",
+		"amount":             "金额",
+		"delivery_date":      "交期",
+		"subtotal":           "小计",
+		"tax":                "税额",
+		"total":              "总计",
+		"total_quantity":     "总数量",
+		"remarks":            "备注",
+		"page":               "页次",
+		"carton_no":          "箱号",
+		"net_weight":         "净重 (KG)",
+		"gross_weight":       "毛重 (KG)",
+		"measurement":        "体积 (CBM)",
+		"total_cartons":      "总箱数",
+		"total_pallets":      "总托盘数",
+		"port_of_discharge":  "卸货港",
+		"vessel":             "船名 / 航次",
+		"etd":                "预计开航日",
+		"eta":                "预计到港日",
+		"container":          "集装箱",
+		"seal_no":            "封条号",
+		"inspection_cert":    "检验证明书 EN 10204 3.1",
+		"certificate_no":     "证明书号码",
+		"product":            "产品",
+		"lot_no":             "批号",
+		"heat_no":            "炉号",
+		"test_item":          "检验项目",
+		"requirement":        "规格",
+		"result":             "实测值",
+		"judgment":           "判定",
+		"pass":               "合格",
+		"chemistry":          "化学成分 (%)",
+		"mill_cert":          "材质证明",
+		"inspector":          "授权检验代表",
+		"declaration_3_1":    "兹证明上述产品符合订单要求，检验结果系依交货批号之特定检验 (EN 10204 3.1)。",
+	},
+}
+
+// Label 取得指定語言的欄位名稱，缺少時依序退回英文、key 本身
+func Label(language, key string) string {
+	if l, ok := labels[language][key]; ok {
+		return l
+	}
+	if l, ok := labels[DefaultLanguage][key]; ok {
+		return l
+	}
+	return key
+}
+
+// Supported 是否有該語言的欄位名稱
+func Supported(language string) bool {
+	_, ok := labels[language]
+	return ok
+}
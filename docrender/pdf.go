@@ -0,0 +1,216 @@
+package docrender
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 尺寸（單位 pt）
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// cjkFont 各語系使用 PDF 預先定義的 CJK 字型（不內嵌字型檔，由閱讀器提供）
+type cjkFont struct {
+	name, ordering, encoding string
+	supplement               int
+}
+
+var cjkFonts = map[string]cjkFont{
+	"zh-TW": {"MSung-Light", "CNS1", "UniCNS-UCS2-H", 0},
+	"zh-CN": {"STSong-Light", "GB1", "UniGB-UCS2-H", 2},
+	"ja":    {"HeiseiMin-W3", "Japan1", "UniJIS-UCS2-H", 2},
+	"ko":    {"HYSMyeongJo-Medium", "Korea1", "UniKS-UCS2-H", 1},
+}
+
+// helveticaWidths Helvetica 字元寬度（千分之一 em），ASCII 32~126
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// textWidth 估算文字寬度：ASCII 依 Helvetica 字寬，其餘字元以全形計
+func textWidth(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			w += helveticaWidths[r-32]
+		} else {
+			w += 1000
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// pdfWriter 依序寫出物件並記錄 xref 位移
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) object(id int, body string) {
+	for len(w.offsets) < id {
+		w.offsets = append(w.offsets, 0)
+	}
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+func (w *pdfWriter) stream(id int, dict string, data []byte) {
+	for len(w.offsets) < id {
+		w.offsets = append(w.offsets, 0)
+	}
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<<%s /Length %d>>\nstream\n", id, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// page 單頁內容串流
+type page struct {
+	ops bytes.Buffer
+}
+
+// text 於 (x, y) 輸出文字；ASCII 與非 ASCII 片段分別使用 Helvetica (F1/F2) 與 CJK 字型 (F3)
+func (p *page) text(x, y, size float64, bold bool, s string) {
+	if s == "" {
+		return
+	}
+	latin := "/F1"
+	if bold {
+		latin = "/F2"
+	}
+	fmt.Fprintf(&p.ops, "BT %.2f %.2f Td ", x, y)
+	for _, run := range splitRuns(s) {
+		if run.ascii {
+			fmt.Fprintf(&p.ops, "%s %.1f Tf (%s) Tj ", latin, size, escapeLiteral(run.text))
+		} else {
+			fmt.Fprintf(&p.ops, "/F3 %.1f Tf <%s> Tj ", size, ucs2Hex(run.text))
+		}
+	}
+	p.ops.WriteString("ET\n")
+}
+
+func (p *page) textRight(right, y, size float64, bold bool, s string) {
+	p.text(right-textWidth(s, size), y, size, bold, s)
+}
+
+func (p *page) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.ops, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+func (p *page) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.ops, "q %.2f g %.2f %.2f %.2f %.2f re f Q\n", gray, x, y, w, h)
+}
+
+func (p *page) image(name string, x, y, w, h float64) {
+	fmt.Fprintf(&p.ops, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, y, name)
+}
+
+type textRun struct {
+	text  string
+	ascii bool
+}
+
+func splitRuns(s string) []textRun {
+	var runs []textRun
+	var cur strings.Builder
+	curASCII := true
+	for i, r := range s {
+		ascii := r >= 32 && r <= 126
+		if i > 0 && ascii != curASCII && cur.Len() > 0 {
+			runs = append(runs, textRun{cur.String(), curASCII})
+			cur.Reset()
+		}
+		curASCII = ascii
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		runs = append(runs, textRun{cur.String(), curASCII})
+	}
+	return runs
+}
+
+func escapeLiteral(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
+
+// ucs2Hex 轉為 UCS-2 (UTF-16BE) 十六進位字串；BMP 以外及控制字元以全形問號代替
+func ucs2Hex(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF || r < 32 {
+			r = '？'
+		}
+		for _, u := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&b, "%04X", u)
+		}
+	}
+	return b.String()
+}
+
+// pdfImage 內嵌的 JPEG 圖片 (DCTDecode)
+type pdfImage struct {
+	data          []byte
+	width, height int
+	colorSpace    string
+}
+
+// assemble 組出完整 PDF；不寫入產生時間等變動資訊，相同輸入必產生相同位元組
+func assemble(pages []*page, language string, logo *pdfImage) []byte {
+	font, ok := cjkFonts[language]
+	if !ok {
+		font = cjkFonts["zh-TW"]
+	}
+
+	// 物件編號：1 Catalog、2 Pages、3~7 字型、8 Logo、9 起為各頁與內容串流
+	const firstPage = 9
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	w.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	w.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	w.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	w.object(5, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /%s /DescendantFonts [6 0 R] >>",
+		font.name, font.encoding))
+	w.object(6, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (%s) /Supplement %d >> /FontDescriptor 7 0 R /DW 1000 >>",
+		font.name, font.ordering, font.supplement))
+	w.object(7, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [-160 -249 1015 1071] "+
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", font.name))
+
+	resources := "/Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >>"
+	if logo != nil {
+		w.stream(8, fmt.Sprintf(" /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode",
+			logo.width, logo.height, logo.colorSpace), logo.data)
+		resources += " /XObject << /Logo 8 0 R >>"
+	} else {
+		w.object(8, "null")
+	}
+
+	for i, p := range pages {
+		id := firstPage + i*2
+		w.object(id, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, resources, id+1))
+		w.stream(id+1, "", p.ops.Bytes())
+	}
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
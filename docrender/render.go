@@ -0,0 +1,329 @@
+// Package docrender 依公司範本將單據（報價單、發票、裝箱單、訂單確認書）排版為 PDF。
+//
+// 只使用標準函式庫：英數字使用 Helvetica，中日韓文字使用 PDF 預先定義的 CJK 字型，
+// Logo 僅支援 JPEG 直接內嵌。輸出不含產生時間，相同的 Template + Document 必產生相同的位元組，
+// 呼叫端保存輸入快照即可日後重新產生完全一致的文件。
+package docrender
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+)
+
+// ErrInvalidLogo Logo 不是可讀取的 JPEG
+var ErrInvalidLogo = errors.New("Logo 必須為 JPEG 圖片")
+
+// Template 公司單據範本（表頭資訊與語言）
+type Template struct {
+	Language    string   `json:"language"`
+	CompanyName string   `json:"company_name"`
+	Address     []string `json:"address"`
+	Contact     string   `json:"contact"`
+	TaxID       string   `json:"tax_id"`
+	Footer      string   `json:"footer"`
+	Logo        []byte   `json:"logo,omitempty"` // JPEG
+}
+
+// Field 標籤 / 內容
+type Field struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Party 交易對象區塊（買方、送貨地址等）
+type Party struct {
+	Label string   `json:"label"`
+	Lines []string `json:"lines"`
+}
+
+// Column 明細欄位；Width 為相對寬度
+type Column struct {
+	Label string  `json:"label"`
+	Width float64 `json:"width"`
+	Right bool    `json:"right"`
+}
+
+// Document 單據內容，欄位名稱與數值格式皆由呼叫端依語言準備好
+type Document struct {
+	Title   string     `json:"title"`
+	Fields  []Field    `json:"fields"`
+	Parties []Party    `json:"parties"`
+	Columns []Column   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	Totals  []Field    `json:"totals"`
+	Notes   []string   `json:"notes"`
+}
+
+const (
+	margin       = 40.0
+	contentWidth = pageWidth - margin*2
+	bottomLimit  = 70.0 // 明細到此高度即換頁，下方保留頁尾
+	bodySize     = 9.0
+	smallSize    = 8.0
+	lineGap      = 11.0
+	cellPad      = 3.0
+)
+
+// Render 排版並輸出 PDF
+func Render(tpl Template, doc Document) ([]byte, error) {
+	var logo *pdfImage
+	if len(tpl.Logo) > 0 {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(tpl.Logo))
+		if err != nil {
+			return nil, ErrInvalidLogo
+		}
+		logo = &pdfImage{data: tpl.Logo, width: cfg.Width, height: cfg.Height, colorSpace: colorSpace(cfg)}
+	}
+
+	l := &layout{tpl: tpl, doc: doc}
+	l.newPage(true, logo)
+	l.parties()
+	l.table()
+	l.totals()
+	l.notes()
+	l.footers()
+	return assemble(l.pages, tpl.Language, logo), nil
+}
+
+// ValidateLogo 檢查 Logo 是否為可內嵌的 JPEG
+func ValidateLogo(data []byte) error {
+	if _, err := jpeg.DecodeConfig(bytes.NewReader(data)); err != nil {
+		return ErrInvalidLogo
+	}
+	return nil
+}
+
+func colorSpace(cfg image.Config) string {
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		return "DeviceGray"
+	case color.CMYKModel:
+		return "DeviceCMYK"
+	}
+	return "DeviceRGB"
+}
+
+type layout struct {
+	tpl    Template
+	doc    Document
+	pages  []*page
+	cur    *page
+	y      float64
+	widths []float64
+}
+
+func (l *layout) newPage(first bool, logo *pdfImage) {
+	l.cur = &page{}
+	l.pages = append(l.pages, l.cur)
+	top := pageHeight - margin
+	right := pageWidth - margin
+
+	if !first {
+		// 續頁只保留標題與單號
+		l.cur.text(margin, top-12, 10, true, l.tpl.CompanyName)
+		title := l.doc.Title
+		if len(l.doc.Fields) > 0 {
+			title += "  " + l.doc.Fields[0].Value
+		}
+		l.cur.textRight(right, top-12, 10, true, title)
+		l.cur.line(margin, top-20, right, top-20, 0.5)
+		l.y = top - 32
+		return
+	}
+
+	// 左側：Logo、公司名稱、地址與聯絡資訊
+	x := margin
+	left := top
+	if logo != nil {
+		h := 48.0
+		w := h * float64(logo.width) / float64(logo.height)
+		if w > 120 {
+			w, h = 120, 120*float64(logo.height)/float64(logo.width)
+		}
+		l.cur.image("Logo", margin, top-h, w, h)
+		x += w + 12
+		left = top - h
+	}
+	ty := top - 14
+	l.cur.text(x, ty, 14, true, l.tpl.CompanyName)
+	ty -= 6
+	info := append([]string{}, l.tpl.Address...)
+	if l.tpl.Contact != "" {
+		info = append(info, l.tpl.Contact)
+	}
+	if l.tpl.TaxID != "" {
+		info = append(info, Label(l.tpl.Language, "tax_id")+": "+l.tpl.TaxID)
+	}
+	for _, s := range info {
+		ty -= lineGap
+		l.cur.text(x, ty, smallSize, false, s)
+	}
+	if ty < left {
+		left = ty
+	}
+
+	// 右側：標題與單據資訊
+	ry := top - 16
+	l.cur.textRight(right, ry, 16, true, l.doc.Title)
+	ry -= 8
+	for _, f := range l.doc.Fields {
+		ry -= lineGap + 1
+		l.cur.text(right-190, ry, bodySize, false, f.Label)
+		l.cur.textRight(right, ry, bodySize, true, f.Value)
+	}
+
+	l.y = min(left, ry) - 14
+	l.cur.line(margin, l.y, right, l.y, 0.8)
+	l.y -= 16
+}
+
+func (l *layout) parties() {
+	if len(l.doc.Parties) == 0 {
+		return
+	}
+	colWidth := contentWidth / float64(len(l.doc.Parties))
+	lowest := l.y
+	for i, p := range l.doc.Parties {
+		x := margin + float64(i)*colWidth
+		y := l.y
+		l.cur.text(x, y, bodySize, true, p.Label)
+		for _, s := range p.Lines {
+			for _, w := range wrap(s, bodySize, colWidth-12) {
+				y -= lineGap
+				l.cur.text(x, y, bodySize, false, w)
+			}
+		}
+		lowest = min(lowest, y)
+	}
+	l.y = lowest - 18
+}
+
+func (l *layout) tableHeader() {
+	l.cur.fillRect(margin, l.y-5, contentWidth, 16, 0.9)
+	x := margin
+	for i, col := range l.doc.Columns {
+		if col.Right {
+			l.cur.textRight(x+l.widths[i]-cellPad, l.y, smallSize, true, col.Label)
+		} else {
+			l.cur.text(x+cellPad, l.y, smallSize, true, col.Label)
+		}
+		x += l.widths[i]
+	}
+	l.y -= 18
+}
+
+func (l *layout) table() {
+	if len(l.doc.Columns) == 0 {
+		return
+	}
+	total := 0.0
+	for _, col := range l.doc.Columns {
+		total += col.Width
+	}
+	l.widths = make([]float64, len(l.doc.Columns))
+	for i, col := range l.doc.Columns {
+		l.widths[i] = contentWidth * col.Width / total
+	}
+	l.tableHeader()
+
+	for _, row := range l.doc.Rows {
+		cells := make([][]string, len(l.doc.Columns))
+		height := 1
+		for i := range l.doc.Columns {
+			if i < len(row) {
+				cells[i] = wrap(row[i], bodySize, l.widths[i]-cellPad*2)
+			}
+			height = max(height, len(cells[i]))
+		}
+		rowHeight := float64(height)*lineGap + 4
+		if l.y-rowHeight < bottomLimit {
+			l.newPage(false, nil)
+			l.tableHeader()
+		}
+		x := margin
+		for i, col := range l.doc.Columns {
+			for j, s := range cells[i] {
+				y := l.y - float64(j)*lineGap
+				if col.Right {
+					l.cur.textRight(x+l.widths[i]-cellPad, y, bodySize, false, s)
+				} else {
+					l.cur.text(x+cellPad, y, bodySize, false, s)
+				}
+			}
+			x += l.widths[i]
+		}
+		l.y -= rowHeight
+		l.cur.line(margin, l.y+lineGap-3, pageWidth-margin, l.y+lineGap-3, 0.3)
+	}
+	l.y -= 6
+}
+
+func (l *layout) totals() {
+	right := pageWidth - margin
+	for i, f := range l.doc.Totals {
+		if l.y-lineGap < bottomLimit {
+			l.newPage(false, nil)
+		}
+		last := i == len(l.doc.Totals)-1
+		l.cur.text(right-200, l.y, bodySize, last, f.Label)
+		l.cur.textRight(right, l.y, bodySize, last, f.Value)
+		l.y -= lineGap + 2
+	}
+	l.y -= 10
+}
+
+func (l *layout) notes() {
+	for _, n := range l.doc.Notes {
+		for _, s := range wrap(n, smallSize, contentWidth) {
+			if l.y-lineGap < bottomLimit {
+				l.newPage(false, nil)
+			}
+			l.cur.text(margin, l.y, smallSize, false, s)
+			l.y -= lineGap
+		}
+	}
+}
+
+// footers 各頁頁尾：範本頁尾文字與頁次
+func (l *layout) footers() {
+	for i, p := range l.pages {
+		p.line(margin, 48, pageWidth-margin, 48, 0.5)
+		if l.tpl.Footer != "" {
+			p.text(margin, 36, smallSize, false, l.tpl.Footer)
+		}
+		p.textRight(pageWidth-margin, 36, smallSize, false,
+			fmt.Sprintf("%s %d / %d", Label(l.tpl.Language, "page"), i+1, len(l.pages)))
+	}
+}
+
+// wrap 依寬度斷行；英文優先於空白處斷行，中日韓文字可於任意字元斷行
+func wrap(s string, size, width float64) []string {
+	var out []string
+	for _, para := range strings.Split(s, "\n") {
+		line := []rune{}
+		lastSpace := -1
+		for _, r := range para {
+			line = append(line, r)
+			if r == ' ' {
+				lastSpace = len(line) - 1
+			}
+			if textWidth(string(line), size) <= width || len(line) == 1 {
+				continue
+			}
+			cut := len(line) - 1
+			if lastSpace > 0 && r <= 126 {
+				cut = lastSpace + 1
+			}
+			out = append(out, strings.TrimRight(string(line[:cut]), " "))
+			line = append([]rune{}, line[cut:]...)
+			lastSpace = -1
+		}
+		out = append(out, string(line))
+	}
+	return out
+}
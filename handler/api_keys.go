@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢 API Key 列表（superadmin 全部，company_admin 為自己及下層公司）
+func GetAPIKeys(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	query := db.DB.Order("id DESC")
+	if role == "company_admin" {
+		query = query.Where("company_id IN ?", getDescendantCompanyIDs(companyID))
+	}
+	var keys []models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢 API Key 失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// 建立 API Key，完整金鑰只在此回應中出現一次
+// 權限不得超出建立者自己擁有的權限
+func CreateAPIKey(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.CompanyID == 0 || len(req.Permissions) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "名稱、公司與權限皆為必填"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "到期時間必須晚於現在"})
+		return
+	}
+	if unknown := unknownPermissions(req.Permissions); len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的權限", "permissions": unknown})
+		return
+	}
+	if role == "company_admin" {
+		if !companyInScope(companyID, req.CompanyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "無法在此公司建立 API Key"})
+			return
+		}
+		if missing := missingPermissions(req.Permissions, currentPermissions(c)); len(missing) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "不可授予自己沒有的權限", "permissions": missing})
+			return
+		}
+	}
+
+	key, prefix, err := models.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生 API Key 失敗"})
+		return
+	}
+	apiKey := models.APIKey{
+		CompanyID:   req.CompanyID,
+		Name:        req.Name,
+		Prefix:      prefix,
+		KeyHash:     models.HashAPIKey(key),
+		Permissions: req.Permissions,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   c.GetString("username"),
+	}
+	if err := db.DB.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立 API Key 失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, models.CreatedAPIKey{APIKey: apiKey, Key: key})
+}
+
+// 撤銷 API Key（保留紀錄，立即失效）
+func RevokeAPIKey(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var apiKey models.APIKey
+	if err := db.DB.First(&apiKey, c.Param("keyId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的 API Key"})
+		return
+	}
+	if role == "company_admin" && !companyInScope(companyID, apiKey.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司的 API Key"})
+		return
+	}
+	if apiKey.RevokedAt == nil {
+		now := time.Now()
+		if err := db.DB.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "撤銷 API Key 失敗: " + err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, apiKey)
+}
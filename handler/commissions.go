@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+var periodPattern = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+// accrueCommission 開立商業發票時依訂單指派的業務與佣金比例，以未稅金額提列佣金；
+// 未指派業務或比例為 0 時不提列
+func accrueCommission(tx *gorm.DB, invoice models.Invoice, order models.SalesOrder) error {
+	if order.AgentID == nil || order.CommissionRate <= 0 {
+		return nil
+	}
+	return tx.Create(&models.Commission{
+		CompanyID:    invoice.CompanyID,
+		AgentID:      *order.AgentID,
+		InvoiceID:    invoice.ID,
+		SalesOrderID: order.ID,
+		CustomerID:   invoice.CustomerID,
+		Period:       invoice.InvoiceDate.Format("2006-01"),
+		CurrencyCode: invoice.CurrencyCode,
+		BaseAmount:   invoice.SubtotalAmount,
+		Rate:         order.CommissionRate,
+		Amount:       roundAmount(invoice.SubtotalAmount * order.CommissionRate / 100),
+		Status:       models.CommissionAccrued,
+	}).Error
+}
+
+// 查詢佣金明細，可依 agent_id、period、status、currency_code 篩選
+func GetCommissions(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("period DESC, id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"agent_id", "period", "status", "currency_code"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var commissions []models.Commission
+	if err := query.Find(&commissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢佣金失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, commissions)
+}
+
+// 月佣金對帳單：依業務與幣別分組，?period=YYYY-MM 必填，可再以 agent_id 篩選
+func GetCommissionStatements(c *gin.Context) {
+	period := c.Query("period")
+	if !periodPattern.MatchString(period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請以 period=YYYY-MM 指定月份"})
+		return
+	}
+	query, ok := scopeByCompany(c, db.DB.Where("period = ?", period).Order("agent_id, currency_code, id"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("agent_id"); v != "" {
+		query = query.Where("agent_id = ?", v)
+	}
+	var commissions []models.Commission
+	if err := query.Find(&commissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢佣金失敗: " + err.Error()})
+		return
+	}
+
+	agentNames := map[uint]string{}
+	var agents []models.SalesAgent
+	db.DB.Find(&agents)
+	for _, a := range agents {
+		agentNames[a.ID] = a.Name
+	}
+
+	statements := []*models.CommissionStatement{}
+	byKey := map[string]*models.CommissionStatement{}
+	for _, cm := range commissions {
+		key := strconv.Itoa(int(cm.AgentID)) + "/" + cm.CurrencyCode
+		st, exists := byKey[key]
+		if !exists {
+			st = &models.CommissionStatement{
+				AgentID:      cm.AgentID,
+				AgentName:    agentNames[cm.AgentID],
+				Period:       period,
+				CurrencyCode: cm.CurrencyCode,
+			}
+			byKey[key] = st
+			statements = append(statements, st)
+		}
+		st.Accrued += cm.Amount
+		if cm.Status == models.CommissionPaid {
+			st.Paid += cm.Amount
+		}
+		st.Commissions = append(st.Commissions, cm)
+	}
+	for _, st := range statements {
+		st.Accrued = roundAmount(st.Accrued)
+		st.Paid = roundAmount(st.Paid)
+		st.Outstanding = roundAmount(st.Accrued - st.Paid)
+	}
+	c.JSON(http.StatusOK, statements)
+}
+
+// 標記佣金已付款：指定 commission_ids，或以 agent_id + period + currency_code 整張對帳單付款
+func PayCommissions(c *gin.Context) {
+	var req models.PayCommissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	query := db.DB.Model(&models.Commission{}).Where("status = ?", models.CommissionAccrued)
+	switch {
+	case len(req.CommissionIDs) > 0:
+		query = query.Where("id IN ?", req.CommissionIDs)
+	case req.AgentID != 0 && periodPattern.MatchString(req.Period) && req.CurrencyCode != "":
+		query = query.Where("agent_id = ? AND period = ? AND currency_code = ?", req.AgentID, req.Period, req.CurrencyCode)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請指定 commission_ids，或 agent_id、period 與 currency_code"})
+		return
+	}
+	query, ok := scopeByCompany(c, query, "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	now := time.Now()
+	result := query.Updates(map[string]interface{}{
+		"status":      models.CommissionPaid,
+		"paid_at":     now,
+		"paid_by":     c.GetString("username"),
+		"payment_ref": req.PaymentRef,
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新佣金失敗: " + result.Error.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "佣金已標記付款", "paid_count": result.RowsAffected})
+}
+
+// 佣金彙總報表：指定公司 (?company_id，預設目前公司) 及其下層公司，依公司、業務、幣別彙總；
+// 可用 from / to (YYYY-MM) 限定月份
+func GetCommissionSummary(c *gin.Context) {
+	_, companyID, ok := getRoleAndCompanyID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("company_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+			return
+		}
+		companyID = uint(id)
+	}
+	if !canAccessCompany(c, companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限查詢此公司"})
+		return
+	}
+
+	query := db.DB.Table("commissions cm").
+		Select(`cm.company_id, co.name AS company_name, cm.agent_id, a.name AS agent_name, cm.currency_code,
+			SUM(cm.amount) AS accrued,
+			SUM(CASE WHEN cm.status = ? THEN cm.amount ELSE 0 END) AS paid`, models.CommissionPaid).
+		Joins("LEFT JOIN companies co ON co.id = cm.company_id").
+		Joins("LEFT JOIN sales_agents a ON a.id = cm.agent_id").
+		Where("cm.company_id IN ?", getDescendantCompanyIDs(companyID)).
+		Group("cm.company_id, co.name, cm.agent_id, a.name, cm.currency_code").
+		Order("cm.company_id, a.name, cm.currency_code")
+	if v := c.Query("from"); v != "" {
+		query = query.Where("cm.period >= ?", v)
+	}
+	if v := c.Query("to"); v != "" {
+		query = query.Where("cm.period <= ?", v)
+	}
+	var rows []models.CommissionSummary
+	if err := query.Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢佣金彙總失敗: " + err.Error()})
+		return
+	}
+	for i := range rows {
+		rows[i].Accrued = roundAmount(rows[i].Accrued)
+		rows[i].Paid = roundAmount(rows[i].Paid)
+		rows[i].Outstanding = roundAmount(rows[i].Accrued - rows[i].Paid)
+	}
+	c.JSON(http.StatusOK, rows)
+}
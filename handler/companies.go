@@ -16,6 +16,12 @@ func GetCompanies(c *gin.Context) {
 		return
 	}
 
+	rootCompanies := buildCompanyTree(companies)
+	c.JSON(http.StatusOK, rootCompanies)
+}
+
+// buildCompanyTree 將公司列表轉為樹狀結構；上層不在列表中的公司視為根節點
+func buildCompanyTree(companies []models.Company) []*models.Company {
 	// ID: pointer mapping for building tree
 	companyMap := make(map[uint]*models.Company)
 	for i := range companies {
@@ -25,18 +31,17 @@ func GetCompanies(c *gin.Context) {
 	var rootCompanies []*models.Company
 	for i := range companies {
 		if companies[i].ParentID != nil {
-			parent, ok := companyMap[*companies[i].ParentID]
-			if ok {
+			if parent, ok := companyMap[*companies[i].ParentID]; ok {
 				parent.Children = append(parent.Children, &companies[i])
+				continue
 			}
-		} else {
-			rootCompanies = append(rootCompanies, &companies[i])
 		}
+		rootCompanies = append(rootCompanies, &companies[i])
 	}
 	if rootCompanies == nil {
 		rootCompanies = make([]*models.Company, 0)
 	}
-	c.JSON(http.StatusOK, rootCompanies)
+	return rootCompanies
 }
 
 // --- 建立公司 ---
@@ -105,3 +110,39 @@ func DeleteCompany(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "公司刪除成功"})
 }
+
+// --- 部分更新公司 (JSON Merge Patch) ---
+func PatchCompany(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+		return
+	}
+	var company models.Company
+	if err := db.DB.First(&company, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的公司"})
+		return
+	}
+	updates, err := bindMergePatch(c, &company, "name", "parent_id", "currency", "language")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	// 上層公司不可為自己或自己的下層公司，避免形成循環
+	if parentID, ok := updates["parent_id"].(*uint); ok && parentID != nil {
+		for _, descendantID := range getDescendantCompanyIDs(company.ID) {
+			if descendantID == *parentID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "上層公司不可為自己或下層公司"})
+				return
+			}
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(&company).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新公司失敗: " + err.Error()})
+			return
+		}
+	}
+	db.DB.First(&company, company.ID)
+	c.JSON(http.StatusOK, company)
+}
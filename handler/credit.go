@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 計入信用曝險的訂單狀態（已確認但尚未全數開立發票）
+var openOrderStatuses = []string{models.OrderConfirmed, models.OrderPartiallyShipped, models.OrderShipped}
+
+// 查詢信用額度，可依 customer_id 篩選
+func GetCreditLimits(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("customer_id, company_id"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("customer_id"); v != "" {
+		query = query.Where("customer_id = ?", v)
+	}
+	var limits []models.CreditLimit
+	if err := query.Find(&limits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢信用額度失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, limits)
+}
+
+// 設定信用額度（同一客戶 + 接單公司新增或覆寫）
+func PutCreditLimit(c *gin.Context) {
+	var req models.CreditLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	req.CurrencyCode = strings.ToUpper(strings.TrimSpace(req.CurrencyCode))
+	if req.CustomerID == 0 || req.CompanyID == 0 || req.CurrencyCode == "" || req.LimitAmount < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "客戶、公司與幣別為必填，額度不可為負數"})
+		return
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	var customer models.Customer
+	if err := db.DB.First(&customer, req.CustomerID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	limit := models.CreditLimit{
+		CustomerID:   req.CustomerID,
+		CompanyID:    req.CompanyID,
+		CurrencyCode: req.CurrencyCode,
+		LimitAmount:  req.LimitAmount,
+		Remarks:      req.Remarks,
+		UpdatedBy:    c.GetString("username"),
+	}
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}, {Name: "company_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"currency_code", "limit_amount", "remarks", "updated_by", "updated_at"}),
+	}).Create(&limit).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "設定信用額度失敗: " + err.Error()})
+		return
+	}
+	db.DB.Where("customer_id = ? AND company_id = ?", req.CustomerID, req.CompanyID).First(&limit)
+	c.JSON(http.StatusOK, limit)
+}
+
+// 刪除信用額度（該客戶在此公司不再做額度檢查）
+func DeleteCreditLimit(c *gin.Context) {
+	var limit models.CreditLimit
+	if err := db.DB.First(&limit, c.Param("id")).Error; err != nil || !canAccessCompany(c, limit.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的信用額度"})
+		return
+	}
+	if err := db.DB.Delete(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除信用額度失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "信用額度刪除成功"})
+}
+
+// 查詢客戶在指定公司 (?company_id，預設目前公司) 的信用曝險
+func GetCreditExposure(c *gin.Context) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的客戶 ID"})
+		return
+	}
+	_, companyID, _ := getRoleAndCompanyID(c)
+	if v := c.Query("company_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+			return
+		}
+		companyID = uint(id)
+	}
+	if !canAccessCompany(c, companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限查詢此公司"})
+		return
+	}
+	exposure, err := computeCreditExposure(db.DB, uint(customerID), companyID, 0)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "此客戶在該公司未設定信用額度"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "無法計算信用曝險: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, exposure)
+}
+
+// 查詢信用凍結紀錄，預設只列出凍結中 (?status=held|released|all)
+func GetCreditHolds(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("held_at DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if status := c.DefaultQuery("status", models.CreditHoldHeld); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	var holds []models.CreditHold
+	if err := query.Find(&holds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢信用凍結失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, holds)
+}
+
+// 核准解除訂單信用凍結，訂單轉為 confirmed
+func ReleaseCreditHold(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	var req models.ReleaseCreditHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請填寫核准原因"})
+		return
+	}
+	username := c.GetString("username")
+	now := time.Now()
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 鎖定訂單後重新確認狀態，避免重複核准；並與同客戶的訂單確認依序處理
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderCreditHold {
+			return newRequestError(http.StatusConflict, "此訂單未被信用凍結")
+		}
+		if err := lockCreditLimit(tx, order.CustomerID, order.CompanyID); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Model(&models.CreditHold{}).
+			Where("sales_order_id = ? AND status = ?", order.ID, models.CreditHoldHeld).
+			Updates(map[string]interface{}{
+				"status":         models.CreditHoldReleased,
+				"released_by":    username,
+				"released_at":    now,
+				"release_reason": strings.TrimSpace(req.Reason),
+			}).Error; err != nil {
+			return err
+		}
+		order.Status = models.OrderConfirmed
+		order.ConfirmedAt = &now
+		order.ConfirmedBy = username
+		return tx.Model(&order).Select("status", "confirmed_at", "confirmed_by").Updates(&order).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "解除信用凍結")
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// computeCreditExposure 計算客戶在接單公司的信用曝險（未開立商業發票的已確認訂單 + 未收款商業發票），
+// 換算為額度幣別；excludeOrderID 不計入（確認中的訂單）。未設定額度時回傳 gorm.ErrRecordNotFound
+func computeCreditExposure(tx *gorm.DB, customerID, companyID, excludeOrderID uint) (models.CreditExposure, error) {
+	var limit models.CreditLimit
+	if err := tx.Where("customer_id = ? AND company_id = ?", customerID, companyID).First(&limit).Error; err != nil {
+		return models.CreditExposure{}, err
+	}
+	exposure := models.CreditExposure{
+		CustomerID:   customerID,
+		CompanyID:    companyID,
+		CurrencyCode: limit.CurrencyCode,
+		LimitAmount:  limit.LimitAmount,
+	}
+
+	type currencyAmount struct {
+		CurrencyCode string
+		Amount       float64
+	}
+	// 訂單金額扣除已開立發票的部分，避免與未收款發票重複計算
+	var orders []currencyAmount
+	err := tx.Raw(`
+		SELECT o.currency_code, SUM(GREATEST(o.total_amount - COALESCE(inv.invoiced, 0), 0)) AS amount
+		FROM sales_orders o
+		LEFT JOIN (
+			SELECT sales_order_id, SUM(total_amount) AS invoiced FROM invoices
+			WHERE type = ? AND status <> ?
+			GROUP BY sales_order_id
+		) inv ON inv.sales_order_id = o.id
+		WHERE o.customer_id = ? AND o.company_id = ? AND o.status IN ? AND o.id <> ?
+		GROUP BY o.currency_code
+	`, models.InvoiceCommercial, models.InvoiceCancelled,
+		customerID, companyID, openOrderStatuses, excludeOrderID).Scan(&orders).Error
+	if err != nil {
+		return exposure, err
+	}
+	var invoices []currencyAmount
+	err = tx.Raw(`
+		SELECT currency_code, SUM(total_amount - paid_amount) AS amount
+		FROM invoices
+		WHERE customer_id = ? AND company_id = ? AND type = ? AND status <> ? AND total_amount > paid_amount
+		GROUP BY currency_code
+	`, customerID, companyID, models.InvoiceCommercial, models.InvoiceCancelled).Scan(&invoices).Error
+	if err != nil {
+		return exposure, err
+	}
+
+	now := time.Now()
+	for _, o := range orders {
+		amount, err := convertAmount(o.Amount, o.CurrencyCode, limit.CurrencyCode, now)
+		if err != nil {
+			return exposure, err
+		}
+		exposure.OpenOrders += amount
+	}
+	for _, inv := range invoices {
+		amount, err := convertAmount(inv.Amount, inv.CurrencyCode, limit.CurrencyCode, now)
+		if err != nil {
+			return exposure, err
+		}
+		exposure.UnpaidInvoices += amount
+	}
+	exposure.OpenOrders = roundAmount(exposure.OpenOrders)
+	exposure.UnpaidInvoices = roundAmount(exposure.UnpaidInvoices)
+	exposure.Exposure = roundAmount(exposure.OpenOrders + exposure.UnpaidInvoices)
+	exposure.Available = roundAmount(exposure.LimitAmount - exposure.Exposure)
+	return exposure, nil
+}
+
+// lockCreditLimit 鎖定客戶在接單公司的信用額度，同一客戶的曝險檢查與變動依序進行，
+// 避免同時確認多張訂單而合計超出額度。未設定額度時回傳 gorm.ErrRecordNotFound
+func lockCreditLimit(tx *gorm.DB, customerID, companyID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("customer_id = ? AND company_id = ?", customerID, companyID).
+		First(&models.CreditLimit{}).Error
+}
+
+// checkOrderCredit 訂單確認前的額度檢查；超出額度時回傳凍結紀錄，未設定額度時不檢查
+func checkOrderCredit(tx *gorm.DB, order models.SalesOrder, username string) (*models.CreditHold, error) {
+	if err := lockCreditLimit(tx, order.CustomerID, order.CompanyID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	exposure, err := computeCreditExposure(tx, order.CustomerID, order.CompanyID, order.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	orderAmount, err := convertAmount(order.TotalAmount, order.CurrencyCode, exposure.CurrencyCode, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	orderAmount = roundAmount(orderAmount)
+	if exposure.Exposure+orderAmount <= exposure.LimitAmount {
+		return nil, nil
+	}
+	return &models.CreditHold{
+		SalesOrderID: order.ID,
+		CustomerID:   order.CustomerID,
+		CompanyID:    order.CompanyID,
+		CurrencyCode: exposure.CurrencyCode,
+		LimitAmount:  exposure.LimitAmount,
+		Exposure:     exposure.Exposure,
+		OrderAmount:  orderAmount,
+		Status:       models.CreditHoldHeld,
+		HeldBy:       username,
+		HeldAt:       time.Now(),
+	}, nil
+}
@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 單一附件大小上限
+const maxAttachmentSize = 10 << 20
+
+// 查詢往來紀錄，可依 customer_id、user_id（業務）、type、from / to (YYYY-MM-DD) 篩選；
+// 路徑有 :id 時限定該客戶
+func GetCustomerActivities(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("occurred_at DESC, id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	customerID := c.Param("id")
+	if customerID == "" {
+		customerID = c.Query("customer_id")
+	}
+	if customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if v := c.Query("user_id"); v != "" {
+		query = query.Where("user_id = ?", v)
+	}
+	if v := c.Query("type"); v != "" {
+		query = query.Where("type = ?", v)
+	}
+	if v := c.Query("from"); v != "" {
+		query = query.Where("occurred_at >= ?", v)
+	}
+	if v := c.Query("to"); v != "" {
+		query = query.Where("occurred_at < (?::date + 1)", v)
+	}
+	var activities []models.CustomerActivity
+	if err := query.Preload("Attachments", omitAttachmentData).Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢往來紀錄失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, activities)
+}
+
+// 查詢單一往來紀錄
+func GetCustomerActivity(c *gin.Context) {
+	activity, ok := findActivity(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, activity)
+}
+
+// 新增往來紀錄，歸屬於目前所在公司
+func CreateCustomerActivity(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	_, companyID, ok := getRoleAndCompanyID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var req models.CustomerActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	activity := models.CustomerActivity{
+		CustomerID: customer.ID,
+		CompanyID:  companyID,
+		CreatedBy:  c.GetString("username"),
+		OccurredAt: time.Now(),
+	}
+	if !applyActivityRequest(c, &activity, req) {
+		return
+	}
+	if err := db.DB.Create(&activity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立往來紀錄失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, activity)
+}
+
+// 修改往來紀錄（記錄者本人或管理員）
+func UpdateCustomerActivity(c *gin.Context) {
+	activity, ok := findEditableActivity(c)
+	if !ok {
+		return
+	}
+	var req models.CustomerActivityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if req.UserID == nil {
+		req.UserID = &activity.UserID
+	}
+	if !applyActivityRequest(c, &activity, req) {
+		return
+	}
+	if err := db.DB.Omit("Attachments").Save(&activity).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新往來紀錄失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, activity)
+}
+
+// 刪除往來紀錄與附件（記錄者本人或管理員）
+func DeleteCustomerActivity(c *gin.Context) {
+	activity, ok := findEditableActivity(c)
+	if !ok {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("activity_id = ?", activity.ID).Delete(&models.ActivityAttachment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&activity).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除往來紀錄失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "往來紀錄刪除成功"})
+}
+
+// 上傳附件 (multipart/form-data，欄位名稱 file)
+func UploadActivityAttachment(c *gin.Context) {
+	activity, ok := findEditableActivity(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請以 file 欄位上傳檔案"})
+		return
+	}
+	if header.Size > maxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "附件大小不可超過 10MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentSize+1))
+	if err != nil || len(data) > maxAttachmentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗或檔案過大"})
+		return
+	}
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	attachment := models.ActivityAttachment{
+		ActivityID:  activity.ID,
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Data:        data,
+		UploadedBy:  c.GetString("username"),
+	}
+	if err := db.DB.Create(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "儲存附件失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// 下載附件
+func DownloadActivityAttachment(c *gin.Context) {
+	activity, ok := findActivity(c)
+	if !ok {
+		return
+	}
+	var attachment models.ActivityAttachment
+	if err := db.DB.Where("activity_id = ?", activity.ID).First(&attachment, c.Param("attachmentId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的附件"})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(attachment.FileName))
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Data)
+}
+
+// 刪除附件
+func DeleteActivityAttachment(c *gin.Context) {
+	activity, ok := findEditableActivity(c)
+	if !ok {
+		return
+	}
+	result := db.DB.Where("activity_id = ? AND id = ?", activity.ID, c.Param("attachmentId")).Delete(&models.ActivityAttachment{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除附件失敗: " + result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的附件"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "附件刪除成功"})
+}
+
+// omitAttachmentData 列表與明細只回傳附件資訊，不載入檔案內容
+func omitAttachmentData(q *gorm.DB) *gorm.DB {
+	return q.Omit("data").Order("id")
+}
+
+// findActivity 依路徑 :activityId 載入往來紀錄並檢查公司範圍
+func findActivity(c *gin.Context) (models.CustomerActivity, bool) {
+	var activity models.CustomerActivity
+	err := db.DB.Preload("Attachments", omitAttachmentData).First(&activity, c.Param("activityId")).Error
+	if err != nil || !canAccessCompany(c, activity.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的往來紀錄"})
+		return activity, false
+	}
+	return activity, true
+}
+
+// findEditableActivity 往來紀錄僅記錄者本人或管理員可修改
+func findEditableActivity(c *gin.Context) (models.CustomerActivity, bool) {
+	activity, ok := findActivity(c)
+	if !ok {
+		return activity, false
+	}
+	role, _, _ := getRoleAndCompanyID(c)
+	if role != models.RoleSuperAdmin && role != models.RoleCompanyAdmin && activity.CreatedBy != c.GetString("username") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "僅記錄者本人或管理員可修改往來紀錄"})
+		return activity, false
+	}
+	return activity, true
+}
+
+// applyActivityRequest 檢查並寫入往來紀錄欄位（未存檔）
+func applyActivityRequest(c *gin.Context, activity *models.CustomerActivity, req models.CustomerActivityRequest) bool {
+	valid := false
+	for _, t := range models.ActivityTypes {
+		if req.Type == t {
+			valid = true
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "類型需為 " + strings.Join(models.ActivityTypes, ", ")})
+		return false
+	}
+	req.Subject = strings.TrimSpace(req.Subject)
+	if req.Subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "主旨為必填"})
+		return false
+	}
+	if req.ContactID != nil {
+		var contact models.CustomerContact
+		if err := db.DB.First(&contact, *req.ContactID).Error; err != nil || contact.CustomerID != activity.CustomerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "聯絡人不屬於此客戶"})
+			return false
+		}
+	}
+	if req.UserID != nil {
+		var user models.User
+		if err := db.DB.First(&user, *req.UserID).Error; err != nil || !canAccessCompany(c, user.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的業務帳號"})
+			return false
+		}
+		activity.UserID = user.ID
+	} else {
+		user, ok := currentUser(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "請以 user_id 指定負責業務"})
+			return false
+		}
+		activity.UserID = user.ID
+	}
+
+	activity.ContactID = req.ContactID
+	activity.Type = req.Type
+	activity.Subject = req.Subject
+	activity.Content = req.Content
+	if req.OccurredAt != nil {
+		activity.OccurredAt = *req.OccurredAt
+	}
+	return true
+}
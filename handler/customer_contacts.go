@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢客戶聯絡人（主要聯絡人排在最前）
+func GetCustomerContacts(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	var contacts []models.CustomerContact
+	if err := db.DB.Where("customer_id = ?", customer.ID).Order("is_primary DESC, name").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢聯絡人失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, contacts)
+}
+
+// 新增客戶聯絡人；設為主要聯絡人時取消其他人的主要標記
+func CreateCustomerContact(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	var contact models.CustomerContact
+	if err := c.ShouldBindJSON(&contact); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	contact.ID = 0
+	contact.CustomerID = customer.ID
+	contact.Name = strings.TrimSpace(contact.Name)
+	if contact.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "聯絡人姓名為必填"})
+		return
+	}
+	if contact.SiteID != nil && !siteBelongsTo(customer.ID, *contact.SiteID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "據點不屬於此客戶"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if contact.IsPrimary {
+			if err := clearPrimaryContact(tx, customer.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&contact).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立聯絡人失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, contact)
+}
+
+// 部分更新客戶聯絡人 (JSON Merge Patch)
+func PatchCustomerContact(c *gin.Context) {
+	var contact models.CustomerContact
+	if err := db.DB.First(&contact, c.Param("contactId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的聯絡人"})
+		return
+	}
+	updates, err := bindMergePatch(c, &contact,
+		"site_id", "name", "title", "email", "phone", "language", "is_primary", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if name, ok := updates["name"].(string); ok {
+		updates["name"] = strings.TrimSpace(name)
+		if updates["name"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "聯絡人姓名為必填"})
+			return
+		}
+	}
+	if siteID, ok := updates["site_id"].(*uint); ok && siteID != nil && !siteBelongsTo(contact.CustomerID, *siteID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "據點不屬於此客戶"})
+		return
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if primary, ok := updates["is_primary"].(bool); ok && primary {
+			if err := clearPrimaryContact(tx, contact.CustomerID); err != nil {
+				return err
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&contact).Updates(updates).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新聯絡人失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&contact, contact.ID)
+	c.JSON(http.StatusOK, contact)
+}
+
+// 刪除客戶聯絡人，往來紀錄中的聯絡人欄位一併清空
+func DeleteCustomerContact(c *gin.Context) {
+	var contact models.CustomerContact
+	if err := db.DB.First(&contact, c.Param("contactId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的聯絡人"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.CustomerActivity{}).Where("contact_id = ?", contact.ID).
+			Update("contact_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&contact).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除聯絡人失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "聯絡人刪除成功"})
+}
+
+func clearPrimaryContact(tx *gorm.DB, customerID uint) error {
+	return tx.Model(&models.CustomerContact{}).
+		Where("customer_id = ? AND is_primary = ?", customerID, true).
+		Update("is_primary", false).Error
+}
+
+func siteBelongsTo(customerID, siteID uint) bool {
+	var count int64
+	db.DB.Model(&models.CustomerSite{}).Where("id = ? AND customer_id = ?", siteID, customerID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢客戶據點，可用 ?role=ship_to 篩選角色
+func GetCustomerSites(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	query := db.DB.Where("customer_id = ?", customer.ID).Order("site_code")
+	if role := c.Query("role"); role != "" {
+		if err := validateSiteRoles([]string{role}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("roles @> ?::jsonb", `["`+role+`"]`)
+	}
+	var sites []models.CustomerSite
+	if err := query.Find(&sites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢客戶據點失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sites)
+}
+
+// 查詢單一客戶據點
+func GetCustomerSite(c *gin.Context) {
+	var site models.CustomerSite
+	if err := db.DB.First(&site, c.Param("siteId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶據點"})
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+// 新增客戶據點
+func CreateCustomerSite(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	var site models.CustomerSite
+	if err := c.ShouldBindJSON(&site); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	site.ID = 0
+	site.CustomerID = customer.ID
+	site.IsActive = true
+	site.SiteCode = strings.TrimSpace(site.SiteCode)
+	site.Name = strings.TrimSpace(site.Name)
+	if site.SiteCode == "" || site.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "據點代碼與名稱為必填"})
+		return
+	}
+	if err := validateSiteRoles(site.Roles); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if siteCodeTaken(customer.ID, site.SiteCode, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "據點代碼已存在"})
+		return
+	}
+	if err := db.DB.Create(&site).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立客戶據點失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, site)
+}
+
+// 部分更新客戶據點 (JSON Merge Patch)，停用請設 is_active = false
+func PatchCustomerSite(c *gin.Context) {
+	var site models.CustomerSite
+	if err := db.DB.First(&site, c.Param("siteId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶據點"})
+		return
+	}
+	updates, err := bindMergePatch(c, &site,
+		"site_code", "name", "roles", "address_line1", "address_line2", "city", "state", "postal_code",
+		"country", "tax_id", "contact_name", "contact_phone", "contact_email", "is_active", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	for _, key := range []string{"site_code", "name"} {
+		if v, ok := updates[key].(string); ok {
+			updates[key] = strings.TrimSpace(v)
+			if updates[key] == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "據點代碼與名稱為必填"})
+				return
+			}
+		}
+	}
+	if code, ok := updates["site_code"].(string); ok && siteCodeTaken(site.CustomerID, code, site.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "據點代碼已存在"})
+		return
+	}
+	if roles, ok := updates["roles"].([]string); ok {
+		if err := validateSiteRoles(roles); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// jsonb 欄位需經 serializer 轉換，改以 struct 欄位寫入
+		delete(updates, "roles")
+		if err := db.DB.Model(&site).Select("roles").Updates(models.CustomerSite{Roles: roles}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新客戶據點失敗: " + err.Error()})
+			return
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(&site).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新客戶據點失敗: " + err.Error()})
+			return
+		}
+	}
+	db.DB.First(&site, site.ID)
+	c.JSON(http.StatusOK, site)
+}
+
+// 刪除客戶據點；已被交易條件或單據引用時請改為停用
+func DeleteCustomerSite(c *gin.Context) {
+	var site models.CustomerSite
+	if err := db.DB.First(&site, c.Param("siteId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶據點"})
+		return
+	}
+	siteRef := "sold_to_site_id = ? OR ship_to_site_id = ? OR bill_to_site_id = ?"
+	for _, model := range []interface{}{&models.CustomerTransactionTerm{}, &models.Quotation{}, &models.SalesOrder{}} {
+		var count int64
+		db.DB.Model(model).Where(siteRef, site.ID, site.ID, site.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "此據點已被交易條件或單據引用，請改為停用"})
+			return
+		}
+	}
+	if err := db.DB.Delete(&site).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除客戶據點失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "客戶據點刪除成功"})
+}
+
+func validateSiteRoles(roles []string) error {
+	if len(roles) == 0 {
+		return fmt.Errorf("至少需指定一個據點角色 (%s)", strings.Join(models.SiteRoles, ", "))
+	}
+	for _, r := range roles {
+		valid := false
+		for _, known := range models.SiteRoles {
+			if r == known {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("未知的據點角色 %s", r)
+		}
+	}
+	return nil
+}
+
+func siteCodeTaken(customerID uint, code string, exceptID uint) bool {
+	var count int64
+	db.DB.Model(&models.CustomerSite{}).Where("customer_id = ? AND site_code = ? AND id <> ?", customerID, code, exceptID).Count(&count)
+	return count > 0
+}
+
+// checkSite 檢查據點屬於該客戶、啟用中且具有指定角色
+func checkSite(customerID, siteID uint, role string) error {
+	var site models.CustomerSite
+	if err := db.DB.First(&site, siteID).Error; err != nil || site.CustomerID != customerID {
+		return fmt.Errorf("據點 %d 不屬於此客戶", siteID)
+	}
+	if !site.IsActive {
+		return fmt.Errorf("據點 %s 已停用", site.SiteCode)
+	}
+	if !site.HasRole(role) {
+		return fmt.Errorf("據點 %s 不具 %s 角色", site.SiteCode, role)
+	}
+	return nil
+}
+
+// checkTermSites 檢查交易條件或單據指定的下單、送貨、請款據點
+func checkTermSites(customerID uint, soldTo, shipTo, billTo *uint) error {
+	for _, ref := range []struct {
+		id   *uint
+		role string
+	}{{soldTo, models.SiteRoleSoldTo}, {shipTo, models.SiteRoleShipTo}, {billTo, models.SiteRoleBillTo}} {
+		if ref.id == nil {
+			continue
+		}
+		if err := checkSite(customerID, *ref.id, ref.role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
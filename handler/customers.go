@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strconv"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // --- 建立新客戶 ---
@@ -44,6 +46,8 @@ func GetCustomerByID(c *gin.Context) {
 	var terms []models.CustomerTransactionTerm
 	db.DB.Where("customer_id = ?", customer.ID).Find(&terms)
 	customer.TransactionTerms = terms
+	// 查詢該客戶的據點
+	db.DB.Where("customer_id = ?", customer.ID).Order("site_code").Find(&customer.Sites)
 	c.JSON(http.StatusOK, customer)
 }
 
@@ -88,5 +92,188 @@ func GetCustomerByCode(c *gin.Context) {
 	var terms []models.CustomerTransactionTerm
 	db.DB.Where("customer_id = ?", customer.ID).Find(&terms)
 	customer.TransactionTerms = terms
+	// 查詢該客戶的據點
+	db.DB.Where("customer_id = ?", customer.ID).Order("site_code").Find(&customer.Sites)
 	c.JSON(http.StatusOK, customer)
 }
+
+// --- 部分更新客戶主檔 (JSON Merge Patch) ---
+func PatchCustomer(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	updates, err := bindMergePatch(c, &customer, "group_customer_code", "group_customer_name", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(&customer).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新客戶失敗: " + err.Error()})
+			return
+		}
+	}
+	db.DB.First(&customer, customer.ID)
+	c.JSON(http.StatusOK, customer)
+}
+
+// --- 部分更新客戶交易條件 (JSON Merge Patch) ---
+func PatchCustomerTransactionTerm(c *gin.Context) {
+	var term models.CustomerTransactionTerm
+	if err := db.DB.First(&term, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的交易條件"})
+		return
+	}
+	updates, err := bindMergePatch(c, &term,
+		"company_id", "incoterm", "currency_code", "commission_rate",
+		"export_port", "destination_country", "is_primary", "remarks", "agent_id",
+		"sold_to_site_id", "ship_to_site_id", "bill_to_site_id", "shipping_mark")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if agentID, ok := updates["agent_id"].(*uint); ok && agentID != nil && !agentUsable(*agentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "指定的業務不存在或已停用"})
+		return
+	}
+	soldTo, _ := updates["sold_to_site_id"].(*uint)
+	shipTo, _ := updates["ship_to_site_id"].(*uint)
+	billTo, _ := updates["bill_to_site_id"].(*uint)
+	if err := checkTermSites(term.CustomerID, soldTo, shipTo, billTo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	primary, companyID := term.IsPrimary, term.CompanyID
+	if v, ok := updates["is_primary"].(bool); ok {
+		primary = v
+	}
+	if v, ok := updates["company_id"].(uint); ok {
+		companyID = v
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if primary {
+			if err := clearPrimaryTerm(tx, term.CustomerID, companyID, term.ID); err != nil {
+				return err
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&term).Updates(updates).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新交易條件失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&term, term.ID)
+	c.JSON(http.StatusOK, term)
+}
+
+// --- 查詢客戶的交易條件 ---
+func GetCustomerTransactionTerms(c *gin.Context) {
+	var terms []models.CustomerTransactionTerm
+	if err := db.DB.Where("customer_id = ?", c.Param("id")).Order("company_id, id").Find(&terms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢交易條件失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+// --- 新增客戶交易條件 ---
+func CreateCustomerTransactionTerm(c *gin.Context) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的客戶"})
+		return
+	}
+	var term models.CustomerTransactionTerm
+	if err := c.ShouldBindJSON(&term); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	term.ID = 0
+	term.CustomerID = customer.ID
+	if !checkTransactionTerm(c, term) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if term.IsPrimary {
+			if err := clearPrimaryTerm(tx, term.CustomerID, term.CompanyID, 0); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&term).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立交易條件失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, term)
+}
+
+// --- 更新客戶交易條件（整筆取代） ---
+func UpdateCustomerTransactionTerm(c *gin.Context) {
+	var existing models.CustomerTransactionTerm
+	if err := db.DB.First(&existing, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的交易條件"})
+		return
+	}
+	var term models.CustomerTransactionTerm
+	if err := c.ShouldBindJSON(&term); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	term.ID = existing.ID
+	term.CustomerID = existing.CustomerID
+	if !checkTransactionTerm(c, term) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if term.IsPrimary {
+			if err := clearPrimaryTerm(tx, term.CustomerID, term.CompanyID, term.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&existing).Select("*").Omit("id", "customer_id").Updates(term).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新交易條件失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, term)
+}
+
+// --- 刪除客戶交易條件 ---
+func DeleteCustomerTransactionTerm(c *gin.Context) {
+	if err := db.DB.Delete(&models.CustomerTransactionTerm{}, c.Param("termId")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除交易條件失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "交易條件刪除成功"})
+}
+
+// clearPrimaryTerm 取消客戶在該公司其他交易條件的主要設定，每個客戶在每間公司只有一筆主要交易條件；
+// 先鎖定客戶資料，避免同時設定兩筆主要交易條件
+func clearPrimaryTerm(tx *gorm.DB, customerID, companyID, exceptID uint) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Customer{}, customerID).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.CustomerTransactionTerm{}).
+		Where("customer_id = ? AND company_id = ? AND is_primary = ? AND id <> ?", customerID, companyID, true, exceptID).
+		Update("is_primary", false).Error
+}
+
+// checkTransactionTerm 檢查交易條件的業務與據點，失敗時已回應錯誤
+func checkTransactionTerm(c *gin.Context, term models.CustomerTransactionTerm) bool {
+	if term.AgentID != nil && !agentUsable(*term.AgentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "指定的業務不存在或已停用"})
+		return false
+	}
+	if err := checkTermSites(term.CustomerID, term.SoldToSiteID, term.ShipToSiteID, term.BillToSiteID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 採用公司流水號的單據類型與預設前綴
+var defaultSequencePrefixes = map[string]string{
+	models.InvoiceProforma:         "PI",
+	models.InvoiceCommercial:       "CI",
+	models.StockReceipt:            "GR",
+	models.StockIssue:              "GI",
+	models.StockTransfer:           "TR",
+	models.StockAdjustment:         "ADJ",
+	models.ProductionOrderSequence: "MO",
+	models.QuotationSequence:       "QT",
+	models.SalesOrderSequence:      "SO",
+	models.ShipmentSequence:        "SH",
+}
+
+// 改用公司流水號前已依單號最大值編號的單據，建立流水號時由公司既有的最大號接續
+var legacyNumberedTables = map[string][2]string{
+	models.QuotationSequence:  {"quotations", "quote_no"},
+	models.SalesOrderSequence: {"sales_orders", "order_no"},
+	models.ShipmentSequence:   {"sales_order_shipments", "shipment_no"},
+}
+
+// 查詢公司單據流水號設定 (?company_id 預設目前公司)
+func GetDocumentSequences(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("company_id, doc_type, year DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("company_id"); v != "" {
+		query = query.Where("company_id = ?", v)
+	}
+	var sequences []models.DocumentSequence
+	if err := query.Find(&sequences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢流水號失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sequences)
+}
+
+// 設定公司單據流水號的前綴與下一號；下一號不可小於目前值，避免重號
+func PutDocumentSequence(c *gin.Context) {
+	var req models.DocumentSequence
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if _, known := defaultSequencePrefixes[req.DocType]; !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的單據類型 " + req.DocType})
+		return
+	}
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	if req.Year == 0 {
+		req.Year = time.Now().Year()
+	}
+	req.Prefix = strings.TrimSpace(req.Prefix)
+
+	var seq models.DocumentSequence
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if seq, err = lockSequence(tx, req.CompanyID, req.DocType, req.Year); err != nil {
+			return err
+		}
+		if req.NextNo != 0 && req.NextNo < seq.NextNo {
+			return newRequestError(http.StatusConflict, "下一號不可小於目前的 %d", seq.NextNo)
+		}
+		if req.NextNo != 0 {
+			seq.NextNo = req.NextNo
+		}
+		if req.Prefix != "" {
+			seq.Prefix = req.Prefix
+		}
+		return tx.Save(&seq).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "設定流水號")
+		return
+	}
+	c.JSON(http.StatusOK, seq)
+}
+
+// nextSequenceNo 取得公司單據的下一個單號（前綴 + 年度 - 流水號），需於交易中呼叫
+func nextSequenceNo(tx *gorm.DB, companyID uint, docType string, date time.Time) (string, error) {
+	seq, err := lockSequence(tx, companyID, docType, date.Year())
+	if err != nil {
+		return "", err
+	}
+	no := fmt.Sprintf("%s%d-%05d", seq.Prefix, seq.Year, seq.NextNo)
+	if err := tx.Model(&seq).Update("next_no", seq.NextNo+1).Error; err != nil {
+		return "", err
+	}
+	return no, nil
+}
+
+// lockSequence 鎖定（必要時建立）公司單據流水號；新年度沿用前一年度的前綴
+func lockSequence(tx *gorm.DB, companyID uint, docType string, year int) (models.DocumentSequence, error) {
+	seq := models.DocumentSequence{CompanyID: companyID, DocType: docType, Year: year, Prefix: defaultSequencePrefixes[docType], NextNo: 1}
+	var previous models.DocumentSequence
+	if err := tx.Where("company_id = ? AND doc_type = ?", companyID, docType).Order("year DESC").First(&previous).Error; err == nil {
+		seq.Prefix = previous.Prefix
+	}
+	if legacy, ok := legacyNumberedTables[docType]; ok && previous.Year != year {
+		p := fmt.Sprintf("%s%d-", seq.Prefix, year)
+		var last []string
+		if err := tx.Table(legacy[0]).Where("company_id = ? AND "+legacy[1]+" LIKE ?", companyID, p+"%").
+			Order(legacy[1]+" DESC").Limit(1).Pluck(legacy[1], &last).Error; err != nil {
+			return seq, err
+		}
+		if len(last) > 0 {
+			n, _ := strconv.Atoi(strings.TrimPrefix(last[0], p))
+			seq.NextNo = n + 1
+		}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+		return seq, err
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? AND doc_type = ? AND year = ?", companyID, docType, year).
+		First(&seq).Error
+	return seq, err
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/docrender"
+	"fastener-api/models"
+)
+
+// Logo 檔案大小上限
+const maxLogoSize = 1 << 20
+
+// 查詢單據範本，可依 company_id、doc_type 篩選
+func GetDocumentTemplates(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Omit("logo").Order("company_id, doc_type"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "doc_type"} {
+		if v, exists := c.GetQuery(f); exists {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var templates []models.DocumentTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢單據範本失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// 設定公司單據範本（依 company_id + doc_type 新增或覆寫）；doc_type 空白為公司預設範本
+func PutDocumentTemplate(c *gin.Context) {
+	var req models.DocumentTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	valid := req.DocType == ""
+	for _, t := range models.DocumentTypes {
+		if req.DocType == t {
+			valid = true
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "單據類型需為 " + strings.Join(models.DocumentTypes, ", ") + " 或空白"})
+		return
+	}
+	req.Language = strings.TrimSpace(req.Language)
+	if req.Language != "" && !docrender.Supported(req.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支援的單據語言 " + req.Language})
+		return
+	}
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+
+	var tpl models.DocumentTemplate
+	db.DB.Where("company_id = ? AND doc_type = ?", req.CompanyID, req.DocType).First(&tpl)
+	tpl.CompanyID = req.CompanyID
+	tpl.DocType = req.DocType
+	tpl.Language = req.Language
+	tpl.CompanyName = strings.TrimSpace(req.CompanyName)
+	tpl.Address = strings.TrimSpace(req.Address)
+	tpl.Contact = strings.TrimSpace(req.Contact)
+	tpl.TaxID = strings.TrimSpace(req.TaxID)
+	tpl.Footer = strings.TrimSpace(req.Footer)
+	tpl.UpdatedBy = c.GetString("username")
+	if err := db.DB.Save(&tpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "儲存單據範本失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// 上傳範本 Logo (multipart/form-data，欄位名稱 file，限 JPEG)
+func UploadDocumentTemplateLogo(c *gin.Context) {
+	tpl, ok := findDocumentTemplate(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請以 file 欄位上傳檔案"})
+		return
+	}
+	if header.Size > maxLogoSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Logo 大小不可超過 1MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxLogoSize+1))
+	if err != nil || len(data) > maxLogoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗或檔案過大"})
+		return
+	}
+	if err := docrender.ValidateLogo(data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tpl.Logo = data
+	tpl.LogoContentType = "image/jpeg"
+	tpl.UpdatedBy = c.GetString("username")
+	if err := db.DB.Model(&tpl).Select("logo", "logo_content_type", "updated_by").Updates(&tpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "儲存 Logo 失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tpl)
+}
+
+// 刪除單據範本；已產生的單據保有當時的範本快照，不受影響
+func DeleteDocumentTemplate(c *gin.Context) {
+	tpl, ok := findDocumentTemplate(c)
+	if !ok {
+		return
+	}
+	if err := db.DB.Delete(&tpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除單據範本失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "單據範本刪除成功"})
+}
+
+// findDocumentTemplate 依路徑 :id 載入範本並檢查公司範圍
+func findDocumentTemplate(c *gin.Context) (models.DocumentTemplate, bool) {
+	var tpl models.DocumentTemplate
+	if err := db.DB.First(&tpl, c.Param("id")).Error; err != nil || !canAccessCompany(c, tpl.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的單據範本"})
+		return tpl, false
+	}
+	return tpl, true
+}
+
+// loadDocumentTemplate 組出公司單據的排版範本：單據類型範本優先，其次公司預設範本，
+// 未設定的名稱與語言沿用公司主檔
+func loadDocumentTemplate(companyID uint, docType string) (docrender.Template, error) {
+	var company models.Company
+	if err := db.DB.First(&company, companyID).Error; err != nil {
+		return docrender.Template{}, err
+	}
+	var tpl models.DocumentTemplate
+	db.DB.Where("company_id = ? AND doc_type IN ?", companyID, []string{docType, ""}).
+		Order("doc_type DESC").First(&tpl)
+
+	out := docrender.Template{
+		Language:    firstNonEmpty(tpl.Language, company.Language, docrender.DefaultLanguage),
+		CompanyName: firstNonEmpty(tpl.CompanyName, company.Name),
+		Contact:     tpl.Contact,
+		TaxID:       tpl.TaxID,
+		Footer:      tpl.Footer,
+		Logo:        tpl.Logo,
+	}
+	for _, line := range strings.Split(tpl.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out.Address = append(out.Address, line)
+		}
+	}
+	return out, nil
+}
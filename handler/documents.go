@@ -0,0 +1,574 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/docrender"
+	"fastener-api/models"
+)
+
+// 各單據類型的來源資料所需的查詢權限（另需 documents 權限）
+var documentSourcePermissions = map[string]string{
+	models.DocQuotation:         models.PermSalesRead,
+	models.DocInvoice:           models.PermInvoicesRead,
+	models.DocPackingList:       models.PermSalesRead,
+	models.DocOrderConfirmation: models.PermSalesRead,
+	models.DocInspectionCert:    models.PermQualityRead,
+}
+
+// documentSnapshot 產生單據時保存的排版輸入
+type documentSnapshot struct {
+	Template docrender.Template `json:"template"`
+	Document docrender.Document `json:"document"`
+}
+
+// 查詢單據已產生的版本
+func GetRenderedDocuments(c *gin.Context) {
+	if !canRenderSource(c) {
+		return
+	}
+	query, ok := scopeByCompany(c, db.DB.Omit("snapshot", "pdf").Order("version DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var docs []models.RenderedDocument
+	err := query.Where("doc_type = ? AND source_id = ?", c.Param("docType"), c.Param("id")).Find(&docs).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢單據版本失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, docs)
+}
+
+// 依目前資料與公司範本產生新版本單據並下載 PDF；PDF 與排版輸入快照一併存檔
+func RenderDocument(c *gin.Context) {
+	if !canRenderSource(c) {
+		return
+	}
+	docType := c.Param("docType")
+	companyID, docNo, build, ok := loadDocumentSource(c, docType)
+	if !ok {
+		return
+	}
+	tpl, err := loadDocumentTemplate(companyID, docType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "載入單據範本失敗: " + err.Error()})
+		return
+	}
+	doc, err := build(tpl.Language)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "組合單據內容失敗: " + err.Error()})
+		return
+	}
+	snapshot, err := json.Marshal(documentSnapshot{Template: tpl, Document: doc})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存單據快照失敗: " + err.Error()})
+		return
+	}
+	pdf, err := docrender.Render(tpl, doc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生 PDF 失敗: " + err.Error()})
+		return
+	}
+
+	sourceID, _ := strconv.ParseUint(c.Param("id"), 10, 64)
+	rendered := models.RenderedDocument{
+		CompanyID:  companyID,
+		DocType:    docType,
+		SourceID:   uint(sourceID),
+		DocumentNo: docNo,
+		Language:   tpl.Language,
+		Snapshot:   snapshot,
+		PDF:        pdf,
+		Checksum:   checksum(pdf),
+		Size:       len(pdf),
+		RenderedBy: c.GetString("username"),
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		tx.Model(&models.RenderedDocument{}).
+			Where("doc_type = ? AND source_id = ?", rendered.DocType, rendered.SourceID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest)
+		rendered.Version = latest + 1
+		rendered.FileName = fmt.Sprintf("%s-v%d.pdf", docNo, rendered.Version)
+		return tx.Create(&rendered).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "保存單據版本")
+		return
+	}
+	sendPDF(c, rendered, pdf)
+}
+
+// 下載指定版本：回傳產生時保存的 PDF；保存 PDF 前產生的舊版本由快照重新產生，
+// 並以 checksum 確認與原始文件完全一致
+func DownloadRenderedDocument(c *gin.Context) {
+	var rendered models.RenderedDocument
+	if err := db.DB.First(&rendered, c.Param("id")).Error; err != nil || !canAccessCompany(c, rendered.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的單據"})
+		return
+	}
+	if !hasSourcePermission(c, rendered.DocType) {
+		return
+	}
+	if len(rendered.PDF) > 0 {
+		sendPDF(c, rendered, rendered.PDF)
+		return
+	}
+	var snap documentSnapshot
+	if err := json.Unmarshal(rendered.Snapshot, &snap); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "讀取單據快照失敗: " + err.Error()})
+		return
+	}
+	pdf, err := docrender.Render(snap.Template, snap.Document)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生 PDF 失敗: " + err.Error()})
+		return
+	}
+	if checksum(pdf) != rendered.Checksum {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重新產生的文件與原始版本不一致（checksum 不符）"})
+		return
+	}
+	sendPDF(c, rendered, pdf)
+}
+
+func sendPDF(c *gin.Context, rendered models.RenderedDocument, pdf []byte) {
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(rendered.FileName))
+	c.Header("X-Document-Id", strconv.FormatUint(uint64(rendered.ID), 10))
+	c.Header("X-Document-Version", strconv.Itoa(rendered.Version))
+	c.Header("X-Document-Checksum", rendered.Checksum)
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canRenderSource 檢查路徑 :docType 是否有效，以及登入者是否可查詢來源資料
+func canRenderSource(c *gin.Context) bool {
+	if _, known := documentSourcePermissions[c.Param("docType")]; !known {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "單據類型需為 " + strings.Join(models.DocumentTypes, ", ")})
+		return false
+	}
+	return hasSourcePermission(c, c.Param("docType"))
+}
+
+func hasSourcePermission(c *gin.Context, docType string) bool {
+	permissions, _ := c.Get("permissions")
+	granted, _ := permissions.([]string)
+	required := documentSourcePermissions[docType]
+	if !models.HasPermission(c.GetString("role"), granted, required) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足", "required_permission": required})
+		return false
+	}
+	return true
+}
+
+// documentBuilder 依語言組出單據內容
+type documentBuilder func(language string) (docrender.Document, error)
+
+// loadDocumentSource 載入路徑 :id 指定的來源資料並檢查公司範圍
+func loadDocumentSource(c *gin.Context, docType string) (uint, string, documentBuilder, bool) {
+	switch docType {
+	case models.DocQuotation:
+		quote, ok := findQuotation(c)
+		if !ok {
+			return 0, "", nil, false
+		}
+		return quote.CompanyID, quote.QuoteNo, func(lang string) (docrender.Document, error) {
+			return quotationDocument(quote, lang)
+		}, true
+	case models.DocInvoice:
+		invoice, ok := findInvoice(c, db.DB)
+		if !ok {
+			return 0, "", nil, false
+		}
+		if invoice.Status == models.InvoiceCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "已作廢的發票不可產生單據"})
+			return 0, "", nil, false
+		}
+		return invoice.CompanyID, invoice.InvoiceNo, func(lang string) (docrender.Document, error) {
+			return invoiceDocument(invoice, lang)
+		}, true
+	case models.DocPackingList:
+		shipment, order, ok := findShipment(c)
+		if !ok {
+			return 0, "", nil, false
+		}
+		return order.CompanyID, shipment.ShipmentNo, func(lang string) (docrender.Document, error) {
+			return packingListDocument(order, shipment, lang)
+		}, true
+	case models.DocOrderConfirmation:
+		order, ok := findSalesOrder(c, db.DB)
+		if !ok {
+			return 0, "", nil, false
+		}
+		if order.ConfirmedAt == nil || order.Status == models.OrderCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "訂單尚未確認，無法產生訂單確認書"})
+			return 0, "", nil, false
+		}
+		return order.CompanyID, order.OrderNo, func(lang string) (docrender.Document, error) {
+			return orderConfirmationDocument(order, lang)
+		}, true
+	case models.DocInspectionCert:
+		line, shipment, order, ok := findShipmentLine(c)
+		if !ok {
+			return 0, "", nil, false
+		}
+		var orderLine models.SalesOrderLine
+		for _, l := range order.Lines {
+			if l.ID == line.SalesOrderLineID {
+				orderLine = l
+			}
+		}
+		lots, err := inspectionCertificateLots(shipment, orderLine)
+		if err != nil {
+			respondTxError(c, err, "產生檢驗證明書")
+			return 0, "", nil, false
+		}
+		certNo := fmt.Sprintf("%s-%02d", shipment.ShipmentNo, orderLine.LineNo)
+		return order.CompanyID, certNo, func(lang string) (docrender.Document, error) {
+			return inspectionCertificateDocument(order, shipment, line, orderLine, certNo, lots, lang)
+		}, true
+	}
+	return 0, "", nil, false
+}
+
+func quotationDocument(quote models.Quotation, lang string) (docrender.Document, error) {
+	buyer, err := siteParty(lang, "buyer", quote.CustomerID, quote.SoldToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	doc := docrender.Document{
+		Title: docrender.Label(lang, "quotation"),
+		Fields: nonEmptyFields(lang,
+			"doc_no", quote.QuoteNo,
+			"date", formatDate(&quote.CreatedAt),
+			"valid_until", formatDate(quote.ValidUntil),
+			"incoterm", quote.Incoterm,
+			"currency", quote.CurrencyCode,
+			"port", quote.ExportPort,
+			"destination", quote.DestinationCountry),
+		Parties: []docrender.Party{buyer},
+		Columns: salesColumns(lang, true),
+		Totals:  []docrender.Field{{Label: docrender.Label(lang, "total"), Value: quote.CurrencyCode + " " + formatMoney(quote.TotalAmount)}},
+		Notes:   remarksNotes(lang, quote.Remarks),
+	}
+	for _, l := range quote.Lines {
+		doc.Rows = append(doc.Rows, []string{strconv.Itoa(l.LineNo), l.Description, formatQuantity(l.Quantity), l.Unit,
+			formatPrice(l.UnitPrice), formatMoney(l.Amount), formatDate(l.DeliveryDate)})
+	}
+	return doc, nil
+}
+
+func invoiceDocument(invoice models.Invoice, lang string) (docrender.Document, error) {
+	var order models.SalesOrder
+	if err := db.DB.First(&order, invoice.SalesOrderID).Error; err != nil {
+		return docrender.Document{}, err
+	}
+	billTo, err := siteParty(lang, "bill_to", invoice.CustomerID, invoice.BillToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	shipTo, err := siteParty(lang, "ship_to", invoice.CustomerID, order.ShipToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	title := "commercial_invoice"
+	if invoice.Type == models.InvoiceProforma {
+		title = "proforma_invoice"
+	}
+	doc := docrender.Document{
+		Title: docrender.Label(lang, title),
+		Fields: nonEmptyFields(lang,
+			"doc_no", invoice.InvoiceNo,
+			"date", formatDate(&invoice.InvoiceDate),
+			"due_date", formatDate(invoice.DueDate),
+			"order_no", order.OrderNo,
+			"customer_po", order.CustomerPONo,
+			"incoterm", order.Incoterm,
+			"currency", invoice.CurrencyCode,
+			"port", order.ExportPort,
+			"destination", order.DestinationCountry),
+		Parties: []docrender.Party{billTo, shipTo},
+		Columns: salesColumns(lang, false),
+		Totals: []docrender.Field{
+			{Label: docrender.Label(lang, "subtotal"), Value: formatMoney(invoice.SubtotalAmount)},
+			{Label: fmt.Sprintf("%s (%s%%)", docrender.Label(lang, "tax"), formatQuantity(invoice.TaxRate)), Value: formatMoney(invoice.TaxAmount)},
+			{Label: docrender.Label(lang, "total"), Value: invoice.CurrencyCode + " " + formatMoney(invoice.TotalAmount)},
+		},
+		Notes: remarksNotes(lang, invoice.Remarks),
+	}
+	for _, l := range invoice.Lines {
+		doc.Rows = append(doc.Rows, []string{strconv.Itoa(l.LineNo), l.Description, formatQuantity(l.Quantity), l.Unit,
+			formatPrice(l.UnitPrice), formatMoney(l.Amount)})
+	}
+	return doc, nil
+}
+
+// packingListDocument 已裝箱時依紙箱列出（相同內容、重量、尺寸的連續箱號合併為一列），
+// 尚未裝箱時僅列出出貨明細
+func packingListDocument(order models.SalesOrder, shipment models.SalesOrderShipment, lang string) (docrender.Document, error) {
+	buyer, err := siteParty(lang, "buyer", order.CustomerID, order.SoldToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	shipTo, err := siteParty(lang, "ship_to", order.CustomerID, order.ShipToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	vessel := strings.TrimSpace(shipment.Vessel + " " + shipment.VoyageNo)
+	doc := docrender.Document{
+		Title: docrender.Label(lang, "packing_list"),
+		Fields: nonEmptyFields(lang,
+			"shipment_no", shipment.ShipmentNo,
+			"date", formatDate(&shipment.ShippedAt),
+			"order_no", order.OrderNo,
+			"customer_po", order.CustomerPONo,
+			"incoterm", order.Incoterm,
+			"port", shipment.PortOfLoading,
+			"port_of_discharge", shipment.PortOfDischarge,
+			"destination", order.DestinationCountry,
+			"vessel", vessel,
+			"etd", formatDate(shipment.ETD),
+			"eta", formatDate(shipment.ETA)),
+		Parties: []docrender.Party{buyer, shipTo},
+	}
+	orderLines := make(map[uint]models.SalesOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		orderLines[l.ID] = l
+	}
+
+	list := buildPackingList(shipment, order)
+	if list.Cartons == 0 {
+		doc.Columns = []docrender.Column{
+			{Label: docrender.Label(lang, "line_no"), Width: 5},
+			{Label: docrender.Label(lang, "description"), Width: 60},
+			{Label: docrender.Label(lang, "quantity"), Width: 15, Right: true},
+			{Label: docrender.Label(lang, "unit"), Width: 10},
+		}
+		for i, sl := range shipment.Lines {
+			ol := orderLines[sl.SalesOrderLineID]
+			doc.Rows = append(doc.Rows, []string{strconv.Itoa(i + 1), ol.Description, formatQuantity(sl.Quantity), ol.Unit})
+		}
+		doc.Notes = remarksNotes(lang, shipment.Remarks)
+		return doc, nil
+	}
+
+	doc.Columns = []docrender.Column{
+		{Label: docrender.Label(lang, "carton_no"), Width: 9},
+		{Label: docrender.Label(lang, "description"), Width: 35},
+		{Label: docrender.Label(lang, "quantity"), Width: 11, Right: true},
+		{Label: docrender.Label(lang, "unit"), Width: 6},
+		{Label: docrender.Label(lang, "net_weight"), Width: 10, Right: true},
+		{Label: docrender.Label(lang, "gross_weight"), Width: 10, Right: true},
+		{Label: docrender.Label(lang, "measurement"), Width: 10, Right: true},
+	}
+	var cartons []models.ShipmentPackage
+	for _, p := range shipment.Packages {
+		if p.Type == models.PackageCarton {
+			cartons = append(cartons, p)
+		}
+	}
+	for start := 0; start < len(cartons); {
+		end := start + 1
+		for end < len(cartons) && sameCarton(cartons[start], cartons[end]) && cartons[end].PackageNo == cartons[end-1].PackageNo+1 {
+			end++
+		}
+		first, n := cartons[start], float64(end-start)
+		cartonNo := strconv.Itoa(first.PackageNo)
+		if end-start > 1 {
+			cartonNo += "-" + strconv.Itoa(cartons[end-1].PackageNo)
+		}
+		var descriptions, quantities, units []string
+		for _, content := range first.Contents {
+			ol := orderLines[content.SalesOrderLineID]
+			descriptions = append(descriptions, ol.Description)
+			quantities = append(quantities, formatQuantity(content.Quantity*n))
+			units = append(units, ol.Unit)
+		}
+		doc.Rows = append(doc.Rows, []string{cartonNo, strings.Join(descriptions, "\n"), strings.Join(quantities, "\n"),
+			strings.Join(units, "\n"), formatMoney(first.NetWeightKG * n), formatMoney(first.GrossWeightKG * n),
+			strconv.FormatFloat(roundCBM(first.CBM()*n), 'f', 3, 64)})
+		start = end
+	}
+
+	doc.Totals = append(doc.Totals, docrender.Field{Label: docrender.Label(lang, "total_cartons"), Value: strconv.Itoa(list.Cartons)})
+	if list.Pallets > 0 {
+		doc.Totals = append(doc.Totals, docrender.Field{Label: docrender.Label(lang, "total_pallets"), Value: strconv.Itoa(list.Pallets)})
+	}
+	doc.Totals = append(doc.Totals,
+		docrender.Field{Label: docrender.Label(lang, "net_weight"), Value: formatMoney(list.NetWeightKG)},
+		docrender.Field{Label: docrender.Label(lang, "gross_weight"), Value: formatMoney(list.GrossWeightKG)},
+		docrender.Field{Label: docrender.Label(lang, "measurement"), Value: strconv.FormatFloat(list.CBM, 'f', 3, 64)})
+	for _, ct := range shipment.Containers {
+		note := docrender.Label(lang, "container") + ": " + strings.TrimSpace(ct.ContainerNo+" "+ct.ContainerType)
+		if ct.SealNo != "" {
+			note += "  " + docrender.Label(lang, "seal_no") + ": " + ct.SealNo
+		}
+		doc.Notes = append(doc.Notes, note)
+	}
+	doc.Notes = append(doc.Notes, remarksNotes(lang, shipment.Remarks)...)
+	return doc, nil
+}
+
+// sameCarton 內容、重量與尺寸皆相同的紙箱在裝箱單上合併列示
+func sameCarton(a, b models.ShipmentPackage) bool {
+	if a.NetWeightKG != b.NetWeightKG || a.GrossWeightKG != b.GrossWeightKG ||
+		a.LengthCM != b.LengthCM || a.WidthCM != b.WidthCM || a.HeightCM != b.HeightCM ||
+		len(a.Contents) != len(b.Contents) {
+		return false
+	}
+	for i := range a.Contents {
+		if a.Contents[i].SalesOrderLineID != b.Contents[i].SalesOrderLineID || a.Contents[i].Quantity != b.Contents[i].Quantity {
+			return false
+		}
+	}
+	return true
+}
+
+func orderConfirmationDocument(order models.SalesOrder, lang string) (docrender.Document, error) {
+	parties := []docrender.Party{}
+	for _, p := range []struct {
+		label  string
+		siteID *uint
+	}{{"buyer", order.SoldToSiteID}, {"ship_to", order.ShipToSiteID}, {"bill_to", order.BillToSiteID}} {
+		party, err := siteParty(lang, p.label, order.CustomerID, p.siteID)
+		if err != nil {
+			return docrender.Document{}, err
+		}
+		parties = append(parties, party)
+	}
+	doc := docrender.Document{
+		Title: docrender.Label(lang, "order_confirmation"),
+		Fields: nonEmptyFields(lang,
+			"order_no", order.OrderNo,
+			"date", formatDate(&order.OrderDate),
+			"customer_po", order.CustomerPONo,
+			"incoterm", order.Incoterm,
+			"currency", order.CurrencyCode,
+			"port", order.ExportPort,
+			"destination", order.DestinationCountry),
+		Parties: parties,
+		Columns: salesColumns(lang, true),
+		Totals:  []docrender.Field{{Label: docrender.Label(lang, "total"), Value: order.CurrencyCode + " " + formatMoney(order.TotalAmount)}},
+		Notes:   remarksNotes(lang, order.Remarks),
+	}
+	for _, l := range order.Lines {
+		doc.Rows = append(doc.Rows, []string{strconv.Itoa(l.LineNo), l.Description, formatQuantity(l.Quantity), l.Unit,
+			formatPrice(l.UnitPrice), formatMoney(l.Amount), formatDate(l.DeliveryDate)})
+	}
+	return doc, nil
+}
+
+func salesColumns(lang string, withDelivery bool) []docrender.Column {
+	cols := []docrender.Column{
+		{Label: docrender.Label(lang, "line_no"), Width: 5},
+		{Label: docrender.Label(lang, "description"), Width: 40},
+		{Label: docrender.Label(lang, "quantity"), Width: 11, Right: true},
+		{Label: docrender.Label(lang, "unit"), Width: 7},
+		{Label: docrender.Label(lang, "unit_price"), Width: 12, Right: true},
+		{Label: docrender.Label(lang, "amount"), Width: 14, Right: true},
+	}
+	if withDelivery {
+		cols = append(cols, docrender.Column{Label: docrender.Label(lang, "delivery_date"), Width: 11})
+	}
+	return cols
+}
+
+// siteParty 據點名稱與地址；未指定據點時以集團客戶名稱表示
+func siteParty(lang, label string, customerID uint, siteID *uint) (docrender.Party, error) {
+	party := docrender.Party{Label: docrender.Label(lang, label)}
+	if siteID == nil {
+		var customer models.Customer
+		if err := db.DB.First(&customer, customerID).Error; err != nil {
+			return party, err
+		}
+		party.Lines = []string{customer.GroupCustomerName}
+		return party, nil
+	}
+	var site models.CustomerSite
+	if err := db.DB.First(&site, *siteID).Error; err != nil {
+		return party, err
+	}
+	cityLine := strings.TrimSpace(strings.Join([]string{site.City, site.State, site.PostalCode}, " "))
+	for _, s := range []string{site.Name, site.AddressLine1, site.AddressLine2, cityLine, site.Country} {
+		if s = strings.TrimSpace(s); s != "" {
+			party.Lines = append(party.Lines, s)
+		}
+	}
+	if site.TaxID != "" {
+		party.Lines = append(party.Lines, docrender.Label(lang, "tax_id")+": "+site.TaxID)
+	}
+	return party, nil
+}
+
+// nonEmptyFields 依 key / value 成對參數組出單據資訊，略過空白值
+func nonEmptyFields(lang string, pairs ...string) []docrender.Field {
+	var fields []docrender.Field
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			fields = append(fields, docrender.Field{Label: docrender.Label(lang, pairs[i]), Value: pairs[i+1]})
+		}
+	}
+	return fields
+}
+
+func remarksNotes(lang, remarks string) []string {
+	if strings.TrimSpace(remarks) == "" {
+		return nil
+	}
+	return []string{docrender.Label(lang, "remarks") + ": " + strings.TrimSpace(remarks)}
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func formatQuantity(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatPrice 單價保留至多 4 位小數，不足 2 位時依金額格式顯示
+func formatPrice(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 4, 64)
+	if strings.HasSuffix(s, "00") {
+		return formatMoney(v)
+	}
+	return strings.TrimRight(s, "0")
+}
+
+// formatMoney 金額加上千分位並保留 2 位小數
+func formatMoney(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + frac
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢匯率，可依 from / to 幣別篩選，最新生效者在前
+func GetExchangeRates(c *gin.Context) {
+	query := db.DB.Order("effective_date DESC, from_currency, to_currency")
+	if v := c.Query("from"); v != "" {
+		query = query.Where("from_currency = ?", strings.ToUpper(v))
+	}
+	if v := c.Query("to"); v != "" {
+		query = query.Where("to_currency = ?", strings.ToUpper(v))
+	}
+	var rates []models.ExchangeRate
+	if err := query.Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢匯率失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rates)
+}
+
+// 新增匯率；同一幣別組合與生效日已存在時覆寫匯率
+func CreateExchangeRate(c *gin.Context) {
+	var rate models.ExchangeRate
+	if err := c.ShouldBindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	rate.ID = 0
+	rate.FromCurrency = strings.ToUpper(strings.TrimSpace(rate.FromCurrency))
+	rate.ToCurrency = strings.ToUpper(strings.TrimSpace(rate.ToCurrency))
+	if rate.FromCurrency == "" || rate.ToCurrency == "" || rate.FromCurrency == rate.ToCurrency || rate.Rate <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請指定不同的 from / to 幣別，且匯率必須大於 0"})
+		return
+	}
+	if rate.EffectiveDate.IsZero() {
+		rate.EffectiveDate = time.Now()
+	}
+	rate.EffectiveDate = time.Date(rate.EffectiveDate.Year(), rate.EffectiveDate.Month(), rate.EffectiveDate.Day(), 0, 0, 0, 0, time.UTC)
+	rate.CreatedBy = c.GetString("username")
+	err := db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_currency"}, {Name: "to_currency"}, {Name: "effective_date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "created_by"}),
+	}).Create(&rate).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立匯率失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rate)
+}
+
+// convertAmount 依 date 當日（含）以前最新的匯率換算金額；無直接匯率時使用反向匯率
+func convertAmount(amount float64, from, to string, date time.Time) (float64, error) {
+	if from == to || amount == 0 {
+		return amount, nil
+	}
+	day := date.Format("2006-01-02")
+	var rate models.ExchangeRate
+	err := db.DB.Where("from_currency = ? AND to_currency = ? AND effective_date <= ?", from, to, day).
+		Order("effective_date DESC").First(&rate).Error
+	if err == nil {
+		return amount * rate.Rate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	err = db.DB.Where("from_currency = ? AND to_currency = ? AND effective_date <= ?", to, from, day).
+		Order("effective_date DESC").First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("缺少 %s → %s 匯率", from, to)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return amount / rate.Rate, nil
+}
@@ -0,0 +1,437 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 計入已承諾量 (committed) 的訂單狀態；信用凍結的訂單仍保留其數量
+var committedOrderStatuses = []string{models.OrderCreditHold, models.OrderConfirmed, models.OrderPartiallyShipped}
+
+// 查詢庫存餘額，可依 company_id、warehouse_id、bin_id、product_specification_id、lot_no 篩選；
+// 預設不列出數量為 0 的餘額，include_zero=true 時全部列出
+func GetStockBalances(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("warehouse_id, product_specification_id, lot_no, bin_id"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "warehouse_id", "bin_id", "product_specification_id", "lot_no"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	if c.Query("include_zero") != "true" {
+		query = query.Where("quantity <> 0")
+	}
+	var balances []models.StockBalance
+	if err := query.Find(&balances).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢庫存失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, balances)
+}
+
+// 查詢庫存異動，可依 company_id、warehouse_id、product_specification_id、lot_no、type、document_no、
+// shipment_id 及異動日期區間 (from / to, YYYY-MM-DD) 篩選
+func GetStockMovements(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("moved_at DESC, id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "warehouse_id", "product_specification_id", "lot_no", "type", "document_no", "shipment_id"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		query = query.Where("moved_at >= ?", v)
+	}
+	if v := c.Query("to"); v != "" {
+		query = query.Where("moved_at < (?::date + 1)", v)
+	}
+	var movements []models.StockMovement
+	if err := query.Find(&movements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢庫存異動失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, movements)
+}
+
+// 入庫
+func CreateStockReceipt(c *gin.Context) {
+	postStock(c, models.StockReceipt)
+}
+
+// 出庫；指定 shipment_id 時記錄為該出貨的出庫（批號追溯用）
+func CreateStockIssue(c *gin.Context) {
+	postStock(c, models.StockIssue)
+}
+
+// 調撥，可於同一集團內的公司之間進行
+func CreateStockTransfer(c *gin.Context) {
+	postStock(c, models.StockTransfer)
+}
+
+// 庫存調整（盤盈盈虧、報廢等），quantity 為增減量，需填原因
+func CreateStockAdjustment(c *gin.Context) {
+	postStock(c, models.StockAdjustment)
+}
+
+// 查詢產品可承諾量 (?product_specification_id 必填，?company_id 預設目前公司)
+func GetStockAvailability(c *gin.Context) {
+	specID, err := strconv.Atoi(c.Query("product_specification_id"))
+	if err != nil || specID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請以 product_specification_id 指定產品規格"})
+		return
+	}
+	_, companyID, _ := getRoleAndCompanyID(c)
+	if v := c.Query("company_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+			return
+		}
+		companyID = uint(id)
+	}
+	if !canAccessCompany(c, companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限查詢此公司"})
+		return
+	}
+	availability, err := availableToPromise(companyID, uint(specID), 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢可承諾量失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, availability)
+}
+
+// 訂單各明細的可承諾量檢查：以接單公司庫存扣除其他訂單承諾量，同一產品的多筆明細依序扣用
+func GetSalesOrderAvailability(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	pools := make(map[uint]map[string]float64)
+	result := []models.OrderLineAvailability{}
+	for _, l := range order.Lines {
+		if l.ProductSpecificationID == nil {
+			continue
+		}
+		specID := *l.ProductSpecificationID
+		if _, loaded := pools[specID]; !loaded {
+			availability, err := availableToPromise(order.CompanyID, specID, order.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢可承諾量失敗: " + err.Error()})
+				return
+			}
+			pools[specID] = make(map[string]float64)
+			for _, a := range availability {
+				pools[specID][a.Unit] = a.Available
+			}
+		}
+		line := models.OrderLineAvailability{
+			SalesOrderLineID:       l.ID,
+			LineNo:                 l.LineNo,
+			ProductSpecificationID: specID,
+			Unit:                   l.Unit,
+			Required:               l.RemainingQuantity(),
+			Available:              pools[specID][l.Unit],
+		}
+		if line.Required > line.Available {
+			line.Shortage = line.Required - line.Available
+		}
+		pools[specID][l.Unit] -= line.Required
+		result = append(result, line)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// availableToPromise 公司某產品規格的可承諾量，依單位分列；excludeOrderID 的訂單不計入承諾量
+func availableToPromise(companyID, specID, excludeOrderID uint) ([]models.StockAvailability, error) {
+	type unitQuantity struct {
+		Unit     string
+		Quantity float64
+	}
+	var onHand, committed []unitQuantity
+	err := db.DB.Raw(`
+		SELECT unit, SUM(quantity) AS quantity FROM stock_balances
+		WHERE company_id = ? AND product_specification_id = ?
+		GROUP BY unit
+	`, companyID, specID).Scan(&onHand).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.DB.Raw(`
+		SELECT l.unit, SUM(l.quantity - l.shipped_quantity) AS quantity
+		FROM sales_order_lines l
+		JOIN sales_orders o ON o.id = l.sales_order_id
+		WHERE o.company_id = ? AND l.product_specification_id = ? AND o.status IN ? AND o.id <> ?
+		GROUP BY l.unit
+	`, companyID, specID, committedOrderStatuses, excludeOrderID).Scan(&committed).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byUnit := make(map[string]*models.StockAvailability)
+	get := func(unit string) *models.StockAvailability {
+		if byUnit[unit] == nil {
+			byUnit[unit] = &models.StockAvailability{CompanyID: companyID, ProductSpecificationID: specID, Unit: unit}
+		}
+		return byUnit[unit]
+	}
+	for _, r := range onHand {
+		get(r.Unit).OnHand = r.Quantity
+	}
+	for _, r := range committed {
+		get(r.Unit).Committed = r.Quantity
+	}
+	result := []models.StockAvailability{}
+	for _, a := range byUnit {
+		a.Available = a.OnHand - a.Committed
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Unit < result[j].Unit })
+	return result, nil
+}
+
+// stockLocation 檢查倉庫與儲位可用，並回傳倉庫
+func stockLocation(c *gin.Context, warehouseID, binID uint) (models.Warehouse, error) {
+	var warehouse models.Warehouse
+	if err := db.DB.First(&warehouse, warehouseID).Error; err != nil || !canAccessCompany(c, warehouse.CompanyID) {
+		return warehouse, newRequestError(http.StatusBadRequest, "找不到指定的倉庫 %d", warehouseID)
+	}
+	if !warehouse.IsActive {
+		return warehouse, newRequestError(http.StatusBadRequest, "倉庫 %s 已停用", warehouse.Code)
+	}
+	if binID != 0 {
+		var bin models.WarehouseBin
+		if err := db.DB.First(&bin, binID).Error; err != nil || bin.WarehouseID != warehouse.ID {
+			return warehouse, newRequestError(http.StatusBadRequest, "儲位 %d 不屬於倉庫 %s", binID, warehouse.Code)
+		}
+		if !bin.IsActive {
+			return warehouse, newRequestError(http.StatusBadRequest, "儲位 %s 已停用", bin.Code)
+		}
+	}
+	return warehouse, nil
+}
+
+// sameCompanyGroup 兩家公司是否屬於同一集團（最上層公司相同）
+func sameCompanyGroup(a, b uint) bool {
+	pathA, pathB := getAncestorCompanyIDs(a), getAncestorCompanyIDs(b)
+	return pathA[len(pathA)-1] == pathB[len(pathB)-1]
+}
+
+// postStock 檢查並過帳入庫 / 出庫 / 調撥 / 調整，單號依調出（或入庫）公司流水號編列
+func postStock(c *gin.Context, docType string) {
+	var req models.StockPostingRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式，至少需要一筆明細"})
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if docType == models.StockAdjustment && req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "庫存調整需填寫原因"})
+		return
+	}
+	if req.ShipmentID != nil && docType != models.StockIssue {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有出庫可指定 shipment_id"})
+		return
+	}
+	from, err := stockLocation(c, req.WarehouseID, req.BinID)
+	if err != nil {
+		respondTxError(c, err, "庫存異動")
+		return
+	}
+	var to models.Warehouse
+	if docType == models.StockTransfer {
+		if to, err = stockLocation(c, req.ToWarehouseID, req.ToBinID); err != nil {
+			respondTxError(c, err, "庫存異動")
+			return
+		}
+		if to.ID == from.ID && req.ToBinID == req.BinID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "調出與調入位置不可相同"})
+			return
+		}
+		if to.CompanyID != from.CompanyID && !sameCompanyGroup(from.CompanyID, to.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "僅可在同一集團的公司之間調撥"})
+			return
+		}
+	}
+	if req.ShipmentID != nil {
+		if !canAccessShipment(c, *req.ShipmentID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的出貨"})
+			return
+		}
+		var companyID uint
+		db.DB.Table("sales_order_shipments").
+			Joins("JOIN sales_orders ON sales_orders.id = sales_order_shipments.sales_order_id").
+			Where("sales_order_shipments.id = ?", *req.ShipmentID).
+			Select("sales_orders.company_id").Scan(&companyID)
+		if companyID != from.CompanyID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "出貨的接單公司與出庫倉庫的公司不同"})
+			return
+		}
+	}
+	// 出貨中各訂單明細的產品規格，用來確認或帶入出庫明細對應的訂單明細
+	var shipmentLines []struct {
+		SalesOrderLineID       uint
+		ProductSpecificationID *uint
+	}
+	if req.ShipmentID != nil {
+		db.DB.Table("sales_order_shipment_lines sl").
+			Joins("JOIN sales_order_lines ol ON ol.id = sl.sales_order_line_id").
+			Where("sl.shipment_id = ?", *req.ShipmentID).
+			Select("sl.sales_order_line_id, ol.product_specification_id").Scan(&shipmentLines)
+	}
+	for i := range req.Lines {
+		line := &req.Lines[i]
+		line.LotNo = strings.TrimSpace(line.LotNo)
+		line.Unit = strings.TrimSpace(line.Unit)
+		if docType == models.StockAdjustment && line.Quantity == 0 || docType != models.StockAdjustment && line.Quantity <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("第 %d 筆明細數量不正確", i+1)})
+			return
+		}
+		var count int64
+		db.DB.Model(&models.ProductSpecification{}).Where("id = ?", line.ProductSpecificationID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("第 %d 筆明細的產品規格不存在", i+1)})
+			return
+		}
+		if req.ShipmentID == nil {
+			if line.SalesOrderLineID != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "只有出貨出庫可指定 sales_order_line_id"})
+				return
+			}
+			continue
+		}
+		var matched []uint
+		for _, sl := range shipmentLines {
+			if sl.ProductSpecificationID != nil && *sl.ProductSpecificationID == line.ProductSpecificationID &&
+				(line.SalesOrderLineID == nil || *line.SalesOrderLineID == sl.SalesOrderLineID) {
+				matched = append(matched, sl.SalesOrderLineID)
+			}
+		}
+		switch {
+		case len(matched) == 0:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("第 %d 筆明細不是此出貨中相同產品規格的訂單明細", i+1)})
+			return
+		case line.SalesOrderLineID == nil && len(matched) > 1:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("第 %d 筆明細的產品規格在出貨中有多筆訂單明細，請指定 sales_order_line_id", i+1)})
+			return
+		}
+		line.SalesOrderLineID = &matched[0]
+	}
+
+	movedAt := time.Now()
+	if req.MovedAt != nil {
+		movedAt = *req.MovedAt
+	}
+	var movements []models.StockMovement
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		docNo, err := nextSequenceNo(tx, from.CompanyID, docType, movedAt)
+		if err != nil {
+			return err
+		}
+		post := func(warehouse models.Warehouse, binID uint, line models.StockLineRequest, quantity float64) error {
+			m := models.StockMovement{
+				DocumentNo:             docNo,
+				Type:                   docType,
+				CompanyID:              warehouse.CompanyID,
+				WarehouseID:            warehouse.ID,
+				BinID:                  binID,
+				ProductSpecificationID: line.ProductSpecificationID,
+				LotNo:                  line.LotNo,
+				Unit:                   line.Unit,
+				Quantity:               quantity,
+				ShipmentID:             req.ShipmentID,
+				SalesOrderLineID:       line.SalesOrderLineID,
+				Reason:                 req.Reason,
+				Reference:              strings.TrimSpace(req.Reference),
+				MovedAt:                movedAt,
+				CreatedBy:              c.GetString("username"),
+			}
+			if err := applyStockMovement(tx, &m); err != nil {
+				return err
+			}
+			movements = append(movements, m)
+			return nil
+		}
+		for _, line := range req.Lines {
+			var err error
+			switch docType {
+			case models.StockReceipt, models.StockAdjustment:
+				err = post(from, req.BinID, line, line.Quantity)
+			case models.StockIssue:
+				err = post(from, req.BinID, line, -line.Quantity)
+			case models.StockTransfer:
+				if err = post(from, req.BinID, line, -line.Quantity); err == nil {
+					err = post(to, req.ToBinID, line, line.Quantity)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondTxError(c, err, "庫存異動")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"document_no": movements[0].DocumentNo, "movements": movements})
+}
+
+// applyStockMovement 鎖定（必要時建立）餘額列、檢查不可為負並寫入異動，需於交易中呼叫
+func applyStockMovement(tx *gorm.DB, m *models.StockMovement) error {
+	balance := models.StockBalance{
+		CompanyID:              m.CompanyID,
+		WarehouseID:            m.WarehouseID,
+		BinID:                  m.BinID,
+		ProductSpecificationID: m.ProductSpecificationID,
+		LotNo:                  m.LotNo,
+		Unit:                   m.Unit,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&balance).Error; err != nil {
+		return err
+	}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("warehouse_id = ? AND bin_id = ? AND product_specification_id = ? AND lot_no = ?",
+			m.WarehouseID, m.BinID, m.ProductSpecificationID, m.LotNo).
+		First(&balance).Error
+	if err != nil {
+		return err
+	}
+	if m.Unit == "" {
+		m.Unit = balance.Unit
+	}
+	if balance.Unit == "" {
+		balance.Unit = m.Unit
+	}
+	if m.Unit != balance.Unit {
+		return newRequestError(http.StatusBadRequest, "產品規格 %d 批號 %q 的庫存單位為 %s，與 %s 不符",
+			m.ProductSpecificationID, m.LotNo, balance.Unit, m.Unit)
+	}
+	balance.Quantity += m.Quantity
+	if balance.Quantity < -1e-9 {
+		return newRequestError(http.StatusConflict, "庫存不足：產品規格 %d 批號 %q 現有 %g",
+			m.ProductSpecificationID, m.LotNo, balance.Quantity-m.Quantity)
+	}
+	if err := tx.Model(&balance).Select("unit", "quantity").Updates(&balance).Error; err != nil {
+		return err
+	}
+	m.BalanceAfter = balance.Quantity
+	return tx.Create(m).Error
+}
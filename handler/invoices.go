@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢發票列表，可依 customer_id、sales_order_id、type、status 篩選
+func GetInvoices(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"customer_id", "sales_order_id", "type", "status"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var invoices []models.Invoice
+	if err := query.Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢發票失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+// 查詢單一發票（含明細與收款紀錄）
+func GetInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c, db.DB)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// 依訂單開立發票：
+//   - commercial（預設）：依 shipment_id 的出貨數量 × 訂單單價開立，並提列業務佣金
+//   - proforma：出貨前依訂單全部明細開立，供客戶預付款
+//
+// 幣別沿用訂單（即客戶交易條件）幣別，單號依公司與發票類型各自編號
+func CreateInvoice(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	var req models.InvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if req.Type == "" {
+		req.Type = models.InvoiceCommercial
+	}
+	if req.TaxRate < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "稅率不可為負數"})
+		return
+	}
+	if order.Status == models.OrderDraft || order.Status == models.OrderCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "草稿或已取消的訂單不可開立發票"})
+		return
+	}
+
+	invoice := models.Invoice{
+		Type:         req.Type,
+		CompanyID:    order.CompanyID,
+		CustomerID:   order.CustomerID,
+		BillToSiteID: order.BillToSiteID,
+		SalesOrderID: order.ID,
+		InvoiceDate:  time.Now(),
+		DueDate:      req.DueDate,
+		CurrencyCode: order.CurrencyCode,
+		TaxRate:      req.TaxRate,
+		Status:       models.InvoiceIssued,
+		Remarks:      req.Remarks,
+		CreatedBy:    c.GetString("username"),
+	}
+	if req.InvoiceDate != nil {
+		invoice.InvoiceDate = *req.InvoiceDate
+	}
+
+	switch req.Type {
+	case models.InvoiceProforma:
+		for _, ol := range order.Lines {
+			addInvoiceLine(&invoice, ol, ol.Quantity)
+		}
+	case models.InvoiceCommercial:
+		var shipment *models.SalesOrderShipment
+		for i := range order.Shipments {
+			if order.Shipments[i].ID == req.ShipmentID {
+				shipment = &order.Shipments[i]
+			}
+		}
+		if shipment == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "商業發票需指定此訂單的 shipment_id"})
+			return
+		}
+		orderLines := make(map[uint]models.SalesOrderLine, len(order.Lines))
+		for _, l := range order.Lines {
+			orderLines[l.ID] = l
+		}
+		invoice.ShipmentID = &shipment.ID
+		for _, sl := range shipment.Lines {
+			addInvoiceLine(&invoice, orderLines[sl.SalesOrderLineID], sl.Quantity)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "發票類型需為 proforma 或 commercial"})
+		return
+	}
+	invoice.SubtotalAmount = roundAmount(invoice.SubtotalAmount)
+	invoice.TaxAmount = roundAmount(invoice.SubtotalAmount * invoice.TaxRate / 100)
+	invoice.TotalAmount = roundAmount(invoice.SubtotalAmount + invoice.TaxAmount)
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if invoice.ShipmentID != nil {
+			// 鎖定出貨，避免同時對同一出貨開立兩張商業發票
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.SalesOrderShipment{}, *invoice.ShipmentID).Error; err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.Invoice{}).
+				Where("shipment_id = ? AND type = ? AND status <> ?", *invoice.ShipmentID, models.InvoiceCommercial, models.InvoiceCancelled).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return newRequestError(http.StatusConflict, "此出貨已開立商業發票")
+			}
+		}
+		no, err := nextSequenceNo(tx, invoice.CompanyID, invoice.Type, invoice.InvoiceDate)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNo = no
+		if err := tx.Create(&invoice).Error; err != nil {
+			return err
+		}
+		if invoice.Type == models.InvoiceCommercial {
+			return accrueCommission(tx, invoice, order)
+		}
+		return nil
+	})
+	if err != nil {
+		respondTxError(c, err, "開立發票")
+		return
+	}
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// 登錄收款，依累計收款更新為 partially_paid / paid
+func RecordInvoicePayment(c *gin.Context) {
+	invoice, ok := findInvoice(c, db.DB)
+	if !ok {
+		return
+	}
+	var req models.InvoicePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收款金額必須大於 0"})
+		return
+	}
+	payment := models.InvoicePayment{
+		InvoiceID: invoice.ID,
+		Amount:    roundAmount(req.Amount),
+		PaidAt:    time.Now(),
+		Reference: req.Reference,
+		CreatedBy: c.GetString("username"),
+	}
+	if req.PaidAt != nil {
+		payment.PaidAt = *req.PaidAt
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoice.ID).Error; err != nil {
+			return err
+		}
+		if invoice.Status == models.InvoiceCancelled || invoice.Status == models.InvoicePaid {
+			return newRequestError(http.StatusConflict, "已作廢或已付清的發票不可再登錄收款")
+		}
+		if payment.Amount > roundAmount(invoice.OutstandingAmount()) {
+			return newRequestError(http.StatusBadRequest, "收款金額超過未收款金額 %.2f", invoice.OutstandingAmount())
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		invoice.PaidAmount = roundAmount(invoice.PaidAmount + payment.Amount)
+		invoice.Status = models.InvoicePartiallyPaid
+		if invoice.PaidAmount >= invoice.TotalAmount {
+			invoice.Status = models.InvoicePaid
+		}
+		return tx.Model(&invoice).Select("paid_amount", "status").Updates(&invoice).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "登錄收款")
+		return
+	}
+	invoice.Payments = append(invoice.Payments, payment)
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// 作廢發票；已有收款或佣金已付款時不可作廢，未付款的佣金一併刪除
+func CancelInvoice(c *gin.Context) {
+	invoice, ok := findInvoice(c, db.DB)
+	if !ok {
+		return
+	}
+	var req models.CancelInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請填寫作廢原因"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, invoice.ID).Error; err != nil {
+			return err
+		}
+		if invoice.Status == models.InvoiceCancelled {
+			return newRequestError(http.StatusConflict, "發票已作廢")
+		}
+		if invoice.PaidAmount > 0 {
+			return newRequestError(http.StatusConflict, "發票已有收款，不可作廢")
+		}
+		var paid int64
+		tx.Model(&models.Commission{}).Where("invoice_id = ? AND status = ?", invoice.ID, models.CommissionPaid).Count(&paid)
+		if paid > 0 {
+			return newRequestError(http.StatusConflict, "此發票的佣金已付款，不可作廢")
+		}
+		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.Commission{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		invoice.Status = models.InvoiceCancelled
+		invoice.CancelledAt = &now
+		invoice.CancelledBy = c.GetString("username")
+		invoice.CancelReason = strings.TrimSpace(req.Reason)
+		return tx.Model(&invoice).Select("status", "cancelled_at", "cancelled_by", "cancel_reason").Updates(&invoice).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "作廢發票")
+		return
+	}
+	c.JSON(http.StatusOK, invoice)
+}
+
+// findInvoice 依路徑 :id 載入發票、明細與收款紀錄，並檢查公司範圍
+func findInvoice(c *gin.Context, tx *gorm.DB) (models.Invoice, bool) {
+	var invoice models.Invoice
+	err := tx.Preload("Lines", func(q *gorm.DB) *gorm.DB { return q.Order("line_no") }).
+		Preload("Payments", func(q *gorm.DB) *gorm.DB { return q.Order("paid_at") }).
+		First(&invoice, c.Param("id")).Error
+	if err != nil || !canAccessCompany(c, invoice.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的發票"})
+		return invoice, false
+	}
+	return invoice, true
+}
+
+func addInvoiceLine(invoice *models.Invoice, ol models.SalesOrderLine, quantity float64) {
+	amount := roundAmount(quantity * ol.UnitPrice)
+	invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+		LineNo:           len(invoice.Lines) + 1,
+		SalesOrderLineID: ol.ID,
+		Description:      ol.Description,
+		Quantity:         quantity,
+		Unit:             ol.Unit,
+		UnitPrice:        ol.UnitPrice,
+		Amount:           amount,
+	})
+	invoice.SubtotalAmount += amount
+}
@@ -6,7 +6,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"fastener-api/db"
 	"fastener-api/models"
@@ -37,6 +37,16 @@ func getRoleAndCompanyID(c *gin.Context) (string, uint, bool) {
 	return roleStr, companyIDUint, true
 }
 
+// requireSuperAdmin 僅允許 superadmin 繼續，否則回應 403
+func requireSuperAdmin(c *gin.Context) bool {
+	role, _, ok := getRoleAndCompanyID(c)
+	if !ok || role != models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return false
+	}
+	return true
+}
+
 // 查詢帳號列表
 func GetAccounts(c *gin.Context) {
 	role, companyID, ok := getRoleAndCompanyID(c)
@@ -117,21 +127,26 @@ func CreateAccount(c *gin.Context) {
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := models.HashPassword(req.Password)
+	if err == models.ErrPasswordTooShort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "密碼加密失敗"})
 		return
 	}
 
-	var roleID uint
-	if err := db.DB.Raw("SELECT id FROM roles WHERE name = ?", req.Role).Scan(&roleID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色失敗: " + err.Error()})
+	assigned, ok := findAssignableRole(role, req.Role, req.CompanyID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "此公司無法使用指定的角色"})
 		return
 	}
+	roleID := assigned.ID
 
 	user := models.User{
 		Username:     req.Username,
-		PasswordHash: string(hashed), // ★ 用正確欄位
+		PasswordHash: hashed, // ★ 用正確欄位
 		RoleID:       roleID,
 		CompanyID:    uint(req.CompanyID),
 		IsActive:     true,
@@ -168,17 +183,18 @@ func UpdateAccount(c *gin.Context) {
 				break
 			}
 		}
-		if !isAllowed {
+		if !isAllowed || !accountInScope(companyID, id) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司帳號"})
 			return
 		}
 	}
 
-	var roleID uint
-	if err := db.DB.Raw("SELECT id FROM roles WHERE name = ?", req.Role).Scan(&roleID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色失敗: " + err.Error()})
+	assigned, found := findAssignableRole(role, req.Role, req.CompanyID)
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "此公司無法使用指定的角色"})
 		return
 	}
+	roleID := assigned.ID
 
 	if err := db.DB.Model(&models.User{}).
 		Where("id = ?", id).
@@ -194,6 +210,199 @@ func UpdateAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "帳號更新成功"})
 }
 
+// 部分更新帳號 (JSON Merge Patch)
+func PatchAccount(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+
+	id := c.Param("id")
+	if _, err := strconv.Atoi(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的帳號 ID"})
+		return
+	}
+
+	var req models.PatchAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+
+	var targetCompanyID uint
+	if err := db.DB.Raw("SELECT tenant_id FROM users WHERE id = ?", id).Scan(&targetCompanyID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢帳號公司失敗"})
+		return
+	}
+	if role == "company_admin" {
+		if !companyInScope(companyID, targetCompanyID) ||
+			(req.CompanyID != nil && !companyInScope(companyID, *req.CompanyID)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司帳號"})
+			return
+		}
+	}
+
+	// 角色須對帳號異動後所屬的公司可見；只改公司時也要確認原角色仍適用
+	newCompanyID := targetCompanyID
+	if req.CompanyID != nil {
+		newCompanyID = *req.CompanyID
+	}
+	roleName := ""
+	if req.Role != nil {
+		roleName = *req.Role
+	} else if req.CompanyID != nil {
+		db.DB.Raw("SELECT r.name FROM users u JOIN roles r ON u.role_id = r.id WHERE u.id = ?", id).Scan(&roleName)
+	}
+
+	updates := map[string]interface{}{}
+	if roleName != "" {
+		assigned, found := findAssignableRole(role, roleName, newCompanyID)
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "此公司無法使用指定的角色"})
+			return
+		}
+		updates["role_id"] = assigned.ID
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.CompanyID != nil {
+		if *req.CompanyID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "公司不可為空"})
+			return
+		}
+		updates["tenant_id"] = *req.CompanyID
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新帳號失敗: " + err.Error()})
+			return
+		}
+	}
+
+	var account models.UserAccount
+	db.DB.Raw(`
+		SELECT u.id, u.username, r.name as role, u.is_active, u.tenant_id as company_id, c.name as company_name
+		FROM users u
+		LEFT JOIN roles r ON u.role_id = r.id
+		LEFT JOIN companies c ON u.tenant_id = c.id
+		WHERE u.id = ?
+	`, id).Scan(&account)
+	if account.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的帳號"})
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// 查詢帳號的其他公司 membership
+func GetAccountMemberships(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	id := c.Param("id")
+	if role == "company_admin" && !accountInScope(companyID, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法查詢此公司帳號"})
+		return
+	}
+	var list []models.CompanyMembershipInfo
+	db.DB.Raw(`
+		SELECT m.company_id, c.name AS company_name, m.role_id, r.name AS role, m.is_default
+		FROM user_company_memberships m
+		LEFT JOIN companies c ON m.company_id = c.id
+		LEFT JOIN roles r ON m.role_id = r.id
+		WHERE m.user_id = ?
+		ORDER BY m.company_id
+	`, id).Scan(&list)
+	if list == nil {
+		list = []models.CompanyMembershipInfo{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// 新增或更新帳號在某公司的 membership（同一公司僅一筆）
+func PutAccountMembership(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	id := c.Param("id")
+	var user models.User
+	if err := db.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的帳號"})
+		return
+	}
+	if role == "company_admin" && !accountInScope(companyID, id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司帳號"})
+		return
+	}
+	var req models.MembershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.CompanyID == 0 || req.Role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	if role == "company_admin" && !companyInScope(companyID, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法指派此公司"})
+		return
+	}
+	assigned, found := findAssignableRole(role, req.Role, req.CompanyID)
+	if !found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "此公司無法使用指定的角色"})
+		return
+	}
+
+	membership := models.UserCompanyMembership{UserID: user.ID, CompanyID: req.CompanyID}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if req.IsDefault {
+			if err := tx.Model(&models.UserCompanyMembership{}).Where("user_id = ?", user.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where(models.UserCompanyMembership{UserID: user.ID, CompanyID: req.CompanyID}).
+			Assign(map[string]interface{}{"role_id": assigned.ID, "is_default": req.IsDefault}).
+			FirstOrCreate(&membership).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "設定公司權限失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, membership)
+}
+
+// 移除帳號在某公司的 membership
+func DeleteAccountMembership(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	targetCompanyID, err := strconv.Atoi(c.Param("companyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+		return
+	}
+	if role == "company_admin" && !companyInScope(companyID, uint(targetCompanyID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司"})
+		return
+	}
+	if role == "company_admin" && !accountInScope(companyID, c.Param("id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司帳號"})
+		return
+	}
+	if err := db.DB.Where("user_id = ? AND company_id = ?", c.Param("id"), targetCompanyID).
+		Delete(&models.UserCompanyMembership{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "移除公司權限失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "公司權限已移除"})
+}
+
 // 刪除帳號
 func DeleteAccount(c *gin.Context) {
 	role, companyID, ok := getRoleAndCompanyID(c)
@@ -232,7 +441,13 @@ func DeleteAccount(c *gin.Context) {
 		}
 	}
 
-	if err := db.DB.Delete(&models.User{}, id).Error; err != nil {
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserCompanyMembership{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除帳號失敗: " + err.Error()})
 		return
 	}
@@ -274,19 +489,64 @@ func ResetPassword(c *gin.Context) {
 			return
 		}
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := models.HashPassword(req.Password)
+	if err == models.ErrPasswordTooShort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "密碼加密失敗"})
 		return
 	}
-	if err := db.DB.Model(&models.User{}).Where("id = ?", id).
-		Update("password_hash", string(hashed)).Error; err != nil {
+	// 重設密碼同時解除登入失敗鎖定
+	if err := db.DB.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_hash":      hashed,
+		"failed_login_count": 0,
+		"locked_until":       nil,
+	}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "密碼更新失敗"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "密碼已重設"})
 }
 
+// accountInScope 判斷帳號的主要公司是否在 companyID 的管轄範圍內
+func accountInScope(companyID uint, userID string) bool {
+	var targetCompanyID uint
+	if err := db.DB.Raw("SELECT tenant_id FROM users WHERE id = ?", userID).Scan(&targetCompanyID).Error; err != nil {
+		return false
+	}
+	return companyInScope(companyID, targetCompanyID)
+}
+
+// companyInScope 判斷 targetID 是否為 companyID 本身或其下層公司
+func companyInScope(companyID, targetID uint) bool {
+	for _, id := range getDescendantCompanyIDs(companyID) {
+		if id == targetID {
+			return true
+		}
+	}
+	return false
+}
+
+// ==== 查詢自己+所有上層公司ID（由下往上）====
+func getAncestorCompanyIDs(companyID uint) []uint {
+	var ids []uint
+	db.DB.Raw(`
+		WITH RECURSIVE company_path AS (
+			SELECT id, parent_id, 0 AS depth FROM companies WHERE id = ?
+			UNION ALL
+			SELECT c.id, c.parent_id, p.depth + 1 FROM companies c
+			JOIN company_path p ON c.id = p.parent_id
+		)
+		SELECT id FROM company_path ORDER BY depth
+	`, companyID).Scan(&ids)
+	if len(ids) == 0 {
+		ids = append(ids, companyID)
+	}
+	return ids
+}
+
 // ==== 查詢自己+所有下層公司ID（支援 RECURSIVE，GORM Raw）====
 func getDescendantCompanyIDs(companyID uint) []uint {
 	var ids []uint
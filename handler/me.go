@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢目前登入者的個人資料、角色、公司、權限與選單
+func GetMe(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	me, err := buildMe(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢個人資料失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, me)
+}
+
+// 更新目前登入者可編輯的個人資料
+func UpdateMe(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !strings.Contains(req.Email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "電子郵件格式錯誤"})
+		return
+	}
+	if err := db.DB.Model(&user).Updates(map[string]interface{}{
+		"display_name":       strings.TrimSpace(req.DisplayName),
+		"email":              req.Email,
+		"preferred_language": strings.TrimSpace(req.PreferredLanguage),
+		"preferred_currency": strings.ToUpper(strings.TrimSpace(req.PreferredCurrency)),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新個人資料失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&user, user.ID)
+	me, err := buildMe(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢個人資料失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, me)
+}
+
+// buildMe 組合 /api/me 的回傳內容，公司以 Token 中目前的公司為準
+func buildMe(c *gin.Context, user models.User) (models.MeResponse, error) {
+	roleName, companyID, _ := getRoleAndCompanyID(c)
+	me := models.MeResponse{
+		User: models.UserProfile{
+			ID:                user.ID,
+			Username:          user.Username,
+			DisplayName:       user.DisplayName,
+			Email:             user.Email,
+			PreferredLanguage: user.PreferredLanguage,
+			PreferredCurrency: user.PreferredCurrency,
+		},
+		Permissions: currentPermissions(c),
+	}
+	if me.Permissions == nil {
+		me.Permissions = []string{}
+	}
+	db.DB.Where("name = ?", roleName).First(&me.Role)
+	if me.Role.Permissions == nil {
+		me.Role.Permissions = []string{}
+	}
+
+	if err := db.DB.First(&me.Company, companyID).Error; err != nil {
+		return me, err
+	}
+
+	// 由下往上取得的上層公司，反轉為由根到目前公司的順序
+	ancestorIDs := getAncestorCompanyIDs(companyID)
+	var ancestors []models.Company
+	db.DB.Where("id IN ?", ancestorIDs).Find(&ancestors)
+	byID := make(map[uint]models.Company, len(ancestors))
+	for _, a := range ancestors {
+		byID[a.ID] = a
+	}
+	me.CompanyPath = make([]models.Company, 0, len(ancestorIDs))
+	for i := len(ancestorIDs) - 1; i >= 0; i-- {
+		if a, ok := byID[ancestorIDs[i]]; ok {
+			me.CompanyPath = append(me.CompanyPath, a)
+		}
+	}
+
+	var subtree []models.Company
+	if err := db.DB.Where("id IN ?", getDescendantCompanyIDs(companyID)).Order("name").Find(&subtree).Error; err != nil {
+		return me, err
+	}
+	me.AccessibleCompanies = buildCompanyTree(subtree)
+
+	menus, err := visibleMenus(roleName, me.Permissions)
+	if err != nil {
+		return me, err
+	}
+	me.Menus = buildMenuTree(menus)
+
+	me.Language = firstNonEmpty(user.PreferredLanguage, me.Company.Language)
+	me.Currency = firstNonEmpty(user.PreferredCurrency, me.Company.Currency)
+	return me, nil
+}
+
+// currentUser 依 Token 中的帳號名稱取得目前登入的使用者
+func currentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	username := c.GetString("username")
+	if username == "" {
+		return user, false
+	}
+	if err := db.DB.Where("username = ? AND is_active = true", username).First(&user).Error; err != nil {
+		return user, false
+	}
+	return user, true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// 查詢目前登入者可切換的公司（主要公司 + membership）
+func GetMyCompanies(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	_, activeCompanyID, _ := getRoleAndCompanyID(c)
+
+	var list []models.CompanyMembershipInfo
+	if err := db.DB.Raw(`
+		SELECT u.tenant_id AS company_id, c.name AS company_name, u.role_id, r.name AS role,
+			TRUE AS is_primary, FALSE AS is_default
+		FROM users u
+		LEFT JOIN companies c ON u.tenant_id = c.id
+		LEFT JOIN roles r ON u.role_id = r.id
+		WHERE u.id = ?
+		UNION ALL
+		SELECT m.company_id, c.name, m.role_id, r.name, FALSE, m.is_default
+		FROM user_company_memberships m
+		LEFT JOIN companies c ON m.company_id = c.id
+		LEFT JOIN roles r ON m.role_id = r.id
+		WHERE m.user_id = ? AND m.company_id <> (SELECT tenant_id FROM users WHERE id = ?)
+	`, user.ID, user.ID, user.ID).Scan(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢可切換公司失敗: " + err.Error()})
+		return
+	}
+	for i := range list {
+		list[i].IsActive = list[i].CompanyID == activeCompanyID
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -1,111 +1,558 @@
-// fastener-api/handler/menus.go
-
 package handler
 
 import (
+	"net/http"
 	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/wac0705/fastener-api/db"
-	"github.com/wac0705/fastener-api/models"
-	"github.com/golang-jwt/jwt/v5"
+	"fastener-api/db"
+	"fastener-api/models"
 )
 
-// ... (您原本的 CreateMenu, GetMenus, GetMenu, UpdateMenu, DeleteMenu 函式保持不變) ...
+// 查詢所有選單（扁平列表）
+func GetMenus(c *gin.Context) {
+	var menus []models.Menu
+	if err := db.DB.Order("parent_id NULLS FIRST, order_no, id").Find(&menus).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢選單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, menus)
+}
 
-// --- 新增的函式 ---
+// 查詢單一選單
+func GetMenu(c *gin.Context) {
+	var menu models.Menu
+	if err := db.DB.First(&menu, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的選單"})
+		return
+	}
+	c.JSON(http.StatusOK, menu)
+}
 
-// buildMenuTree 將扁平的選單列表轉換為樹狀結構
-func buildMenuTree(menus []models.Menu) []models.Menu {
-	menuMap := make(map[uint]models.Menu)
-	var rootMenus []models.Menu
+// 新增選單，未指定 order_no 時排在同層最後
+func CreateMenu(c *gin.Context) {
+	if !requireSuperAdmin(c) {
+		return
+	}
+	var menu models.Menu
+	if err := c.ShouldBindJSON(&menu); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	menu.ID = 0
+	menu.Children = nil
+	menu.Name = strings.TrimSpace(menu.Name)
+	if menu.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "選單名稱為必填"})
+		return
+	}
+	if menu.ParentID != nil && db.DB.First(&models.Menu{}, *menu.ParentID).Error != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "上層選單不存在"})
+		return
+	}
+	if unknown := unknownPermissions(menu.RequiredPermissions); len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的權限", "permissions": unknown})
+		return
+	}
+	if menu.RequiredPermissions == nil {
+		menu.RequiredPermissions = []string{}
+	}
+	if menu.OrderNo == 0 {
+		menu.OrderNo = nextMenuOrderNo(menu.ParentID)
+	}
+	if err := db.DB.Create(&menu).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立選單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, menu)
+}
+
+// 更新選單
+func UpdateMenu(c *gin.Context) {
+	if !requireSuperAdmin(c) {
+		return
+	}
+	var menu models.Menu
+	if err := db.DB.First(&menu, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的選單"})
+		return
+	}
+	var req models.Menu
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	updates := map[string]interface{}{
+		"name":      strings.TrimSpace(req.Name),
+		"path":      req.Path,
+		"icon":      req.Icon,
+		"parent_id": req.ParentID,
+		"order_no":  req.OrderNo,
+		"is_active": req.IsActive,
+
+		"required_permissions": req.RequiredPermissions,
+	}
+	if !applyMenuUpdates(c, &menu, updates) {
+		return
+	}
+	c.JSON(http.StatusOK, menu)
+}
+
+// 部分更新選單 (JSON Merge Patch)
+func PatchMenu(c *gin.Context) {
+	if !requireSuperAdmin(c) {
+		return
+	}
+	var menu models.Menu
+	if err := db.DB.First(&menu, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的選單"})
+		return
+	}
+	updates, err := bindMergePatch(c, &menu, "name", "path", "icon", "parent_id", "order_no", "is_active", "required_permissions")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if name, ok := updates["name"].(string); ok {
+		updates["name"] = strings.TrimSpace(name)
+	}
+	if !applyMenuUpdates(c, &menu, updates) {
+		return
+	}
+	c.JSON(http.StatusOK, menu)
+}
+
+// applyMenuUpdates 檢查名稱與上層選單後寫入，失敗時已回應錯誤並回傳 false
+func applyMenuUpdates(c *gin.Context, menu *models.Menu, updates map[string]interface{}) bool {
+	if name, ok := updates["name"].(string); ok && name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "選單名稱為必填"})
+		return false
+	}
+	if parentID, ok := updates["parent_id"].(*uint); ok && parentID != nil {
+		if db.DB.First(&models.Menu{}, *parentID).Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "上層選單不存在"})
+			return false
+		}
+		parents := loadMenuParents()
+		parents[menu.ID] = parentID
+		if menuHasCycle(parents, menu.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "上層選單不可為自己或下層選單"})
+			return false
+		}
+	}
+	if perms, ok := updates["required_permissions"].([]string); ok {
+		if perms == nil {
+			perms = []string{}
+		}
+		if unknown := unknownPermissions(perms); len(unknown) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未知的權限", "permissions": unknown})
+			return false
+		}
+		// jsonb 欄位需經 serializer 轉換，改以 struct 欄位寫入
+		delete(updates, "required_permissions")
+		if err := db.DB.Model(menu).Select("required_permissions").
+			Updates(models.Menu{RequiredPermissions: perms}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新選單失敗: " + err.Error()})
+			return false
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(menu).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新選單失敗: " + err.Error()})
+			return false
+		}
+	}
+	db.DB.First(menu, menu.ID)
+	return true
+}
+
+// 刪除選單，仍有下層選單時拒絕刪除
+func DeleteMenu(c *gin.Context) {
+	if !requireSuperAdmin(c) {
+		return
+	}
+	var menu models.Menu
+	if err := db.DB.First(&menu, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的選單"})
+		return
+	}
+	var childCount int64
+	db.DB.Model(&models.Menu{}).Where("parent_id = ?", menu.ID).Count(&childCount)
+	if childCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "請先刪除或移動下層選單"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("menu_id = ?", menu.ID).Delete(&models.RoleMenuRelation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&menu).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除選單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "選單刪除成功"})
+}
+
+// 拖拉排序：依陣列順序重寫同層的 order_no（從 1 開始），並可一併變更上層選單
+// 未出現在請求中的選單維持原狀
+func ReorderMenus(c *gin.Context) {
+	if !requireSuperAdmin(c) {
+		return
+	}
+	var items []models.MenuOrderItem
+	if err := c.ShouldBindJSON(&items); err != nil || len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+
+	parents := loadMenuParents()
+	seen := make(map[uint]bool, len(items))
+	for _, item := range items {
+		if _, ok := parents[item.ID]; !ok || seen[item.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "選單不存在或重複: " + strconv.Itoa(int(item.ID))})
+			return
+		}
+		if item.ParentID != nil {
+			if _, ok := parents[*item.ParentID]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "上層選單不存在: " + strconv.Itoa(int(*item.ParentID))})
+				return
+			}
+		}
+		seen[item.ID] = true
+		parents[item.ID] = item.ParentID
+	}
+	for _, item := range items {
+		if menuHasCycle(parents, item.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "選單階層不可形成循環"})
+			return
+		}
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		positions := map[uint]int{} // 以上層 ID 分組計算順序，0 代表最上層
+		for _, item := range items {
+			var key uint
+			if item.ParentID != nil {
+				key = *item.ParentID
+			}
+			positions[key]++
+			if err := tx.Model(&models.Menu{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+				"parent_id": item.ParentID,
+				"order_no":  positions[key],
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新選單順序失敗: " + err.Error()})
+		return
+	}
+
+	var menus []models.Menu
+	db.DB.Order("order_no ASC").Find(&menus)
+	c.JSON(http.StatusOK, buildMenuTree(menus))
+}
 
+// 更新角色層級的選單覆寫設定 (JSON Merge Patch)：is_active 控制是否顯示，order_no 為 null 時沿用預設順序
+func PatchRoleMenu(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	var rel models.RoleMenuRelation
+	if err := db.DB.Where("role_id = ? AND menu_id = ?", role.ID, c.Param("menuId")).
+		First(&rel).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "此角色尚未授權該選單"})
+		return
+	}
+	updates, err := bindMergePatch(c, &rel, "is_active", "order_no")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(&models.RoleMenuRelation{}).
+			Where("role_id = ? AND menu_id = ?", rel.RoleID, rel.MenuID).
+			Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新角色選單設定失敗: " + err.Error()})
+			return
+		}
+	}
+	db.DB.Where("role_id = ? AND menu_id = ?", rel.RoleID, rel.MenuID).First(&rel)
+	c.JSON(http.StatusOK, rel)
+}
+
+// nextMenuOrderNo 取得同層下一個排序號
+func nextMenuOrderNo(parentID *uint) int {
+	var maxOrder int
+	query := db.DB.Model(&models.Menu{}).Select("COALESCE(MAX(order_no), 0)")
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	query.Scan(&maxOrder)
+	return maxOrder + 1
+}
+
+// loadMenuParents 讀取所有選單的上層關係
+func loadMenuParents() map[uint]*uint {
+	var menus []models.Menu
+	db.DB.Select("id", "parent_id").Find(&menus)
+	parents := make(map[uint]*uint, len(menus))
 	for _, m := range menus {
-		menuMap[m.ID] = m
+		parents[m.ID] = m.ParentID
+	}
+	return parents
+}
+
+// menuHasCycle 由 id 沿上層往上走，若回到自己即為循環
+func menuHasCycle(parents map[uint]*uint, id uint) bool {
+	visited := map[uint]bool{}
+	for current := parents[id]; current != nil; current = parents[*current] {
+		if *current == id || visited[*current] {
+			return true
+		}
+		visited[*current] = true
 	}
+	return false
+}
 
+// buildMenuTree 將扁平的選單列表轉換為樹狀結構，每一層依 OrderNo 排序
+func buildMenuTree(menus []models.Menu) []models.Menu {
+	childrenOf := make(map[uint][]models.Menu)
+	ids := make(map[uint]bool, len(menus))
+	for _, m := range menus {
+		ids[m.ID] = true
+	}
+	var roots []models.Menu
 	for _, m := range menus {
-		if m.ParentID != nil {
-			parent, ok := menuMap[*m.ParentID]
-			if ok {
-				if parent.Children == nil {
-					parent.Children = []models.Menu{}
-				}
-				parent.Children = append(parent.Children, m)
-				menuMap[*m.ParentID] = parent
+		// 上層不在列表中（例如未授權）時不顯示，避免子選單脫離原有位置
+		if m.ParentID == nil {
+			roots = append(roots, m)
+		} else if ids[*m.ParentID] {
+			childrenOf[*m.ParentID] = append(childrenOf[*m.ParentID], m)
+		}
+	}
+
+	var attach func(nodes []models.Menu) []models.Menu
+	attach = func(nodes []models.Menu) []models.Menu {
+		sort.SliceStable(nodes, func(i, j int) bool {
+			if nodes[i].OrderNo != nodes[j].OrderNo {
+				return nodes[i].OrderNo < nodes[j].OrderNo
 			}
-		} else {
-			rootMenus = append(rootMenus, m)
-		}
-	}
-    
-    // 為了確保每次遍歷順序一致，需要對 map 的 key 進行排序
-    var keys []uint
-    for k := range menuMap {
-        keys = append(keys, k)
-    }
-    sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
-
-    var resultMenus []models.Menu
-    // 從 rootMenus 開始構建最終結果，確保順序
-    for _, root := range rootMenus {
-        resultMenus = append(resultMenus, menuMap[root.ID])
-    }
-
-
-	// 對每一層的 children 進行排序
-	var sortChildren func(menus []models.Menu)
-	sortChildren = func(menus []models.Menu) {
-		sort.Slice(menus, func(i, j int) bool {
-			return menus[i].OrderNo < menus[j].OrderNo
+			return nodes[i].ID < nodes[j].ID
 		})
-		for i := range menus {
-			if len(menus[i].Children) > 0 {
-				sortChildren(menus[i].Children)
+		for i := range nodes {
+			if children, ok := childrenOf[nodes[i].ID]; ok {
+				nodes[i].Children = attach(children)
 			}
 		}
+		return nodes
 	}
 
-	sortChildren(resultMenus)
-    sort.Slice(resultMenus, func(i, j int) bool {
-        return resultMenus[i].OrderNo < resultMenus[j].OrderNo
-    })
+	result := attach(roots)
+	if result == nil {
+		result = []models.Menu{}
+	}
+	return result
+}
 
-	return resultMenus
+// GetUserMenus 依登入者的有效權限計算可見的選單樹：
+//   - 有設定 required_permissions 的選單，權限全部具備才顯示
+//   - 未設定權限的選單（通常是分組節點），需由角色選單明確授權才顯示
+//   - 可見選單的上層會自動一併顯示，並套用角色層級的隱藏與排序設定
+func GetUserMenus(c *gin.Context) {
+	roleName, _, ok := getRoleAndCompanyID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	menus, err := visibleMenus(roleName, currentPermissions(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, buildMenuTree(menus))
 }
 
+// visibleMenus 回傳角色可見的選單（扁平列表，已套用角色排序設定）
+func visibleMenus(roleName string, permissions []string) ([]models.Menu, error) {
+	var role models.Role
+	db.DB.Where("name = ?", roleName).First(&role)
+
+	var rels []models.RoleMenuRelation
+	if role.ID != 0 {
+		if err := db.DB.Where("role_id = ?", role.ID).Find(&rels).Error; err != nil {
+			return nil, err
+		}
+	}
+	relByMenu := make(map[uint]models.RoleMenuRelation, len(rels))
+	for _, rel := range rels {
+		relByMenu[rel.MenuID] = rel
+	}
+
+	var all []models.Menu
+	if err := db.DB.Where("is_active = ?", true).Find(&all).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Menu, len(all))
+	for _, m := range all {
+		byID[m.ID] = m
+	}
+	hidden := func(id uint) bool {
+		rel, ok := relByMenu[id]
+		return ok && !rel.IsActive
+	}
+
+	visible := map[uint]bool{}
+	for _, m := range all {
+		if hidden(m.ID) {
+			continue
+		}
+		_, granted := relByMenu[m.ID]
+		if !menuPermitted(m, roleName, permissions, granted) {
+			continue
+		}
+		// 連同上層選單一併顯示
+		for id := &m.ID; id != nil; {
+			parent, ok := byID[*id]
+			if !ok || hidden(*id) || visible[*id] {
+				break
+			}
+			visible[*id] = true
+			id = parent.ParentID
+		}
+	}
 
-// GetUserMenus 根據 JWT 中的 role_id 獲取使用者可見的選單樹
-func GetUserMenus(c *fiber.Ctx) error {
-	user := c.Locals("user").(*jwt.Token)
-	claims := user.Claims.(jwt.MapClaims)
-	roleID := uint(claims["role_id"].(float64))
+	menus := make([]models.Menu, 0, len(visible))
+	for _, m := range all {
+		if !visible[m.ID] {
+			continue
+		}
+		if rel, ok := relByMenu[m.ID]; ok && rel.OrderNo != nil {
+			m.OrderNo = *rel.OrderNo
+		}
+		menus = append(menus, m)
+	}
+	return menus, nil
+}
+
+// menuPermitted 判斷選單本身是否可對角色顯示（不含上層自動顯示）
+func menuPermitted(menu models.Menu, roleName string, permissions []string, granted bool) bool {
+	if len(menu.RequiredPermissions) == 0 {
+		return granted || roleName == models.RoleSuperAdmin
+	}
+	for _, perm := range menu.RequiredPermissions {
+		if !models.HasPermission(roleName, permissions, perm) {
+			return false
+		}
+	}
+	return true
+}
 
+// 選單一致性檢查：找出選單設定與 API 權限不一致的地方
+//   - unknown_permission：選單要求的權限不存在於權限清單
+//   - route_permission_not_required：選單頁面呼叫的 API 需要某權限，但選單未要求，可能顯示出無法使用的頁面
+//   - role_cannot_call_route：角色被明確授權此選單，卻沒有選單頁面 API 所需的權限
+//
+// 可用 ?role_id= 只檢查單一角色
+func CheckMenuConsistency(c *gin.Context) {
 	var menus []models.Menu
-	// 使用 GORM 進行 Join 查詢
-	result := db.DB.
-		Joins("JOIN role_menu_relations ON role_menu_relations.menu_id = menus.id").
-		Where("role_menu_relations.role_id = ?", roleID).
-        Where("menus.is_active = ?", true).
-		Order("menus.order_no ASC").
-		Find(&menus)
+	if err := db.DB.Where("is_active = ?", true).Order("id").Find(&menus).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢選單失敗: " + err.Error()})
+		return
+	}
+	roleQuery := db.DB.Order("id")
+	if roleID := c.Query("role_id"); roleID != "" {
+		roleQuery = roleQuery.Where("id = ?", roleID)
+	}
+	var roles []models.Role
+	if err := roleQuery.Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色失敗: " + err.Error()})
+		return
+	}
+	var rels []models.RoleMenuRelation
+	db.DB.Where("is_active = ?", true).Find(&rels)
+	granted := map[uint]map[uint]bool{} // role_id -> menu_id
+	for _, rel := range rels {
+		if granted[rel.RoleID] == nil {
+			granted[rel.RoleID] = map[uint]bool{}
+		}
+		granted[rel.RoleID][rel.MenuID] = true
+	}
 
-	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": result.Error.Error()})
+	known := make(map[string]bool, len(models.PermissionCatalog))
+	for _, p := range models.PermissionCatalog {
+		known[p.Code] = true
 	}
 
-	menuTree := buildMenuTree(menus)
+	issues := []models.MenuConsistencyIssue{}
+	for _, menu := range menus {
+		var unknown []string
+		for _, perm := range menu.RequiredPermissions {
+			if !known[perm] {
+				unknown = append(unknown, perm)
+			}
+		}
+		if len(unknown) > 0 {
+			issues = append(issues, models.MenuConsistencyIssue{
+				MenuID: menu.ID, MenuName: menu.Name, Path: menu.Path,
+				Problem: "unknown_permission", Details: unknown,
+			})
+		}
 
-	return c.JSON(menuTree)
+		route, routePerm := menuRoutePermission(menu)
+		if routePerm == "" {
+			continue
+		}
+		if missing := missingPermissions([]string{routePerm}, menu.RequiredPermissions); len(missing) > 0 {
+			issues = append(issues, models.MenuConsistencyIssue{
+				MenuID: menu.ID, MenuName: menu.Name, Path: menu.Path,
+				Problem: "route_permission_not_required", Details: []string{route, routePerm},
+			})
+		}
+		for _, role := range roles {
+			if !granted[role.ID][menu.ID] || models.HasPermission(role.Name, role.Permissions, routePerm) {
+				continue
+			}
+			issues = append(issues, models.MenuConsistencyIssue{
+				MenuID: menu.ID, MenuName: menu.Name, Path: menu.Path,
+				RoleID: role.ID, RoleName: role.Name,
+				Problem: "role_cannot_call_route", Details: []string{route, routePerm},
+			})
+		}
+	}
+	c.JSON(http.StatusOK, issues)
+}
+
+// menuRoutePermission 以選單路徑對應到 GET /api<path> 的路由，回傳路由與所需權限
+func menuRoutePermission(menu models.Menu) (string, string) {
+	if menu.Path == "" {
+		return "", ""
+	}
+	route := "GET /api/" + strings.TrimPrefix(menu.Path, "/")
+	return route, models.RoutePermissions[route]
 }
 
 // GetAllMenusTree 獲取完整的選單樹（供後台管理使用）
-func GetAllMenusTree(c *fiber.Ctx) error {
-    var menus []models.Menu
-    result := db.DB.Order("order_no ASC").Find(&menus)
-    if result.Error != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": result.Error.Error()})
-    }
-    menuTree := buildMenuTree(menus)
-    return c.JSON(menuTree)
+func GetAllMenusTree(c *gin.Context) {
+	var menus []models.Menu
+	if err := db.DB.Order("order_no ASC").Find(&menus).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, buildMenuTree(menus))
 }
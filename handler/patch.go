@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"fastener-api/db"
+)
+
+// bindMergePatch 依 JSON Merge Patch (RFC 7396) 解析請求內容，
+// 回傳可直接交給 GORM Updates 的「欄位名稱 → 值」map。
+//   - 未出現在 body 的欄位不會被更新
+//   - 明確給 null 的欄位：指標型別寫入 NULL，其餘型別寫入零值（例如清空字串）
+//   - editable 限定允許修改的 JSON 欄位，其餘欄位一律拒絕
+func bindMergePatch(c *gin.Context, model interface{}, editable ...string) (map[string]interface{}, error) {
+	raw, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+	return parseMergePatch(raw, model, editable...)
+}
+
+func parseMergePatch(raw []byte, model interface{}, editable ...string) (map[string]interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, errors.New("請求內容不可為空")
+	}
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &patch); err != nil {
+		return nil, errors.New("請求內容必須為 JSON 物件")
+	}
+
+	stmt := &gorm.Statement{DB: db.DB}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(editable))
+	for _, name := range editable {
+		allowed[name] = true
+	}
+
+	updates := make(map[string]interface{}, len(patch))
+	for key, value := range patch {
+		if !allowed[key] {
+			return nil, fmt.Errorf("欄位 %s 不可修改", key)
+		}
+		field := jsonField(stmt, key)
+		if field == nil {
+			return nil, fmt.Errorf("未知的欄位 %s", key)
+		}
+		target := reflect.New(field.FieldType)
+		if err := json.Unmarshal(value, target.Interface()); err != nil {
+			return nil, fmt.Errorf("欄位 %s 格式錯誤", key)
+		}
+		updates[field.DBName] = target.Elem().Interface()
+	}
+	return updates, nil
+}
+
+// jsonField 以 JSON tag 名稱找出對應的資料表欄位
+func jsonField(stmt *gorm.Statement, name string) *schema.Field {
+	for _, f := range stmt.Schema.Fields {
+		if f.DBName == "" {
+			continue
+		}
+		tag := strings.Split(f.StructField.Tag.Get("json"), ",")[0]
+		if tag == name {
+			return f
+		}
+	}
+	return nil
+}
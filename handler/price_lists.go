@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢交易條件下的價格表
+func GetPriceLists(c *gin.Context) {
+	term, ok := findScopedTerm(c)
+	if !ok {
+		return
+	}
+	var lists []models.PriceList
+	if err := db.DB.Where("transaction_term_id = ?", term.ID).Order("valid_from DESC").Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢價格表失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, lists)
+}
+
+// 查詢單一價格表（含明細）
+func GetPriceList(c *gin.Context) {
+	list, ok := findPriceList(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// 新增價格表，客戶、公司與幣別皆由交易條件帶入
+func CreatePriceList(c *gin.Context) {
+	term, ok := findScopedTerm(c)
+	if !ok {
+		return
+	}
+	var req models.PriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(term.CurrencyCode) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "交易條件未設定幣別，無法建立價格表"})
+		return
+	}
+	list := models.PriceList{
+		TransactionTermID: term.ID,
+		CustomerID:        term.CustomerID,
+		CompanyID:         term.CompanyID,
+		CurrencyCode:      strings.ToUpper(term.CurrencyCode),
+		IsActive:          true,
+	}
+	if err := applyPriceListRequest(&list, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.DB.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立價格表失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+// 修改價格表，明細整批取代
+func UpdatePriceList(c *gin.Context) {
+	list, ok := findPriceList(c)
+	if !ok {
+		return
+	}
+	var req models.PriceListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if err := applyPriceListRequest(&list, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", list.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&list).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新價格表失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// 刪除價格表
+func DeletePriceList(c *gin.Context) {
+	list, ok := findPriceList(c)
+	if !ok {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("price_list_id = ?", list.ID).Delete(&models.PriceListItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&list).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除價格表失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "價格表刪除成功"})
+}
+
+// 價格查詢：依客戶、公司、產品規格、數量與日期找出適用單價
+// GET /price-resolution?customer_id=&company_id=&product_specification_id=&quantity=&date=YYYY-MM-DD[&transaction_term_id=]
+func ResolvePrice(c *gin.Context) {
+	customerID, err1 := strconv.Atoi(c.Query("customer_id"))
+	specID, err2 := strconv.Atoi(c.Query("product_specification_id"))
+	quantity, err3 := strconv.ParseFloat(c.Query("quantity"), 64)
+	if err1 != nil || err2 != nil || err3 != nil || quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "customer_id、product_specification_id 與 quantity 為必填"})
+		return
+	}
+	_, companyID, _ := getRoleAndCompanyID(c)
+	if v := c.Query("company_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的公司 ID"})
+			return
+		}
+		companyID = uint(id)
+	}
+	if !canAccessCompany(c, companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限查詢此公司"})
+		return
+	}
+	date := time.Now()
+	if v := c.Query("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "日期格式需為 YYYY-MM-DD"})
+			return
+		}
+		date = d
+	}
+	var termID *uint
+	if v := c.Query("transaction_term_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的交易條件 ID"})
+			return
+		}
+		tid := uint(id)
+		termID = &tid
+	}
+
+	price, err := resolvePrice(uint(customerID), companyID, termID, uint(specID), quantity, date)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "查無適用的價格"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢價格失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, price)
+}
+
+// resolvePrice 取得適用單價：有效期間涵蓋 date 的啟用價格表中，起訂數量不超過 quantity 的最高級距；
+// 多張價格表重疊時以生效日較晚者優先。查無價格回傳 gorm.ErrRecordNotFound
+func resolvePrice(customerID, companyID uint, termID *uint, specID uint, quantity float64, date time.Time) (models.ResolvedPrice, error) {
+	var prices []models.ResolvedPrice
+	day := date.Format("2006-01-02")
+	query := db.DB.Table("price_list_items i").
+		Select(`pl.id AS price_list_id, pl.name AS price_list_name, pl.transaction_term_id,
+			i.product_specification_id, i.min_quantity, i.unit_price, i.unit,
+			pl.currency_code, pl.valid_from, pl.valid_to`).
+		Joins("JOIN price_lists pl ON pl.id = i.price_list_id").
+		Where("pl.customer_id = ? AND pl.company_id = ? AND pl.is_active = ?", customerID, companyID, true).
+		Where("pl.valid_from::date <= ?::date AND (pl.valid_to IS NULL OR pl.valid_to::date >= ?::date)", day, day).
+		Where("i.product_specification_id = ? AND i.min_quantity <= ?", specID, quantity).
+		Order("pl.valid_from DESC, i.min_quantity DESC").
+		Limit(1)
+	if termID != nil {
+		query = query.Where("pl.transaction_term_id = ?", *termID)
+	}
+	if err := query.Scan(&prices).Error; err != nil {
+		return models.ResolvedPrice{}, err
+	}
+	if len(prices) == 0 {
+		return models.ResolvedPrice{}, gorm.ErrRecordNotFound
+	}
+	prices[0].Quantity = quantity
+	return prices[0], nil
+}
+
+// priceSalesLines 為未指定單價的明細帶入價格表單價，價格表幣別必須與單據幣別一致
+func priceSalesLines(customerID, companyID uint, termID *uint, currency string, date time.Time, lines []models.SalesLineRequest) error {
+	for i := range lines {
+		if lines[i].UnitPrice != nil {
+			continue
+		}
+		if lines[i].ProductSpecificationID == nil {
+			return fmt.Errorf("第 %d 筆明細未指定單價，也未指定產品規格可查價", i+1)
+		}
+		price, err := resolvePrice(customerID, companyID, termID, *lines[i].ProductSpecificationID, lines[i].Quantity, date)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("第 %d 筆明細查無適用的價格表單價，請直接指定 unit_price", i+1)
+		}
+		if err != nil {
+			return err
+		}
+		if price.CurrencyCode != currency {
+			return fmt.Errorf("第 %d 筆明細的價格表幣別 %s 與單據幣別 %s 不同", i+1, price.CurrencyCode, currency)
+		}
+		unitPrice := price.UnitPrice
+		lines[i].UnitPrice = &unitPrice
+		if lines[i].Unit == "" {
+			lines[i].Unit = price.Unit
+		}
+	}
+	return nil
+}
+
+// findScopedTerm 依路徑 :termId 載入交易條件並檢查公司範圍
+func findScopedTerm(c *gin.Context) (models.CustomerTransactionTerm, bool) {
+	var term models.CustomerTransactionTerm
+	if err := db.DB.First(&term, c.Param("termId")).Error; err != nil || !canAccessCompany(c, term.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的交易條件"})
+		return term, false
+	}
+	return term, true
+}
+
+func findPriceList(c *gin.Context) (models.PriceList, bool) {
+	var list models.PriceList
+	err := db.DB.Preload("Items", func(q *gorm.DB) *gorm.DB { return q.Order("product_specification_id, min_quantity") }).
+		First(&list, c.Param("id")).Error
+	if err != nil || !canAccessCompany(c, list.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的價格表"})
+		return list, false
+	}
+	return list, true
+}
+
+// applyPriceListRequest 檢查並寫入價格表表頭與明細（未存檔）
+func applyPriceListRequest(list *models.PriceList, req models.PriceListRequest) error {
+	list.Name = strings.TrimSpace(req.Name)
+	if list.Name == "" {
+		return errors.New("價格表名稱為必填")
+	}
+	if req.ValidFrom == nil {
+		return errors.New("生效日為必填")
+	}
+	if req.ValidTo != nil && req.ValidTo.Before(*req.ValidFrom) {
+		return errors.New("到期日不可早於生效日")
+	}
+	if len(req.Items) == 0 {
+		return errors.New("至少需要一筆價格明細")
+	}
+	seen := map[string]bool{}
+	for i, item := range req.Items {
+		if item.ProductSpecificationID == 0 {
+			return fmt.Errorf("第 %d 筆明細需指定產品規格", i+1)
+		}
+		if item.MinQuantity < 0 || item.UnitPrice < 0 {
+			return fmt.Errorf("第 %d 筆明細起訂數量與單價不可為負數", i+1)
+		}
+		key := fmt.Sprintf("%d/%g", item.ProductSpecificationID, item.MinQuantity)
+		if seen[key] {
+			return fmt.Errorf("第 %d 筆明細與其他明細的產品規格與起訂數量重複", i+1)
+		}
+		seen[key] = true
+	}
+	var specCount int64
+	specIDs := make([]uint, 0, len(req.Items))
+	for _, item := range req.Items {
+		specIDs = append(specIDs, item.ProductSpecificationID)
+	}
+	db.DB.Model(&models.ProductSpecification{}).Where("id IN ?", specIDs).Distinct("id").Count(&specCount)
+	distinct := map[uint]bool{}
+	for _, id := range specIDs {
+		distinct[id] = true
+	}
+	if int(specCount) != len(distinct) {
+		return errors.New("明細中有不存在的產品規格")
+	}
+
+	list.ValidFrom = *req.ValidFrom
+	list.ValidTo = req.ValidTo
+	if req.IsActive != nil {
+		list.IsActive = *req.IsActive
+	}
+	list.Remarks = req.Remarks
+	list.Items = make([]models.PriceListItem, 0, len(req.Items))
+	for _, item := range req.Items {
+		list.Items = append(list.Items, models.PriceListItem{
+			ProductSpecificationID: item.ProductSpecificationID,
+			MinQuantity:            item.MinQuantity,
+			UnitPrice:              item.UnitPrice,
+			Unit:                   item.Unit,
+		})
+	}
+	return nil
+}
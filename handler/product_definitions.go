@@ -103,3 +103,40 @@ func DeleteProductCategory(c *gin.Context) {
 }
 
 // (未來可在這裡擴充 Shape, Function, Specification 等 handler)
+
+// 部分更新產品類別 (JSON Merge Patch)
+func PatchProductCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var category models.ProductCategory
+	if err := db.DB.First(&category, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product category not found"})
+		return
+	}
+
+	updates, err := bindMergePatch(c, &category, "category_code", "name")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+		return
+	}
+	for _, key := range []string{"category_code", "name"} {
+		if v, ok := updates[key].(string); ok {
+			updates[key] = strings.TrimSpace(v)
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(&category).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product category: " + err.Error()})
+			return
+		}
+	}
+
+	db.DB.First(&category, category.ID)
+	c.JSON(http.StatusOK, category)
+}
@@ -0,0 +1,540 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢原料爐號，可依 company_id、heat_no、material_grade、supplier 篩選
+func GetMaterialHeats(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("received_at DESC, id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "heat_no", "material_grade", "supplier"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var heats []models.MaterialHeat
+	if err := query.Find(&heats).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢爐號失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, heats)
+}
+
+// 新增原料爐號，company_id 未指定時為目前所在公司
+func CreateMaterialHeat(c *gin.Context) {
+	var heat models.MaterialHeat
+	if err := c.ShouldBindJSON(&heat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if heat.CompanyID == 0 {
+		_, heat.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, heat.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	heat.ID = 0
+	heat.HeatNo = strings.TrimSpace(heat.HeatNo)
+	heat.MaterialGrade = strings.TrimSpace(heat.MaterialGrade)
+	if heat.HeatNo == "" || heat.MaterialGrade == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "爐號與鋼種為必填"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.MaterialHeat{}).Where("company_id = ? AND heat_no = ?", heat.CompanyID, heat.HeatNo).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "爐號已存在"})
+		return
+	}
+	if err := db.DB.Create(&heat).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立爐號失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, heat)
+}
+
+// 部分更新原料爐號 (JSON Merge Patch)；爐號本身不可修改
+func PatchMaterialHeat(c *gin.Context) {
+	var heat models.MaterialHeat
+	if err := db.DB.First(&heat, c.Param("id")).Error; err != nil || !canAccessCompany(c, heat.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的爐號"})
+		return
+	}
+	updates, err := bindMergePatch(c, &heat, "material_grade", "supplier", "diameter_mm", "weight_kg", "received_at", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if err := db.DB.Model(&heat).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新爐號失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&heat, heat.ID)
+	c.JSON(http.StatusOK, heat)
+}
+
+// 查詢製程批次，可依 company_id、type、batch_no、vendor 篩選
+func GetProcessBatches(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("processed_at DESC, id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "type", "batch_no", "vendor"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var batches []models.ProcessBatch
+	if err := query.Find(&batches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢製程批次失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+// 新增製程批次（熱處理 / 電鍍）
+func CreateProcessBatch(c *gin.Context) {
+	var batch models.ProcessBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if batch.CompanyID == 0 {
+		_, batch.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, batch.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	validType := false
+	for _, t := range models.ProcessBatchTypes {
+		validType = validType || t == batch.Type
+	}
+	if !validType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "製程批次類型需為 heat_treatment 或 plating"})
+		return
+	}
+	batch.ID = 0
+	batch.BatchNo = strings.TrimSpace(batch.BatchNo)
+	if batch.BatchNo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批次號碼為必填"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.ProcessBatch{}).
+		Where("company_id = ? AND type = ? AND batch_no = ?", batch.CompanyID, batch.Type, batch.BatchNo).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "批次號碼已存在"})
+		return
+	}
+	if err := db.DB.Create(&batch).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立製程批次失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, batch)
+}
+
+// 部分更新製程批次 (JSON Merge Patch)
+func PatchProcessBatch(c *gin.Context) {
+	var batch models.ProcessBatch
+	if err := db.DB.First(&batch, c.Param("id")).Error; err != nil || !canAccessCompany(c, batch.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的製程批次"})
+		return
+	}
+	updates, err := bindMergePatch(c, &batch, "vendor", "process", "parameters", "processed_at", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if err := db.DB.Model(&batch).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新製程批次失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&batch, batch.ID)
+	c.JSON(http.StatusOK, batch)
+}
+
+// 查詢製令，可依 company_id、status、product_specification_id、sales_order_line_id、material_heat_id 篩選
+func GetProductionOrders(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "status", "product_specification_id", "sales_order_line_id"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	if v := c.Query("material_heat_id"); v != "" {
+		query = query.Where("id IN (?)", db.DB.Model(&models.ProductionOrderMaterial{}).
+			Select("production_order_id").Where("material_heat_id = ?", v))
+	}
+	var orders []models.ProductionOrder
+	if err := query.Preload("Materials").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢製令失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// 查詢單一製令（含投料）
+func GetProductionOrder(c *gin.Context) {
+	order, ok := findProductionOrder(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 新增製令，投料爐號需屬於同一集團
+func CreateProductionOrder(c *gin.Context) {
+	var req models.ProductionOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	if req.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "生產數量需大於 0"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.ProductSpecification{}).Where("id = ?", req.ProductSpecificationID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "產品規格不存在"})
+		return
+	}
+	if req.SalesOrderLineID != nil {
+		var companyID uint
+		db.DB.Table("sales_order_lines").
+			Joins("JOIN sales_orders ON sales_orders.id = sales_order_lines.sales_order_id").
+			Where("sales_order_lines.id = ?", *req.SalesOrderLineID).
+			Select("sales_orders.company_id").Scan(&companyID)
+		if companyID == 0 || !canAccessCompany(c, companyID) || !sameCompanyGroup(companyID, req.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的訂單明細"})
+			return
+		}
+	}
+	order := models.ProductionOrder{
+		CompanyID:              req.CompanyID,
+		ProductSpecificationID: req.ProductSpecificationID,
+		SalesOrderLineID:       req.SalesOrderLineID,
+		Quantity:               req.Quantity,
+		Unit:                   strings.TrimSpace(req.Unit),
+		Status:                 models.ProductionPlanned,
+		Remarks:                req.Remarks,
+		CreatedBy:              c.GetString("username"),
+	}
+	heatIDs := make([]uint, 0, len(req.Materials))
+	for _, m := range req.Materials {
+		heatIDs = append(heatIDs, m.MaterialHeatID)
+		order.Materials = append(order.Materials, models.ProductionOrderMaterial{MaterialHeatID: m.MaterialHeatID, QuantityKG: m.QuantityKG})
+	}
+	if err := checkTraceRefs(c, req.CompanyID, heatIDs, nil); err != nil {
+		respondTxError(c, err, "建立製令")
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		no, err := nextSequenceNo(tx, order.CompanyID, models.ProductionOrderSequence, time.Now())
+		if err != nil {
+			return err
+		}
+		order.OrderNo = no
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立製令失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, order)
+}
+
+// 開工
+func StartProductionOrder(c *gin.Context) {
+	transitionProductionOrder(c, models.ProductionInProgress, func(order *models.ProductionOrder) error {
+		if order.Status != models.ProductionPlanned {
+			return errors.New("僅已排程的製令可開工")
+		}
+		now := time.Now()
+		order.StartedAt = &now
+		return nil
+	})
+}
+
+// 完工
+func CompleteProductionOrder(c *gin.Context) {
+	transitionProductionOrder(c, models.ProductionCompleted, func(order *models.ProductionOrder) error {
+		if order.Status != models.ProductionInProgress {
+			return errors.New("僅生產中的製令可完工")
+		}
+		now := time.Now()
+		order.CompletedAt = &now
+		return nil
+	})
+}
+
+// 取消製令；已建立成品批號的製令不可取消
+func CancelProductionOrder(c *gin.Context) {
+	transitionProductionOrder(c, models.ProductionCancelled, func(order *models.ProductionOrder) error {
+		if order.Status != models.ProductionPlanned && order.Status != models.ProductionInProgress {
+			return errors.New("已完工或已取消的製令不可取消")
+		}
+		var count int64
+		db.DB.Model(&models.ProductionLot{}).Where("production_order_id = ?", order.ID).Count(&count)
+		if count > 0 {
+			return errors.New("製令已有成品批號，不可取消")
+		}
+		return nil
+	})
+}
+
+func transitionProductionOrder(c *gin.Context, status string, check func(order *models.ProductionOrder) error) {
+	order, ok := findProductionOrder(c)
+	if !ok {
+		return
+	}
+	if err := check(&order); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	order.Status = status
+	if err := db.DB.Model(&order).Select("status", "started_at", "completed_at").Updates(&order).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新製令狀態失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 查詢成品批號，可依 company_id、lot_no、product_specification_id、production_order_id、
+// material_heat_id、process_batch_id 篩選
+func GetProductionLots(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	for _, f := range []string{"company_id", "lot_no", "product_specification_id", "production_order_id"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	if v := c.Query("material_heat_id"); v != "" {
+		query = query.Where("id IN (?)", db.DB.Model(&models.ProductionLotHeat{}).Select("lot_id").Where("material_heat_id = ?", v))
+	}
+	if v := c.Query("process_batch_id"); v != "" {
+		query = query.Where("id IN (?)", db.DB.Model(&models.ProductionLotBatch{}).Select("lot_id").Where("process_batch_id = ?", v))
+	}
+	var lots []models.ProductionLot
+	if err := query.Preload("Heats").Preload("Batches").Find(&lots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢成品批號失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, lots)
+}
+
+// 查詢單一成品批號
+func GetProductionLot(c *gin.Context) {
+	lot, ok := findProductionLot(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, lot)
+}
+
+// 新增成品批號；指定製令時產品規格、單位與爐號預設由製令帶入
+func CreateProductionLot(c *gin.Context) {
+	var req models.ProductionLotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	lot := models.ProductionLot{
+		CompanyID: req.CompanyID,
+		LotNo:     strings.TrimSpace(req.LotNo),
+		CreatedBy: c.GetString("username"),
+	}
+	if lot.LotNo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批號為必填"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.ProductionLot{}).Where("company_id = ? AND lot_no = ?", lot.CompanyID, lot.LotNo).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "批號已存在"})
+		return
+	}
+	if !applyProductionLotRequest(c, &lot, req) {
+		return
+	}
+	if err := db.DB.Create(&lot).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立成品批號失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, lot)
+}
+
+// 修改成品批號，爐號與製程批次整批取代；批號與公司不可修改
+func UpdateProductionLot(c *gin.Context) {
+	lot, ok := findProductionLot(c)
+	if !ok {
+		return
+	}
+	var req models.ProductionLotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if !applyProductionLotRequest(c, &lot, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lot_id = ?", lot.ID).Delete(&models.ProductionLotHeat{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("lot_id = ?", lot.ID).Delete(&models.ProductionLotBatch{}).Error; err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(&lot).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新成品批號失敗: " + err.Error()})
+		return
+	}
+	lot, _ = findProductionLot(c)
+	c.JSON(http.StatusOK, lot)
+}
+
+// applyProductionLotRequest 檢查並套用成品批號的產品、數量與追溯關聯
+func applyProductionLotRequest(c *gin.Context, lot *models.ProductionLot, req models.ProductionLotRequest) bool {
+	lot.ProductionOrderID = req.ProductionOrderID
+	lot.ProductSpecificationID = req.ProductSpecificationID
+	lot.Quantity = req.Quantity
+	lot.Unit = strings.TrimSpace(req.Unit)
+	lot.ProducedAt = req.ProducedAt
+	lot.Remarks = req.Remarks
+	heatIDs := req.MaterialHeatIDs
+	if req.ProductionOrderID != nil {
+		var order models.ProductionOrder
+		err := db.DB.Preload("Materials").First(&order, *req.ProductionOrderID).Error
+		if err != nil || !canAccessCompany(c, order.CompanyID) || !sameCompanyGroup(order.CompanyID, lot.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的製令"})
+			return false
+		}
+		if order.Status == models.ProductionCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "製令已取消"})
+			return false
+		}
+		if lot.ProductSpecificationID == 0 {
+			lot.ProductSpecificationID = order.ProductSpecificationID
+		}
+		if lot.ProductSpecificationID != order.ProductSpecificationID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "批號的產品規格與製令不同"})
+			return false
+		}
+		lot.Unit = firstNonEmpty(lot.Unit, order.Unit)
+		if len(heatIDs) == 0 {
+			for _, m := range order.Materials {
+				heatIDs = append(heatIDs, m.MaterialHeatID)
+			}
+		}
+	}
+	var count int64
+	db.DB.Model(&models.ProductSpecification{}).Where("id = ?", lot.ProductSpecificationID).Count(&count)
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "產品規格不存在"})
+		return false
+	}
+	if lot.Quantity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "批號數量需大於 0"})
+		return false
+	}
+	heatIDs, batchIDs := uniqueIDs(heatIDs), uniqueIDs(req.ProcessBatchIDs)
+	if err := checkTraceRefs(c, lot.CompanyID, heatIDs, batchIDs); err != nil {
+		respondTxError(c, err, "儲存成品批號")
+		return false
+	}
+	lot.Heats, lot.Batches = nil, nil
+	for _, id := range heatIDs {
+		lot.Heats = append(lot.Heats, models.ProductionLotHeat{MaterialHeatID: id})
+	}
+	for _, id := range batchIDs {
+		lot.Batches = append(lot.Batches, models.ProductionLotBatch{ProcessBatchID: id})
+	}
+	return true
+}
+
+// checkTraceRefs 爐號與製程批次需存在、可存取且與 companyID 屬於同一集團
+func checkTraceRefs(c *gin.Context, companyID uint, heatIDs, batchIDs []uint) error {
+	for _, id := range heatIDs {
+		var heat models.MaterialHeat
+		if err := db.DB.First(&heat, id).Error; err != nil || !canAccessCompany(c, heat.CompanyID) || !sameCompanyGroup(heat.CompanyID, companyID) {
+			return newRequestError(http.StatusBadRequest, "找不到指定的爐號 %d", id)
+		}
+	}
+	for _, id := range batchIDs {
+		var batch models.ProcessBatch
+		if err := db.DB.First(&batch, id).Error; err != nil || !canAccessCompany(c, batch.CompanyID) || !sameCompanyGroup(batch.CompanyID, companyID) {
+			return newRequestError(http.StatusBadRequest, "找不到指定的製程批次 %d", id)
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func findProductionOrder(c *gin.Context) (models.ProductionOrder, bool) {
+	var order models.ProductionOrder
+	if err := db.DB.Preload("Materials").First(&order, c.Param("id")).Error; err != nil || !canAccessCompany(c, order.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的製令"})
+		return order, false
+	}
+	return order, true
+}
+
+func findProductionLot(c *gin.Context) (models.ProductionLot, bool) {
+	var lot models.ProductionLot
+	if err := db.DB.Preload("Heats").Preload("Batches").First(&lot, c.Param("id")).Error; err != nil || !canAccessCompany(c, lot.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的成品批號"})
+		return lot, false
+	}
+	return lot, true
+}
@@ -0,0 +1,593 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/docrender"
+	"fastener-api/models"
+)
+
+const maxCertificateFileSize = 10 << 20
+
+// 材質證明原始檔案允許的格式
+var certificateFileTypes = map[string]bool{"application/pdf": true, "image/jpeg": true, "image/png": true}
+
+// 查詢材質證明，可依 material_heat_id、certificate_no 篩選
+func GetMillCertificates(c *gin.Context) {
+	heats, ok := scopeByCompany(c, db.DB.Model(&models.MaterialHeat{}).Select("id"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	query := db.DB.Omit("file").Preload("Elements").Where("material_heat_id IN (?)", heats).Order("id DESC")
+	for _, f := range []string{"material_heat_id", "certificate_no"} {
+		if v := c.Query(f); v != "" {
+			query = query.Where(f+" = ?", v)
+		}
+	}
+	var certs []models.MillCertificate
+	if err := query.Find(&certs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢材質證明失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// 新增材質證明（含化學成分），原始檔案另以上傳 API 存放
+func CreateMillCertificate(c *gin.Context) {
+	var req models.MillCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	var cert models.MillCertificate
+	if !applyMillCertificateRequest(c, &cert, req) {
+		return
+	}
+	if err := db.DB.Create(&cert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立材質證明失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, cert)
+}
+
+// 修改材質證明，化學成分整批取代；已上傳的檔案保留
+func UpdateMillCertificate(c *gin.Context) {
+	cert, ok := findMillCertificate(c)
+	if !ok {
+		return
+	}
+	var req models.MillCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if !applyMillCertificateRequest(c, &cert, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&cert).
+			Select("material_heat_id", "certificate_no", "standard", "issued_by", "issued_at", "remarks", "updated_by").
+			Updates(&cert).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("mill_certificate_id = ?", cert.ID).Delete(&models.MillCertificateElement{}).Error; err != nil {
+			return err
+		}
+		for i := range cert.Elements {
+			cert.Elements[i].MillCertificateID = cert.ID
+		}
+		if len(cert.Elements) == 0 {
+			return nil
+		}
+		return tx.Create(&cert.Elements).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新材質證明失敗: " + err.Error()})
+		return
+	}
+	cert, _ = findMillCertificate(c)
+	c.JSON(http.StatusOK, cert)
+}
+
+// 刪除材質證明
+func DeleteMillCertificate(c *gin.Context) {
+	cert, ok := findMillCertificate(c)
+	if !ok {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mill_certificate_id = ?", cert.ID).Delete(&models.MillCertificateElement{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&cert).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除材質證明失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "材質證明刪除成功"})
+}
+
+// 上傳材質證明原始檔案（multipart 欄位 file；PDF / JPEG / PNG，10MB 以內），重複上傳時取代
+func UploadMillCertificateFile(c *gin.Context) {
+	cert, ok := findMillCertificate(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請以 file 欄位上傳檔案"})
+		return
+	}
+	if header.Size > maxCertificateFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "檔案大小不可超過 10MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxCertificateFileSize+1))
+	if err != nil || len(data) > maxCertificateFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "讀取檔案失敗或檔案過大"})
+		return
+	}
+	contentType := http.DetectContentType(data)
+	if !certificateFileTypes[contentType] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "僅接受 PDF、JPEG 或 PNG 檔案"})
+		return
+	}
+	cert.File = data
+	cert.FileName = header.Filename
+	cert.FileContentType = contentType
+	cert.FileSize = len(data)
+	cert.UpdatedBy = c.GetString("username")
+	err = db.DB.Model(&cert).Select("file", "file_name", "file_content_type", "file_size", "updated_by").Updates(&cert).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "儲存檔案失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cert)
+}
+
+// 下載材質證明原始檔案
+func DownloadMillCertificateFile(c *gin.Context) {
+	cert, ok := findMillCertificate(c)
+	if !ok {
+		return
+	}
+	var file []byte
+	db.DB.Model(&models.MillCertificate{}).Where("id = ?", cert.ID).Select("file").Row().Scan(&file)
+	if len(file) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "尚未上傳材質證明檔案"})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(firstNonEmpty(cert.FileName, cert.CertificateNo)))
+	c.Data(http.StatusOK, cert.FileContentType, file)
+}
+
+// applyMillCertificateRequest 檢查並套用材質證明內容，爐號需可存取
+func applyMillCertificateRequest(c *gin.Context, cert *models.MillCertificate, req models.MillCertificateRequest) bool {
+	var heat models.MaterialHeat
+	if err := db.DB.First(&heat, req.MaterialHeatID).Error; err != nil || !canAccessCompany(c, heat.CompanyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的爐號"})
+		return false
+	}
+	cert.MaterialHeatID = heat.ID
+	cert.CertificateNo = strings.TrimSpace(req.CertificateNo)
+	if cert.CertificateNo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "材質證明號碼為必填"})
+		return false
+	}
+	cert.Standard = strings.TrimSpace(req.Standard)
+	cert.IssuedBy = firstNonEmpty(strings.TrimSpace(req.IssuedBy), heat.Supplier)
+	cert.IssuedAt = req.IssuedAt
+	cert.Remarks = req.Remarks
+	cert.UpdatedBy = c.GetString("username")
+	cert.Elements = nil
+	for _, e := range req.Elements {
+		element := strings.TrimSpace(e.Element)
+		if element == "" || e.Percent < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "化學成分需有元素名稱且含量不可為負"})
+			return false
+		}
+		cert.Elements = append(cert.Elements, models.MillCertificateElement{Element: element, Percent: e.Percent})
+	}
+	return true
+}
+
+// findMillCertificate 載入材質證明（不含檔案內容）並依爐號檢查公司範圍
+func findMillCertificate(c *gin.Context) (models.MillCertificate, bool) {
+	var cert models.MillCertificate
+	var heat models.MaterialHeat
+	err := db.DB.Omit("file").Preload("Elements", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).
+		First(&cert, c.Param("id")).Error
+	if err == nil {
+		err = db.DB.First(&heat, cert.MaterialHeatID).Error
+	}
+	if err != nil || !canAccessCompany(c, heat.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的材質證明"})
+		return cert, false
+	}
+	return cert, true
+}
+
+// 查詢成品批號的檢驗結果
+func GetLotInspections(c *gin.Context) {
+	lot, ok := findProductionLot(c)
+	if !ok {
+		return
+	}
+	var results []models.InspectionResult
+	if err := db.DB.Where("lot_id = ?", lot.ID).Order("type, id").Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢檢驗結果失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// 新增成品批號的檢驗結果，合格與否依規格上下限判定
+func CreateLotInspection(c *gin.Context) {
+	lot, ok := findProductionLot(c)
+	if !ok {
+		return
+	}
+	var req models.InspectionResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	result := models.InspectionResult{LotID: lot.ID}
+	if !applyInspectionRequest(c, &result, req) {
+		return
+	}
+	if err := db.DB.Create(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立檢驗結果失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// 修改檢驗結果；已產生的證明書保有當時的快照，不受影響
+func UpdateInspectionResult(c *gin.Context) {
+	result, ok := findInspectionResult(c)
+	if !ok {
+		return
+	}
+	var req models.InspectionResultRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if !applyInspectionRequest(c, &result, req) {
+		return
+	}
+	if err := db.DB.Save(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新檢驗結果失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// 刪除檢驗結果
+func DeleteInspectionResult(c *gin.Context) {
+	result, ok := findInspectionResult(c)
+	if !ok {
+		return
+	}
+	if err := db.DB.Delete(&result).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除檢驗結果失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "檢驗結果刪除成功"})
+}
+
+// applyInspectionRequest 檢查並套用檢驗結果；只填平均值時最小 / 最大值同平均值
+func applyInspectionRequest(c *gin.Context, r *models.InspectionResult, req models.InspectionResultRequest) bool {
+	validType := false
+	for _, t := range models.InspectionTypes {
+		validType = validType || t == req.Type
+	}
+	if !validType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "檢驗項目類型需為 " + strings.Join(models.InspectionTypes, ", ")})
+		return false
+	}
+	if req.SpecMin == nil && req.SpecMax == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "規格上限與下限至少需填一項"})
+		return false
+	}
+	if req.SpecMin != nil && req.SpecMax != nil && *req.SpecMin > *req.SpecMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "規格下限不可大於上限"})
+		return false
+	}
+	// 只提供部分實測值時，最小/最大值以平均值（或另一端）代替，平均值以最小與最大值的中點代替
+	measuredMin := firstNonNil(req.MeasuredMin, req.MeasuredAvg, req.MeasuredMax)
+	measuredMax := firstNonNil(req.MeasuredMax, req.MeasuredAvg, req.MeasuredMin)
+	if measuredMin == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請輸入實測值"})
+		return false
+	}
+	if *measuredMin > *measuredMax {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "實測最小值不可大於最大值"})
+		return false
+	}
+	measuredAvg := (*measuredMin + *measuredMax) / 2
+	if req.MeasuredAvg != nil {
+		measuredAvg = *req.MeasuredAvg
+	}
+	if req.SampleSize <= 0 {
+		req.SampleSize = 1
+	}
+	r.Type = req.Type
+	r.Characteristic = firstNonEmpty(strings.TrimSpace(req.Characteristic), req.Type)
+	r.Method = strings.TrimSpace(req.Method)
+	r.SpecMin, r.SpecMax = req.SpecMin, req.SpecMax
+	r.Unit = strings.TrimSpace(req.Unit)
+	r.Requirement = firstNonEmpty(strings.TrimSpace(req.Requirement), formatRequirement(req.SpecMin, req.SpecMax, r.Unit))
+	r.SampleSize = req.SampleSize
+	r.MeasuredMin, r.MeasuredMax, r.MeasuredAvg = *measuredMin, *measuredMax, measuredAvg
+	r.Passed = (r.SpecMin == nil || r.MeasuredMin >= *r.SpecMin) && (r.SpecMax == nil || r.MeasuredMax <= *r.SpecMax)
+	r.InspectedAt = time.Now()
+	if req.InspectedAt != nil {
+		r.InspectedAt = *req.InspectedAt
+	}
+	r.InspectedBy = c.GetString("username")
+	r.Remarks = req.Remarks
+	return true
+}
+
+// firstNonNil 回傳第一個非 nil 的值
+func firstNonNil(values ...*float64) *float64 {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func findInspectionResult(c *gin.Context) (models.InspectionResult, bool) {
+	var result models.InspectionResult
+	var lot models.ProductionLot
+	err := db.DB.First(&result, c.Param("id")).Error
+	if err == nil {
+		err = db.DB.First(&lot, result.LotID).Error
+	}
+	if err != nil || !canAccessCompany(c, lot.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的檢驗結果"})
+		return result, false
+	}
+	return result, true
+}
+
+// 訂單各明細的檢驗證明書：每筆出貨明細一份，列出最新產生的版本供下載
+func GetSalesOrderCertificates(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	lineNos := make(map[uint]int, len(order.Lines))
+	for _, l := range order.Lines {
+		lineNos[l.ID] = l.LineNo
+	}
+	result := []models.OrderLineCertificate{}
+	for _, s := range order.Shipments {
+		for _, sl := range s.Lines {
+			cert := models.OrderLineCertificate{
+				SalesOrderLineID: sl.SalesOrderLineID,
+				LineNo:           lineNos[sl.SalesOrderLineID],
+				ShipmentID:       s.ID,
+				ShipmentNo:       s.ShipmentNo,
+				ShipmentLineID:   sl.ID,
+				Quantity:         sl.Quantity,
+			}
+			var latest []models.RenderedDocument
+			db.DB.Omit("snapshot", "pdf").Where("doc_type = ? AND source_id = ?", models.DocInspectionCert, sl.ID).
+				Order("version DESC").Limit(1).Find(&latest)
+			if len(latest) > 0 {
+				cert.Latest = &latest[0]
+			}
+			result = append(result, cert)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].LineNo != result[j].LineNo {
+			return result[i].LineNo < result[j].LineNo
+		}
+		return result[i].ShipmentNo < result[j].ShipmentNo
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// findShipmentLine 依路徑 :id 載入出貨明細、出貨與訂單，並檢查公司範圍
+func findShipmentLine(c *gin.Context) (models.SalesOrderShipmentLine, models.SalesOrderShipment, models.SalesOrder, bool) {
+	var line models.SalesOrderShipmentLine
+	var shipment models.SalesOrderShipment
+	var order models.SalesOrder
+	err := db.DB.First(&line, c.Param("id")).Error
+	if err == nil {
+		err = db.DB.First(&shipment, line.ShipmentID).Error
+	}
+	if err == nil {
+		err = db.DB.Preload("Lines").First(&order, shipment.SalesOrderID).Error
+	}
+	if err != nil || !canAccessCompany(c, order.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的出貨明細"})
+		return line, shipment, order, false
+	}
+	return line, shipment, order, true
+}
+
+// certificateLot 證明書上的一個出貨批號
+type certificateLot struct {
+	lot       models.ProductionLot
+	quantity  float64
+	heats     []models.MaterialHeat
+	millCerts map[uint]models.MillCertificate
+	results   []models.InspectionResult
+}
+
+// inspectionCertificateLots 依出庫異動找出出貨中該訂單明細的批號，並載入爐號、材質證明與檢驗結果；
+// 每個批號都需有成品批號資料及全數合格的檢驗結果才可出具 3.1 證明書
+func inspectionCertificateLots(shipment models.SalesOrderShipment, orderLine models.SalesOrderLine) ([]certificateLot, error) {
+	if orderLine.ProductSpecificationID == nil {
+		return nil, newRequestError(http.StatusConflict, "訂單明細 %d 未指定產品規格，無法對應批號", orderLine.LineNo)
+	}
+	specID := *orderLine.ProductSpecificationID
+	var issued []struct {
+		CompanyID uint
+		LotNo     string
+		Quantity  float64
+	}
+	err := db.DB.Model(&models.StockMovement{}).
+		Select("company_id, lot_no, -SUM(quantity) AS quantity").
+		Where("shipment_id = ? AND type = ? AND sales_order_line_id = ?", shipment.ID, models.StockIssue, orderLine.ID).
+		Group("company_id, lot_no").Order("lot_no").
+		Scan(&issued).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(issued) == 0 {
+		return nil, newRequestError(http.StatusConflict, "出貨 %s 尚無訂單明細 %d 的出庫批號，無法產生檢驗證明書", shipment.ShipmentNo, orderLine.LineNo)
+	}
+
+	var lots []certificateLot
+	for _, m := range issued {
+		lot := findGroupLot(db.DB, m.CompanyID, specID, m.LotNo)
+		if lot.ID == 0 {
+			return nil, newRequestError(http.StatusConflict, "批號 %s 沒有成品批號資料", m.LotNo)
+		}
+		cl := certificateLot{lot: lot, quantity: m.Quantity, millCerts: make(map[uint]models.MillCertificate)}
+		if err := db.DB.Where("lot_id = ?", lot.ID).Order("type, id").Find(&cl.results).Error; err != nil {
+			return nil, err
+		}
+		if len(cl.results) == 0 {
+			return nil, newRequestError(http.StatusConflict, "批號 %s 尚無檢驗結果", lot.LotNo)
+		}
+		for _, r := range cl.results {
+			if !r.Passed {
+				return nil, newRequestError(http.StatusConflict, "批號 %s 的 %s 檢驗不合格，不可出具 3.1 證明書", lot.LotNo, r.Characteristic)
+			}
+		}
+		err := db.DB.Where("id IN (?)", db.DB.Model(&models.ProductionLotHeat{}).Select("material_heat_id").Where("lot_id = ?", lot.ID)).
+			Order("heat_no").Find(&cl.heats).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range cl.heats {
+			var certs []models.MillCertificate
+			db.DB.Omit("file").Preload("Elements", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).
+				Where("material_heat_id = ?", h.ID).Order("issued_at DESC NULLS LAST, id DESC").Limit(1).Find(&certs)
+			if len(certs) > 0 {
+				cl.millCerts[h.ID] = certs[0]
+			}
+		}
+		lots = append(lots, cl)
+	}
+	return lots, nil
+}
+
+// inspectionCertificateDocument EN 10204 3.1 檢驗證明書：每個批號列出原料爐號的化學成分與自主檢驗結果
+func inspectionCertificateDocument(order models.SalesOrder, shipment models.SalesOrderShipment, line models.SalesOrderShipmentLine,
+	orderLine models.SalesOrderLine, certNo string, lots []certificateLot, lang string) (docrender.Document, error) {
+	buyer, err := siteParty(lang, "buyer", order.CustomerID, order.SoldToSiteID)
+	if err != nil {
+		return docrender.Document{}, err
+	}
+	doc := docrender.Document{
+		Title: docrender.Label(lang, "inspection_cert"),
+		Fields: nonEmptyFields(lang,
+			"certificate_no", certNo,
+			"date", formatDate(&shipment.ShippedAt),
+			"order_no", order.OrderNo,
+			"customer_po", order.CustomerPONo,
+			"shipment_no", shipment.ShipmentNo,
+			"product", orderLine.Description,
+			"quantity", formatQuantity(line.Quantity)+" "+orderLine.Unit),
+		Parties: []docrender.Party{buyer},
+		Columns: []docrender.Column{
+			{Label: docrender.Label(lang, "lot_no"), Width: 14},
+			{Label: docrender.Label(lang, "heat_no"), Width: 13},
+			{Label: docrender.Label(lang, "test_item"), Width: 23},
+			{Label: docrender.Label(lang, "requirement"), Width: 16},
+			{Label: docrender.Label(lang, "result"), Width: 24},
+			{Label: docrender.Label(lang, "judgment"), Width: 10},
+		},
+	}
+	var inspectors []string
+	seen := make(map[string]bool)
+	for _, cl := range lots {
+		lotCell := cl.lot.LotNo + "\n" + formatQuantity(cl.quantity) + " " + orderLine.Unit
+		for _, h := range cl.heats {
+			item := docrender.Label(lang, "chemistry")
+			result := "-"
+			if cert, ok := cl.millCerts[h.ID]; ok {
+				item += "\n" + docrender.Label(lang, "mill_cert") + ": " + cert.CertificateNo
+				var elements []string
+				for _, e := range cert.Elements {
+					elements = append(elements, e.Element+" "+formatQuantity(e.Percent))
+				}
+				result = firstNonEmpty(strings.Join(elements, "  "), result)
+			}
+			doc.Rows = append(doc.Rows, []string{lotCell, h.HeatNo, item, h.MaterialGrade, result, ""})
+			lotCell = ""
+		}
+		for _, r := range cl.results {
+			item := r.Characteristic
+			if r.Method != "" {
+				item += "\n" + r.Method
+			}
+			doc.Rows = append(doc.Rows, []string{lotCell, "", item, r.Requirement, formatMeasurement(r), docrender.Label(lang, "pass")})
+			lotCell = ""
+			if r.InspectedBy != "" && !seen[r.InspectedBy] {
+				seen[r.InspectedBy] = true
+				inspectors = append(inspectors, r.InspectedBy)
+			}
+		}
+	}
+	doc.Notes = append(doc.Notes, docrender.Label(lang, "declaration_3_1"))
+	if len(inspectors) > 0 {
+		doc.Notes = append(doc.Notes, docrender.Label(lang, "inspector")+": "+strings.Join(inspectors, ", "))
+	}
+	return doc, nil
+}
+
+// formatRequirement 依規格上下限產生顯示文字，例如 22 - 32 HRC、min. 800 MPa
+func formatRequirement(min, max *float64, unit string) string {
+	var s string
+	switch {
+	case min != nil && max != nil:
+		s = formatQuantity(*min) + " - " + formatQuantity(*max)
+	case min != nil:
+		s = "min. " + formatQuantity(*min)
+	case max != nil:
+		s = "max. " + formatQuantity(*max)
+	}
+	return strings.TrimSpace(s + " " + unit)
+}
+
+// formatMeasurement 實測值：多個樣本時顯示範圍與平均值
+func formatMeasurement(r models.InspectionResult) string {
+	s := formatQuantity(r.MeasuredAvg)
+	if r.MeasuredMin != r.MeasuredMax {
+		s = fmt.Sprintf("%s - %s (avg %s)", formatQuantity(r.MeasuredMin), formatQuantity(r.MeasuredMax), formatQuantity(r.MeasuredAvg))
+	}
+	if r.SampleSize > 1 {
+		s += fmt.Sprintf(" n=%d", r.SampleSize)
+	}
+	return strings.TrimSpace(s + " " + r.Unit)
+}
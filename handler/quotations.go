@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢報價單列表，可依 customer_id、status 篩選
+func GetQuotations(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("customer_id"); v != "" {
+		query = query.Where("customer_id = ?", v)
+	}
+	if v := c.Query("status"); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	var quotes []models.Quotation
+	if err := query.Find(&quotes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢報價單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quotes)
+}
+
+// 查詢單一報價單（含明細）
+func GetQuotation(c *gin.Context) {
+	quote, ok := findQuotation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// 新增報價單，交易條件由客戶在報價公司的主要交易條件帶入
+func CreateQuotation(c *gin.Context) {
+	var req models.QuotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	quote := models.Quotation{Status: models.QuotationDraft, CreatedBy: c.GetString("username")}
+	if !applyQuotationRequest(c, &quote, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		no, err := nextSequenceNo(tx, quote.CompanyID, models.QuotationSequence, time.Now())
+		if err != nil {
+			return err
+		}
+		quote.QuoteNo = no
+		return tx.Create(&quote).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立報價單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, quote)
+}
+
+// 修改報價單（僅限草稿），明細整批取代
+func UpdateQuotation(c *gin.Context) {
+	quote, ok := findQuotation(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuotationDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "僅草稿狀態的報價單可修改"})
+		return
+	}
+	var req models.QuotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if !applyQuotationRequest(c, &quote, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("quotation_id = ?", quote.ID).Delete(&models.QuotationLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&quote).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新報價單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// 變更報價單狀態：draft → sent → accepted / rejected（草稿也可直接標記接受或拒絕）
+func UpdateQuotationStatus(c *gin.Context) {
+	quote, ok := findQuotation(c)
+	if !ok {
+		return
+	}
+	var req models.QuotationStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	allowed := map[string][]string{
+		models.QuotationDraft: {models.QuotationSent, models.QuotationAccepted, models.QuotationRejected},
+		models.QuotationSent:  {models.QuotationAccepted, models.QuotationRejected},
+	}
+	valid := false
+	for _, s := range allowed[quote.Status] {
+		if s == req.Status {
+			valid = true
+		}
+	}
+	if !valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "報價單狀態無法由 " + quote.Status + " 變更為 " + req.Status})
+		return
+	}
+	if err := db.DB.Model(&quote).Update("status", req.Status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新報價單狀態失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+// 刪除報價單（僅限草稿）
+func DeleteQuotation(c *gin.Context) {
+	quote, ok := findQuotation(c)
+	if !ok {
+		return
+	}
+	if quote.Status != models.QuotationDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "僅草稿狀態的報價單可刪除"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("quotation_id = ?", quote.ID).Delete(&models.QuotationLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&quote).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除報價單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "報價單刪除成功"})
+}
+
+// findQuotation 依路徑 :id 載入報價單與明細，並檢查公司範圍
+func findQuotation(c *gin.Context) (models.Quotation, bool) {
+	var quote models.Quotation
+	err := db.DB.Preload("Lines", func(tx *gorm.DB) *gorm.DB { return tx.Order("line_no") }).
+		First(&quote, c.Param("id")).Error
+	if err != nil || !canAccessCompany(c, quote.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的報價單"})
+		return quote, false
+	}
+	return quote, true
+}
+
+// applyQuotationRequest 檢查請求並寫入報價單表頭與明細（未存檔）
+func applyQuotationRequest(c *gin.Context, quote *models.Quotation, req models.QuotationRequest) bool {
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return false
+	}
+	var customer models.Customer
+	if err := db.DB.First(&customer, req.CustomerID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的客戶"})
+		return false
+	}
+	if err := validateSalesLines(req.Lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	terms, termID, err := resolveTradeTerms(req.CustomerID, req.CompanyID, req.TransactionTermID, req.TradeTermsRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if err := priceSalesLines(req.CustomerID, req.CompanyID, termID, terms.CurrencyCode, time.Now(), req.Lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	quote.CompanyID = req.CompanyID
+	quote.CustomerID = req.CustomerID
+	quote.TransactionTermID = termID
+	quote.TradeTerms = terms
+	quote.ValidUntil = req.ValidUntil
+	quote.Remarks = req.Remarks
+	quote.Lines = nil
+	quote.TotalAmount = 0
+	for i, l := range req.Lines {
+		amount := roundAmount(l.Quantity * *l.UnitPrice)
+		quote.Lines = append(quote.Lines, models.QuotationLine{
+			LineNo:                 i + 1,
+			ProductCategoryID:      l.ProductCategoryID,
+			ProductSpecificationID: l.ProductSpecificationID,
+			Description:            l.Description,
+			Quantity:               l.Quantity,
+			Unit:                   l.Unit,
+			UnitPrice:              *l.UnitPrice,
+			Amount:                 amount,
+			DeliveryDate:           l.DeliveryDate,
+		})
+		quote.TotalAmount += amount
+	}
+	quote.TotalAmount = roundAmount(quote.TotalAmount)
+	return true
+}
@@ -1,53 +1,180 @@
 package handler
 
 import (
-    "net/http"
-    "github.com/gin-gonic/gin"
-    "fastener-api/db"
-    "fastener-api/models"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
 )
 
 // 查詢角色擁有哪些 menu（回傳 menu id list）
 func GetRoleMenus(c *gin.Context) {
-    roleID := c.Query("role_id")
-    var rels []models.RoleMenuRelation
-    db.DB.Where("role_id = ?", roleID).Find(&rels)
-    menuIDs := []uint{}
-    for _, rel := range rels {
-        menuIDs = append(menuIDs, rel.MenuID)
-    }
-    c.JSON(http.StatusOK, menuIDs)
+	var rels []models.RoleMenuRelation
+	if err := db.DB.Where("role_id = ?", c.Param("id")).Order("menu_id").Find(&rels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色選單失敗: " + err.Error()})
+		return
+	}
+	menuIDs := []uint{}
+	for _, rel := range rels {
+		menuIDs = append(menuIDs, rel.MenuID)
+	}
+	c.JSON(http.StatusOK, menuIDs)
 }
 
 // 批次更新角色 menu 權限
+// 以差異方式更新（只新增/移除有變動的項目，保留既有的角色覆寫設定），
+// 授權子選單時自動包含其所有上層選單，整個過程在同一個交易內完成並記錄異動
 func UpdateRoleMenus(c *gin.Context) {
-    var input struct {
-        RoleID  uint   `json:"role_id"`
-        MenuIDs []uint `json:"menu_ids"`
-    }
-    if err := c.ShouldBindJSON(&input); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "格式錯誤"})
-        return
-    }
-    // 先清空此角色所有 menu 關聯
-    db.DB.Where("role_id = ?", input.RoleID).Delete(&models.RoleMenuRelation{})
-    // 再新增
-    for _, mid := range input.MenuIDs {
-        db.DB.Create(&models.RoleMenuRelation{RoleID: input.RoleID, MenuID: mid})
-    }
-    c.Status(http.StatusOK)
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	var req models.UpdateRoleMenusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "格式錯誤"})
+		return
+	}
+
+	parents := loadMenuParents()
+	desired := map[uint]bool{}
+	for _, mid := range req.MenuIDs {
+		if _, exists := parents[mid]; !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "選單不存在: " + strconv.Itoa(int(mid))})
+			return
+		}
+		for current := &mid; current != nil && !desired[*current]; current = parents[*current] {
+			desired[*current] = true
+		}
+	}
+
+	assignment := models.RoleMenuAssignment{RoleID: role.ID, Added: []uint{}, Removed: []uint{}}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var rels []models.RoleMenuRelation
+		if err := tx.Where("role_id = ?", role.ID).Find(&rels).Error; err != nil {
+			return err
+		}
+		current := make(map[uint]bool, len(rels))
+		for _, rel := range rels {
+			current[rel.MenuID] = true
+			if !desired[rel.MenuID] {
+				assignment.Removed = append(assignment.Removed, rel.MenuID)
+			}
+		}
+		for mid := range desired {
+			if !current[mid] {
+				assignment.Added = append(assignment.Added, mid)
+			}
+		}
+		sortUints(assignment.Added)
+		sortUints(assignment.Removed)
+
+		if len(assignment.Removed) > 0 {
+			if err := tx.Where("role_id = ? AND menu_id IN ?", role.ID, assignment.Removed).
+				Delete(&models.RoleMenuRelation{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(assignment.Added) > 0 {
+			added := make([]models.RoleMenuRelation, 0, len(assignment.Added))
+			for _, mid := range assignment.Added {
+				added = append(added, models.RoleMenuRelation{RoleID: role.ID, MenuID: mid, IsActive: true})
+			}
+			if err := tx.Create(&added).Error; err != nil {
+				return err
+			}
+		}
+		if len(assignment.Added) > 0 || len(assignment.Removed) > 0 {
+			return recordRoleMenuChange(tx, c, role.ID, assignment.Added, assignment.Removed)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新角色選單失敗: " + err.Error()})
+		return
+	}
+
+	assignment.MenuIDs = make([]uint, 0, len(desired))
+	for mid := range desired {
+		assignment.MenuIDs = append(assignment.MenuIDs, mid)
+	}
+	sortUints(assignment.MenuIDs)
+	c.JSON(http.StatusOK, assignment)
 }
 
-// 單一刪除（可選）
+// 單一刪除：移除角色的某個選單授權及其下層選單授權
 func DeleteRoleMenu(c *gin.Context) {
-    var input struct {
-        RoleID uint `json:"role_id"`
-        MenuID uint `json:"menu_id"`
-    }
-    if err := c.ShouldBindJSON(&input); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "格式錯誤"})
-        return
-    }
-    db.DB.Where("role_id = ? AND menu_id = ?", input.RoleID, input.MenuID).Delete(&models.RoleMenuRelation{})
-    c.Status(http.StatusOK)
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	menuID, err := strconv.Atoi(c.Param("menuId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "格式錯誤"})
+		return
+	}
+	// 連同所有下層選單一併移除，避免留下沒有上層的子選單授權
+	menuIDs := []uint{uint(menuID)}
+	parents := loadMenuParents()
+	for mid := range parents {
+		for current := parents[mid]; current != nil; current = parents[*current] {
+			if *current == uint(menuID) {
+				menuIDs = append(menuIDs, mid)
+				break
+			}
+		}
+	}
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var removed []uint
+		if err := tx.Model(&models.RoleMenuRelation{}).Where("role_id = ? AND menu_id IN ?", role.ID, menuIDs).
+			Pluck("menu_id", &removed).Error; err != nil || len(removed) == 0 {
+			return err
+		}
+		if err := tx.Where("role_id = ? AND menu_id IN ?", role.ID, removed).Delete(&models.RoleMenuRelation{}).Error; err != nil {
+			return err
+		}
+		sortUints(removed)
+		return recordRoleMenuChange(tx, c, role.ID, []uint{}, removed)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除角色選單失敗: " + err.Error()})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// 查詢角色選單授權的異動紀錄（新到舊）
+func GetRoleMenuHistory(c *gin.Context) {
+	var logs []models.RoleMenuChangeLog
+	if err := db.DB.Where("role_id = ?", c.Param("id")).Order("id DESC").Limit(100).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢異動紀錄失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, logs)
+}
+
+// recordRoleMenuChange 寫入角色選單異動紀錄
+func recordRoleMenuChange(tx *gorm.DB, c *gin.Context, roleID uint, added, removed []uint) error {
+	return tx.Create(&models.RoleMenuChangeLog{
+		RoleID:    roleID,
+		ChangedBy: c.GetString("username"),
+		Added:     added,
+		Removed:   removed,
+	}).Error
+}
+
+func sortUints(ids []uint) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
 }
@@ -1,18 +1,485 @@
 package handler
 
 import (
-    "net/http"
-    "fastener-api/db"
-    "fastener-api/models"
-    "github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fastener-api/db"
+	"fastener-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // 查詢所有角色
+// superadmin 可見全部（可用 ?company_id= 篩選該公司可用的角色）；
+// 其他使用者可見自己公司可用的角色，以及自己下層公司所擁有的角色
 func GetRoles(c *gin.Context) {
-    var roles []models.Role
-    if err := db.DB.Find(&roles).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色失敗"})
-        return
-    }
-    c.JSON(http.StatusOK, roles)
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+
+	query := db.DB.Order("id")
+	if role == models.RoleSuperAdmin {
+		if cid, err := strconv.Atoi(c.Query("company_id")); err == nil && cid > 0 {
+			query = query.Where("company_id IS NULL OR company_id IN ?", getAncestorCompanyIDs(uint(cid)))
+		}
+	} else {
+		query = query.Where("company_id IS NULL OR company_id IN ? OR company_id IN ?",
+			getAncestorCompanyIDs(companyID), getDescendantCompanyIDs(companyID))
+	}
+
+	var roles []models.Role
+	if err := query.Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢角色失敗"})
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// 查詢單一角色
+func GetRole(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var target models.Role
+	if err := db.DB.First(&target, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的角色"})
+		return
+	}
+	if role != models.RoleSuperAdmin && !roleVisibleTo(target, companyID) &&
+		(target.CompanyID == nil || !companyInScope(companyID, *target.CompanyID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的角色"})
+		return
+	}
+	c.JSON(http.StatusOK, target)
+}
+
+// 新增角色
+// company_admin 只能建立屬於自己或下層公司的角色，且權限不得超出自己擁有的權限
+func CreateRole(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	var role models.Role
+	if err := c.ShouldBindJSON(&role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	role.ID = 0
+	role.Name = strings.TrimSpace(role.Name)
+	if role.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "角色名稱為必填"})
+		return
+	}
+	if unknown := unknownPermissions(role.Permissions); len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的權限", "permissions": unknown})
+		return
+	}
+	if adminRole == models.RoleCompanyAdmin {
+		role.RequireTwoFactor = false
+		if role.CompanyID == nil {
+			role.CompanyID = &adminCompanyID
+		}
+		if !companyInScope(adminCompanyID, *role.CompanyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "無法在此公司建立角色"})
+			return
+		}
+		if missing := missingPermissions(role.Permissions, rolePermissions(adminRole)); len(missing) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "不可授予自己沒有的權限", "permissions": missing})
+			return
+		}
+	}
+	if role.CompanyID != nil && !companyExists(*role.CompanyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的公司"})
+		return
+	}
+	if roleNameTaken(role.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "角色名稱已存在"})
+		return
+	}
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	if err := db.DB.Create(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立角色失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// 更新角色
+func UpdateRole(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	var req models.Role
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	updates := map[string]interface{}{
+		"name":        strings.TrimSpace(req.Name),
+		"permissions": req.Permissions,
+	}
+	if adminRole == models.RoleSuperAdmin {
+		updates["require_two_factor"] = req.RequireTwoFactor
+	}
+	if !applyRoleUpdates(c, adminRole, &role, updates) {
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// 部分更新角色 (JSON Merge Patch)
+func PatchRole(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &role, "name", "permissions", "require_two_factor")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if name, ok := updates["name"].(string); ok {
+		updates["name"] = strings.TrimSpace(name)
+	}
+	if !applyRoleUpdates(c, adminRole, &role, updates) {
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// applyRoleUpdates 檢查名稱與權限規則後寫入角色，失敗時已回應錯誤並回傳 false
+func applyRoleUpdates(c *gin.Context, adminRole string, role *models.Role, updates map[string]interface{}) bool {
+	// 雙因素驗證政策僅 superadmin 可設定
+	if _, ok := updates["require_two_factor"]; ok && adminRole != models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "僅 superadmin 可設定雙因素驗證政策"})
+		return false
+	}
+	if name, ok := updates["name"].(string); ok {
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "角色名稱為必填"})
+			return false
+		}
+		if name != role.Name {
+			if models.IsSystemRole(role.Name) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "系統內建角色不可改名"})
+				return false
+			}
+			if roleNameTaken(name, role.ID) {
+				c.JSON(http.StatusConflict, gin.H{"error": "角色名稱已存在"})
+				return false
+			}
+		}
+	}
+	if perms, ok := updates["permissions"].([]string); ok {
+		if perms == nil {
+			perms = []string{}
+		}
+		if unknown := unknownPermissions(perms); len(unknown) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未知的權限", "permissions": unknown})
+			return false
+		}
+		if adminRole == models.RoleCompanyAdmin {
+			if missing := missingPermissions(perms, rolePermissions(adminRole)); len(missing) > 0 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "不可授予自己沒有的權限", "permissions": missing})
+				return false
+			}
+		}
+		// jsonb 欄位需經 serializer 轉換，改以 struct 欄位寫入
+		role.Permissions = perms
+		delete(updates, "permissions")
+		if err := db.DB.Model(role).Select("permissions").Updates(models.Role{Permissions: perms}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新角色失敗: " + err.Error()})
+			return false
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(role).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新角色失敗: " + err.Error()})
+			return false
+		}
+	}
+	db.DB.First(role, role.ID)
+	return true
+}
+
+// 刪除角色
+// 若仍有帳號使用此角色，需以 ?reassign_to=<角色ID> 指定轉移的角色，否則拒絕刪除
+func DeleteRole(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	role, ok := findManagedRole(c, adminRole, adminCompanyID)
+	if !ok {
+		return
+	}
+	if models.IsSystemRole(role.Name) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "系統內建角色不可刪除"})
+		return
+	}
+
+	// 公司成員資格的角色不隨 reassign_to 轉移，需先逐一調整
+	var membershipCount int64
+	db.DB.Model(&models.UserCompanyMembership{}).Where("role_id = ?", role.ID).Count(&membershipCount)
+	if membershipCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "此角色仍有公司成員資格使用中，請先變更這些成員資格的角色",
+			"membership_count": membershipCount,
+		})
+		return
+	}
+
+	var userCount int64
+	db.DB.Model(&models.User{}).Where("role_id = ?", role.ID).Count(&userCount)
+
+	var reassignTo uint
+	if userCount > 0 {
+		target, err := strconv.Atoi(c.Query("reassign_to"))
+		if err != nil || target <= 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "此角色仍有帳號使用中，請指定 reassign_to 轉移角色",
+				"user_count": userCount,
+			})
+			return
+		}
+		var targetRole models.Role
+		if uint(target) == role.ID || db.DB.First(&targetRole, target).Error != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的轉移角色"})
+			return
+		}
+		// 轉移角色必須對所有受影響帳號的公司都可見
+		if role.CompanyID != nil && !roleVisibleTo(targetRole, *role.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "轉移角色不適用於此公司"})
+			return
+		}
+		reassignTo = targetRole.ID
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if reassignTo != 0 {
+			if err := tx.Model(&models.User{}).Where("role_id = ?", role.ID).
+				Update("role_id", reassignTo).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.RoleMenuRelation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除角色失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "角色刪除成功", "reassigned_users": userCount})
+}
+
+// 複製角色（權限與選單設定一併複製）
+func CloneRole(c *gin.Context) {
+	adminRole, adminCompanyID, ok := requireRoleAdmin(c)
+	if !ok {
+		return
+	}
+	var source models.Role
+	if err := db.DB.First(&source, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的角色"})
+		return
+	}
+	var req models.CloneRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "角色名稱為必填"})
+		return
+	}
+	if adminRole == models.RoleCompanyAdmin {
+		if !roleVisibleTo(source, adminCompanyID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的角色"})
+			return
+		}
+		if req.CompanyID == nil {
+			req.CompanyID = &adminCompanyID
+		}
+		if !companyInScope(adminCompanyID, *req.CompanyID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "無法在此公司建立角色"})
+			return
+		}
+		if missing := missingPermissions(source.Permissions, rolePermissions(adminRole)); len(missing) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "不可授予自己沒有的權限", "permissions": missing})
+			return
+		}
+	}
+	if req.CompanyID != nil && !companyExists(*req.CompanyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的公司"})
+		return
+	}
+	if roleNameTaken(req.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "角色名稱已存在"})
+		return
+	}
+
+	clone := models.Role{
+		Name:             req.Name,
+		CompanyID:        req.CompanyID,
+		Permissions:      append([]string{}, source.Permissions...),
+		RequireTwoFactor: source.RequireTwoFactor,
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&clone).Error; err != nil {
+			return err
+		}
+		var rels []models.RoleMenuRelation
+		if err := tx.Where("role_id = ?", source.ID).Find(&rels).Error; err != nil {
+			return err
+		}
+		for i := range rels {
+			rels[i].RoleID = clone.ID
+		}
+		if len(rels) > 0 {
+			// is_active 有資料庫預設值 true，需指定全部欄位才不會把 false 的覆寫寫成 true
+			return tx.Select("*").Create(&rels).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "複製角色失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, clone)
+}
+
+// roleNameTaken 檢查角色名稱（不分大小寫）是否已被其他角色使用
+func roleNameTaken(name string, exceptID uint) bool {
+	var count int64
+	db.DB.Model(&models.Role{}).Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
+
+// requireRoleAdmin 僅允許 superadmin 與 company_admin 管理角色，否則回應 403
+func requireRoleAdmin(c *gin.Context) (string, uint, bool) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != models.RoleSuperAdmin && role != models.RoleCompanyAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return "", 0, false
+	}
+	return role, companyID, true
+}
+
+// findManagedRole 取得路徑中的角色，並確認呼叫者可管理它
+// company_admin 只能管理屬於自己或下層公司的角色，全域角色僅 superadmin 可異動
+func findManagedRole(c *gin.Context, adminRole string, adminCompanyID uint) (models.Role, bool) {
+	var role models.Role
+	if err := db.DB.First(&role, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的角色"})
+		return role, false
+	}
+	if adminRole != models.RoleSuperAdmin &&
+		(role.CompanyID == nil || !companyInScope(adminCompanyID, *role.CompanyID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此角色"})
+		return role, false
+	}
+	return role, true
+}
+
+// companyExists 公司是否存在
+func companyExists(id uint) bool {
+	var count int64
+	db.DB.Model(&models.Company{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
+
+// roleVisibleTo 判斷角色是否可被指定公司使用（全域角色，或由該公司/上層公司擁有）
+func roleVisibleTo(role models.Role, companyID uint) bool {
+	if role.CompanyID == nil {
+		return true
+	}
+	for _, id := range getAncestorCompanyIDs(companyID) {
+		if id == *role.CompanyID {
+			return true
+		}
+	}
+	return false
+}
+
+// findAssignableRole 依名稱找出可指派給 targetCompanyID 帳號的角色
+// 角色必須對目標公司可見；company_admin 不可指派 superadmin
+func findAssignableRole(adminRole, roleName string, targetCompanyID uint) (models.Role, bool) {
+	var role models.Role
+	if err := db.DB.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return role, false
+	}
+	if adminRole != models.RoleSuperAdmin && role.Name == models.RoleSuperAdmin {
+		return role, false
+	}
+	return role, roleVisibleTo(role, targetCompanyID)
+}
+
+// rolePermissions 取得角色名稱對應的權限清單
+func rolePermissions(roleName string) []string {
+	var role models.Role
+	if err := db.DB.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return nil
+	}
+	return role.Permissions
+}
+
+// missingPermissions 回傳 requested 中不在 granted 內的權限
+func missingPermissions(requested, granted []string) []string {
+	has := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		has[p] = true
+	}
+	var missing []string
+	for _, p := range requested {
+		if !has[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// unknownPermissions 回傳不在權限清單中的權限代碼
+func unknownPermissions(perms []string) []string {
+	known := make([]string, 0, len(models.PermissionCatalog))
+	for _, p := range models.PermissionCatalog {
+		known = append(known, p.Code)
+	}
+	return missingPermissions(perms, known)
+}
+
+// currentPermissions 取得登入者角色的有效權限（優先使用權限中介軟體已載入的結果）
+func currentPermissions(c *gin.Context) []string {
+	if perms, ok := c.Get("permissions"); ok {
+		if list, ok := perms.([]string); ok {
+			return list
+		}
+	}
+	return rolePermissions(c.GetString("role"))
+}
+
+// 查詢所有可授予的權限
+func GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.PermissionCatalog)
 }
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢業務 / 代理商列表
+func GetSalesAgents(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("name"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+	var agents []models.SalesAgent
+	if err := query.Find(&agents).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢業務失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agents)
+}
+
+// 新增業務 / 代理商
+func CreateSalesAgent(c *gin.Context) {
+	var agent models.SalesAgent
+	if err := c.ShouldBindJSON(&agent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	agent.ID = 0
+	agent.IsActive = true
+	if !validateSalesAgent(c, &agent) {
+		return
+	}
+	if err := db.DB.Create(&agent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立業務失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, agent)
+}
+
+// 部分更新業務 / 代理商 (JSON Merge Patch)，停用請設 is_active = false
+func PatchSalesAgent(c *gin.Context) {
+	agent, ok := findSalesAgent(c)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &agent, "name", "user_id", "email", "is_active", "company_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if name, ok := updates["name"].(string); ok && strings.TrimSpace(name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "業務名稱為必填"})
+		return
+	}
+	if companyID, ok := updates["company_id"].(uint); ok && !canAccessCompany(c, companyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	if userID, ok := updates["user_id"].(*uint); ok && userID != nil {
+		var user models.User
+		if err := db.DB.First(&user, *userID).Error; err != nil || !canAccessCompany(c, user.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的帳號"})
+			return
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.DB.Model(&agent).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新業務失敗: " + err.Error()})
+			return
+		}
+	}
+	db.DB.First(&agent, agent.ID)
+	c.JSON(http.StatusOK, agent)
+}
+
+// 刪除業務 / 代理商；已有佣金或仍被交易條件指派時請改為停用
+func DeleteSalesAgent(c *gin.Context) {
+	agent, ok := findSalesAgent(c)
+	if !ok {
+		return
+	}
+	var used int64
+	db.DB.Model(&models.Commission{}).Where("agent_id = ?", agent.ID).Count(&used)
+	if used == 0 {
+		db.DB.Model(&models.CustomerTransactionTerm{}).Where("agent_id = ?", agent.ID).Count(&used)
+	}
+	if used > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "此業務已有佣金紀錄或仍被交易條件指派，請改為停用"})
+		return
+	}
+	if err := db.DB.Delete(&agent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除業務失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "業務刪除成功"})
+}
+
+func findSalesAgent(c *gin.Context) (models.SalesAgent, bool) {
+	var agent models.SalesAgent
+	if err := db.DB.First(&agent, c.Param("id")).Error; err != nil || !canAccessCompany(c, agent.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的業務"})
+		return agent, false
+	}
+	return agent, true
+}
+
+func validateSalesAgent(c *gin.Context, agent *models.SalesAgent) bool {
+	agent.Name = strings.TrimSpace(agent.Name)
+	if agent.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "業務名稱為必填"})
+		return false
+	}
+	if agent.CompanyID == 0 {
+		_, agent.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, agent.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return false
+	}
+	if agent.UserID != nil {
+		var user models.User
+		if err := db.DB.First(&user, *agent.UserID).Error; err != nil || !canAccessCompany(c, user.CompanyID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的帳號"})
+			return false
+		}
+	}
+	return true
+}
+
+// agentUsable 業務是否存在且啟用中
+func agentUsable(agentID uint) bool {
+	var count int64
+	db.DB.Model(&models.SalesAgent{}).Where("id = ? AND is_active = ?", agentID, true).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// scopedCompanyIDs 目前使用者可存取的公司（自己及下層公司）；superadmin 回傳 nil 代表不限
+func scopedCompanyIDs(c *gin.Context) ([]uint, bool) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok {
+		return nil, false
+	}
+	if role == models.RoleSuperAdmin {
+		return nil, true
+	}
+	return getDescendantCompanyIDs(companyID), true
+}
+
+// scopeByCompany 將查詢限制在目前使用者可存取的公司
+func scopeByCompany(c *gin.Context, query *gorm.DB, column string) (*gorm.DB, bool) {
+	ids, ok := scopedCompanyIDs(c)
+	if !ok {
+		return nil, false
+	}
+	if ids != nil {
+		query = query.Where(column+" IN ?", ids)
+	}
+	return query, true
+}
+
+// canAccessCompany 目前使用者是否可存取指定公司的資料
+func canAccessCompany(c *gin.Context, targetID uint) bool {
+	ids, ok := scopedCompanyIDs(c)
+	if !ok {
+		return false
+	}
+	if ids == nil {
+		return true
+	}
+	for _, id := range ids {
+		if id == targetID {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTradeTerms 帶入客戶交易條件：指定 termID 時使用該筆，否則使用客戶在該公司的主要交易條件，
+// 再以請求中有提供的欄位覆寫
+func resolveTradeTerms(customerID, companyID uint, termID *uint, override models.TradeTermsRequest) (models.TradeTerms, *uint, error) {
+	var terms models.TradeTerms
+	var term models.CustomerTransactionTerm
+	query := db.DB.Where("customer_id = ? AND company_id = ?", customerID, companyID)
+	if termID != nil {
+		query = query.Where("id = ?", *termID)
+	} else {
+		query = query.Where("is_primary = ?", true)
+	}
+	err := query.First(&term).Error
+	switch {
+	case err == nil:
+		terms = models.TradeTerms{
+			Incoterm:           term.Incoterm,
+			CurrencyCode:       term.CurrencyCode,
+			CommissionRate:     term.CommissionRate,
+			AgentID:            term.AgentID,
+			ExportPort:         term.ExportPort,
+			DestinationCountry: term.DestinationCountry,
+			SoldToSiteID:       term.SoldToSiteID,
+			ShipToSiteID:       term.ShipToSiteID,
+			BillToSiteID:       term.BillToSiteID,
+		}
+		termID = &term.ID
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return terms, nil, err
+	case termID != nil:
+		return terms, nil, errors.New("找不到該客戶在此公司的指定交易條件")
+	}
+
+	if override.Incoterm != nil {
+		terms.Incoterm = *override.Incoterm
+	}
+	if override.CurrencyCode != nil {
+		terms.CurrencyCode = *override.CurrencyCode
+	}
+	if override.CommissionRate != nil {
+		terms.CommissionRate = *override.CommissionRate
+	}
+	if override.AgentID != nil {
+		if !agentUsable(*override.AgentID) {
+			return terms, nil, errors.New("指定的業務不存在或已停用")
+		}
+		terms.AgentID = override.AgentID
+	}
+	if override.ExportPort != nil {
+		terms.ExportPort = *override.ExportPort
+	}
+	if override.DestinationCountry != nil {
+		terms.DestinationCountry = *override.DestinationCountry
+	}
+	if override.SoldToSiteID != nil {
+		terms.SoldToSiteID = override.SoldToSiteID
+	}
+	if override.ShipToSiteID != nil {
+		terms.ShipToSiteID = override.ShipToSiteID
+	}
+	if override.BillToSiteID != nil {
+		terms.BillToSiteID = override.BillToSiteID
+	}
+	if err := checkTermSites(customerID, override.SoldToSiteID, override.ShipToSiteID, override.BillToSiteID); err != nil {
+		return terms, nil, err
+	}
+	terms.CurrencyCode = strings.ToUpper(strings.TrimSpace(terms.CurrencyCode))
+	if terms.CurrencyCode == "" {
+		return terms, nil, errors.New("缺少幣別，請設定客戶主要交易條件或指定 currency_code")
+	}
+	if terms.CommissionRate < 0 {
+		return terms, nil, errors.New("佣金比例不可為負數")
+	}
+	return terms, termID, nil
+}
+
+// validateSalesLines 檢查明細數量、單價與品名
+func validateSalesLines(lines []models.SalesLineRequest) error {
+	if len(lines) == 0 {
+		return errors.New("至少需要一筆明細")
+	}
+	for i, l := range lines {
+		if l.Quantity <= 0 {
+			return fmt.Errorf("第 %d 筆明細數量必須大於 0", i+1)
+		}
+		if l.UnitPrice != nil && *l.UnitPrice < 0 {
+			return fmt.Errorf("第 %d 筆明細單價不可為負數", i+1)
+		}
+		if strings.TrimSpace(l.Description) == "" && l.ProductSpecificationID == nil && l.ProductCategoryID == nil {
+			return fmt.Errorf("第 %d 筆明細需指定產品或品名", i+1)
+		}
+	}
+	return nil
+}
+
+// roundAmount 金額四捨五入至小數第二位
+func roundAmount(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// requestError 交易中因請求內容不合法而中止，respondTxError 以指定狀態碼回應
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string { return e.msg }
+
+func newRequestError(status int, format string, args ...interface{}) error {
+	return &requestError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// respondTxError 回應交易錯誤：requestError 依其狀態碼，其餘為 500
+func respondTxError(c *gin.Context, err error, action string) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		c.JSON(reqErr.status, gin.H{"error": reqErr.msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": action + "失敗: " + err.Error()})
+}
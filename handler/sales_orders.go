@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢訂單列表，可依 customer_id、status、order_date 區間 (from / to, YYYY-MM-DD) 篩選
+func GetSalesOrders(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("id DESC"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("customer_id"); v != "" {
+		query = query.Where("customer_id = ?", v)
+	}
+	if v := c.Query("status"); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	if v := c.Query("from"); v != "" {
+		query = query.Where("order_date >= ?", v)
+	}
+	if v := c.Query("to"); v != "" {
+		query = query.Where("order_date < (?::date + 1)", v)
+	}
+	var orders []models.SalesOrder
+	if err := query.Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢訂單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// 查詢單一訂單（含明細與出貨紀錄）
+func GetSalesOrder(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 新增訂單：直接建立，或指定 quotation_id 由已接受的報價單轉入
+func CreateSalesOrder(c *gin.Context) {
+	var req models.SalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	order := models.SalesOrder{Status: models.OrderDraft, CreatedBy: c.GetString("username")}
+	if req.QuotationID != nil && !applyQuotationToOrder(c, &req) {
+		return
+	}
+	if !applySalesOrderRequest(c, &order, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		no, err := nextSequenceNo(tx, order.CompanyID, models.SalesOrderSequence, time.Now())
+		if err != nil {
+			return err
+		}
+		order.OrderNo = no
+		return tx.Create(&order).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立訂單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, order)
+}
+
+// 修改訂單（僅限草稿），明細整批取代
+func UpdateSalesOrder(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	if order.Status != models.OrderDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "僅草稿狀態的訂單可修改"})
+		return
+	}
+	var req models.SalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	// 來源報價單建立後不可更換
+	req.QuotationID = order.QuotationID
+	if !applySalesOrderRequest(c, &order, req) {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sales_order_id = ?", order.ID).Delete(&models.SalesOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(&order).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新訂單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 刪除訂單（僅限草稿）
+func DeleteSalesOrder(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	if order.Status != models.OrderDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "僅草稿狀態的訂單可刪除，其他狀態請改用取消"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sales_order_id = ?", order.ID).Delete(&models.SalesOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&order).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除訂單失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "訂單刪除成功"})
+}
+
+// 確認訂單（draft → confirmed），確認後才可出貨與產生訂單確認書。
+// 客戶在接單公司設有信用額度且確認後曝險超出額度時，訂單轉為 credit_hold 等待核准
+func ConfirmSalesOrder(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	username := c.GetString("username")
+	var hold *models.CreditHold
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 鎖定訂單後重新確認狀態，避免重複確認；額度檢查在鎖定信用額度後於同一交易中進行
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderDraft {
+			return newRequestError(http.StatusConflict, "僅草稿狀態的訂單可確認")
+		}
+		var err error
+		if hold, err = checkOrderCredit(tx, order, username); err != nil {
+			return newRequestError(http.StatusConflict, "無法檢查信用額度: %v", err)
+		}
+		if hold != nil {
+			order.Status = models.OrderCreditHold
+			if err := tx.Create(hold).Error; err != nil {
+				return err
+			}
+		} else {
+			now := time.Now()
+			order.Status = models.OrderConfirmed
+			order.ConfirmedAt = &now
+			order.ConfirmedBy = username
+		}
+		return tx.Model(&order).Select("status", "confirmed_at", "confirmed_by").Updates(&order).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "更新訂單狀態")
+		return
+	}
+	if hold != nil {
+		c.JSON(http.StatusAccepted, gin.H{"order": order, "credit_hold": hold, "message": "超出信用額度，訂單已凍結待核准"})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 取消訂單（尚未出貨的 draft / credit_hold / confirmed 訂單）
+func CancelSalesOrder(c *gin.Context) {
+	transitionSalesOrder(c, models.OrderCancelled, func(order *models.SalesOrder) error {
+		switch order.Status {
+		case models.OrderDraft, models.OrderCreditHold, models.OrderConfirmed:
+		default:
+			return errors.New("已出貨或已結案的訂單不可取消")
+		}
+		return nil
+	})
+}
+
+// 結案訂單：全數出貨或部分出貨後不再出貨（短交）
+func CloseSalesOrder(c *gin.Context) {
+	transitionSalesOrder(c, models.OrderClosed, func(order *models.SalesOrder) error {
+		if order.Status != models.OrderShipped && order.Status != models.OrderPartiallyShipped {
+			return errors.New("僅已出貨或部分出貨的訂單可結案")
+		}
+		return nil
+	})
+}
+
+func transitionSalesOrder(c *gin.Context, status string, check func(order *models.SalesOrder) error) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	if err := check(&order); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	previous := order.Status
+	order.Status = status
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 凍結中的訂單被取消時一併結束凍結紀錄
+		if previous == models.OrderCreditHold {
+			if err := tx.Model(&models.CreditHold{}).
+				Where("sales_order_id = ? AND status = ?", order.ID, models.CreditHoldHeld).
+				Updates(map[string]interface{}{
+					"status":         models.CreditHoldReleased,
+					"released_by":    c.GetString("username"),
+					"released_at":    time.Now(),
+					"release_reason": "訂單已取消",
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&order).Select("status", "confirmed_at", "confirmed_by").Updates(&order).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新訂單狀態失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// 新增出貨（可分批），依出貨數量更新明細已出貨數量與訂單狀態
+func CreateSalesOrderShipment(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	var req models.ShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Lines) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式，至少需要一筆出貨明細"})
+		return
+	}
+
+	if req.ETD != nil && req.ETA != nil && req.ETA.Before(*req.ETD) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ETA 不可早於 ETD"})
+		return
+	}
+	shipment := models.SalesOrderShipment{
+		SalesOrderID:    order.ID,
+		ShippedAt:       time.Now(),
+		PortOfLoading:   firstNonEmpty(strings.TrimSpace(req.PortOfLoading), order.ExportPort),
+		PortOfDischarge: strings.TrimSpace(req.PortOfDischarge),
+		Vessel:          strings.TrimSpace(req.Vessel),
+		VoyageNo:        strings.TrimSpace(req.VoyageNo),
+		ETD:             req.ETD,
+		ETA:             req.ETA,
+		ShippingMark:    strings.TrimSpace(req.ShippingMark),
+		Remarks:         req.Remarks,
+		CreatedBy:       c.GetString("username"),
+	}
+	if req.ShippedAt != nil {
+		shipment.ShippedAt = *req.ShippedAt
+	}
+	if shipment.ShippingMark == "" && order.TransactionTermID != nil {
+		var term models.CustomerTransactionTerm
+		if db.DB.First(&term, *order.TransactionTermID).Error == nil {
+			shipment.ShippingMark = term.ShippingMark
+		}
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 鎖定訂單後重新讀取狀態與明細，避免同時出貨超量
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, order.ID).Error; err != nil {
+			return err
+		}
+		if order.Status != models.OrderConfirmed && order.Status != models.OrderPartiallyShipped {
+			return newRequestError(http.StatusConflict, "僅已確認或部分出貨的訂單可出貨")
+		}
+		var lines []models.SalesOrderLine
+		if err := tx.Where("sales_order_id = ?", order.ID).Find(&lines).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.SalesOrderLine, len(lines))
+		for i := range lines {
+			byID[lines[i].ID] = &lines[i]
+		}
+		for _, l := range req.Lines {
+			line, exists := byID[l.SalesOrderLineID]
+			if !exists {
+				return newRequestError(http.StatusBadRequest, "明細 %d 不屬於此訂單", l.SalesOrderLineID)
+			}
+			if l.Quantity <= 0 || l.Quantity > line.RemainingQuantity() {
+				return newRequestError(http.StatusBadRequest, "第 %d 筆明細出貨數量需大於 0 且不可超過未出貨數量 %g", line.LineNo, line.RemainingQuantity())
+			}
+			line.ShippedQuantity += l.Quantity
+			if err := tx.Model(line).Update("shipped_quantity", line.ShippedQuantity).Error; err != nil {
+				return err
+			}
+			shipment.Lines = append(shipment.Lines, models.SalesOrderShipmentLine{
+				SalesOrderLineID: l.SalesOrderLineID,
+				Quantity:         l.Quantity,
+			})
+		}
+
+		no, err := nextSequenceNo(tx, order.CompanyID, models.ShipmentSequence, time.Now())
+		if err != nil {
+			return err
+		}
+		shipment.CompanyID = order.CompanyID
+		shipment.ShipmentNo = no
+		if err := tx.Create(&shipment).Error; err != nil {
+			return err
+		}
+
+		order.Status = models.OrderShipped
+		for _, line := range lines {
+			if line.RemainingQuantity() > 0 {
+				order.Status = models.OrderPartiallyShipped
+				break
+			}
+		}
+		return tx.Model(&order).Update("status", order.Status).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "建立出貨")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"shipment": shipment, "order_status": order.Status})
+}
+
+// 訂單確認書：已確認（含後續狀態）的訂單才可產生，語言依接單公司設定
+func GetOrderConfirmation(c *gin.Context) {
+	order, ok := findSalesOrder(c, db.DB)
+	if !ok {
+		return
+	}
+	if order.ConfirmedAt == nil || order.Status == models.OrderCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "訂單尚未確認，無法產生訂單確認書"})
+		return
+	}
+	doc := models.OrderConfirmation{Order: order, GeneratedAt: time.Now()}
+	doc.Order.Shipments = nil
+	if err := db.DB.First(&doc.Seller, order.CompanyID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢接單公司失敗"})
+		return
+	}
+	if err := db.DB.First(&doc.Customer, order.CustomerID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢客戶失敗"})
+		return
+	}
+	doc.Language = doc.Seller.Language
+	c.JSON(http.StatusOK, doc)
+}
+
+// findSalesOrder 依路徑 :id 載入訂單、明細與出貨紀錄，並檢查公司範圍
+func findSalesOrder(c *gin.Context, tx *gorm.DB) (models.SalesOrder, bool) {
+	var order models.SalesOrder
+	err := tx.Preload("Lines", func(q *gorm.DB) *gorm.DB { return q.Order("line_no") }).
+		Preload("Shipments.Lines").
+		First(&order, c.Param("id")).Error
+	if err != nil || !canAccessCompany(c, order.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的訂單"})
+		return order, false
+	}
+	return order, true
+}
+
+// applyQuotationToOrder 由已接受的報價單補齊訂單請求：公司、客戶、交易條件，
+// 以及未提供明細時的報價明細
+func applyQuotationToOrder(c *gin.Context, req *models.SalesOrderRequest) bool {
+	var quote models.Quotation
+	err := db.DB.Preload("Lines", func(q *gorm.DB) *gorm.DB { return q.Order("line_no") }).
+		First(&quote, *req.QuotationID).Error
+	if err != nil || !canAccessCompany(c, quote.CompanyID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的報價單"})
+		return false
+	}
+	if quote.Status != models.QuotationAccepted {
+		c.JSON(http.StatusConflict, gin.H{"error": "僅已接受的報價單可轉為訂單"})
+		return false
+	}
+	var count int64
+	db.DB.Model(&models.SalesOrder{}).Where("quotation_id = ?", quote.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "此報價單已轉為訂單"})
+		return false
+	}
+
+	req.CompanyID = quote.CompanyID
+	req.CustomerID = quote.CustomerID
+	req.TransactionTermID = quote.TransactionTermID
+	t := quote.TradeTerms
+	if req.Incoterm == nil {
+		req.Incoterm = &t.Incoterm
+	}
+	if req.CurrencyCode == nil {
+		req.CurrencyCode = &t.CurrencyCode
+	}
+	if req.CommissionRate == nil {
+		req.CommissionRate = &t.CommissionRate
+	}
+	if req.AgentID == nil {
+		req.AgentID = t.AgentID
+	}
+	if req.SoldToSiteID == nil {
+		req.SoldToSiteID = t.SoldToSiteID
+	}
+	if req.ShipToSiteID == nil {
+		req.ShipToSiteID = t.ShipToSiteID
+	}
+	if req.BillToSiteID == nil {
+		req.BillToSiteID = t.BillToSiteID
+	}
+	if req.ExportPort == nil {
+		req.ExportPort = &t.ExportPort
+	}
+	if req.DestinationCountry == nil {
+		req.DestinationCountry = &t.DestinationCountry
+	}
+	if len(req.Lines) == 0 {
+		for _, l := range quote.Lines {
+			unitPrice := l.UnitPrice
+			req.Lines = append(req.Lines, models.SalesLineRequest{
+				ProductCategoryID:      l.ProductCategoryID,
+				ProductSpecificationID: l.ProductSpecificationID,
+				Description:            l.Description,
+				Quantity:               l.Quantity,
+				Unit:                   l.Unit,
+				UnitPrice:              &unitPrice,
+				DeliveryDate:           l.DeliveryDate,
+			})
+		}
+	}
+	return true
+}
+
+// applySalesOrderRequest 檢查請求並寫入訂單表頭與明細（未存檔）
+func applySalesOrderRequest(c *gin.Context, order *models.SalesOrder, req models.SalesOrderRequest) bool {
+	if req.CompanyID == 0 {
+		_, req.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, req.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return false
+	}
+	var customer models.Customer
+	if err := db.DB.First(&customer, req.CustomerID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "找不到指定的客戶"})
+		return false
+	}
+	if err := validateSalesLines(req.Lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	terms, termID, err := resolveTradeTerms(req.CustomerID, req.CompanyID, req.TransactionTermID, req.TradeTermsRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	order.OrderDate = time.Now()
+	if req.OrderDate != nil {
+		order.OrderDate = *req.OrderDate
+	}
+	if err := priceSalesLines(req.CustomerID, req.CompanyID, termID, terms.CurrencyCode, order.OrderDate, req.Lines); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	order.CompanyID = req.CompanyID
+	order.CustomerID = req.CustomerID
+	order.TransactionTermID = termID
+	order.QuotationID = req.QuotationID
+	order.CustomerPONo = req.CustomerPONo
+	order.TradeTerms = terms
+	order.Remarks = req.Remarks
+	order.Lines = nil
+	order.TotalAmount = 0
+	for i, l := range req.Lines {
+		amount := roundAmount(l.Quantity * *l.UnitPrice)
+		order.Lines = append(order.Lines, models.SalesOrderLine{
+			LineNo:                 i + 1,
+			ProductCategoryID:      l.ProductCategoryID,
+			ProductSpecificationID: l.ProductSpecificationID,
+			Description:            l.Description,
+			Quantity:               l.Quantity,
+			Unit:                   l.Unit,
+			UnitPrice:              *l.UnitPrice,
+			Amount:                 amount,
+			DeliveryDate:           l.DeliveryDate,
+		})
+		order.TotalAmount += amount
+	}
+	order.TotalAmount = roundAmount(order.TotalAmount)
+	return true
+}
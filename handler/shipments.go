@@ -0,0 +1,613 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 單次最多建立的包裝數
+const maxPackagesPerRequest = 1000
+
+// 未設定嘜頭範本時使用的預設嘜頭
+const defaultShippingMark = `{customer}
+{port_of_discharge}
+PO NO.: {customer_po}
+{description}
+QTY: {quantity}
+N.W.: {net_weight} KGS  G.W.: {gross_weight} KGS
+C/NO.: {carton_no}/{carton_total}`
+
+// 查詢出貨列表（裝船追蹤），可依 sales_order_id、customer_id、port_of_loading、port_of_discharge
+// 及 ETD / ETA 區間 (etd_from, etd_to, eta_from, eta_to, YYYY-MM-DD) 篩選
+func GetShipments(c *gin.Context) {
+	query := db.DB.Model(&models.SalesOrderShipment{}).
+		Joins("JOIN sales_orders ON sales_orders.id = sales_order_shipments.sales_order_id").
+		Order("sales_order_shipments.id DESC")
+	query, ok := scopeByCompany(c, query, "sales_orders.company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	filters := map[string]string{
+		"sales_order_id":    "sales_order_shipments.sales_order_id = ?",
+		"customer_id":       "sales_orders.customer_id = ?",
+		"port_of_loading":   "sales_order_shipments.port_of_loading = ?",
+		"port_of_discharge": "sales_order_shipments.port_of_discharge = ?",
+		"etd_from":          "sales_order_shipments.etd >= ?",
+		"etd_to":            "sales_order_shipments.etd < (?::date + 1)",
+		"eta_from":          "sales_order_shipments.eta >= ?",
+		"eta_to":            "sales_order_shipments.eta < (?::date + 1)",
+	}
+	for param, cond := range filters {
+		if v := c.Query(param); v != "" {
+			query = query.Where(cond, v)
+		}
+	}
+	var shipments []models.SalesOrderShipment
+	if err := query.Select("sales_order_shipments.*").Preload("Containers").Find(&shipments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢出貨失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, shipments)
+}
+
+// 查詢單一出貨（含明細、貨櫃與包裝）
+func GetShipment(c *gin.Context) {
+	shipment, _, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, shipment)
+}
+
+// 修改裝船資訊與嘜頭 (JSON Merge Patch)
+func PatchShipment(c *gin.Context) {
+	shipment, _, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &shipment,
+		"port_of_loading", "port_of_discharge", "vessel", "voyage_no", "etd", "eta", "shipping_mark", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	etd, eta := shipment.ETD, shipment.ETA
+	if v, ok := updates["etd"]; ok {
+		etd = v.(*time.Time)
+	}
+	if v, ok := updates["eta"]; ok {
+		eta = v.(*time.Time)
+	}
+	if etd != nil && eta != nil && eta.Before(*etd) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ETA 不可早於 ETD"})
+		return
+	}
+	if err := db.DB.Model(&shipment).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新出貨失敗: " + err.Error()})
+		return
+	}
+	shipment, _, _ = findShipment(c)
+	c.JSON(http.StatusOK, shipment)
+}
+
+// 新增出貨貨櫃；櫃號可於訂艙後再補
+func CreateShipmentContainer(c *gin.Context) {
+	shipment, _, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	var container models.ShipmentContainer
+	if err := c.ShouldBindJSON(&container); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	container.ID = 0
+	container.ShipmentID = shipment.ID
+	container.ContainerNo = strings.ToUpper(strings.TrimSpace(container.ContainerNo))
+	container.SealNo = strings.TrimSpace(container.SealNo)
+	container.ContainerType = strings.ToUpper(strings.TrimSpace(container.ContainerType))
+	if container.ContainerNo == "" && container.ContainerType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "櫃號與櫃型至少需填一項"})
+		return
+	}
+	if err := db.DB.Create(&container).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "新增貨櫃失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, container)
+}
+
+// 修改貨櫃櫃號、封條號碼與櫃型 (JSON Merge Patch)
+func PatchShipmentContainer(c *gin.Context) {
+	container, ok := findShipmentContainer(c)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &container, "container_no", "seal_no", "container_type", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	for _, key := range []string{"container_no", "container_type"} {
+		if v, ok := updates[key].(string); ok {
+			updates[key] = strings.ToUpper(strings.TrimSpace(v))
+		}
+	}
+	if err := db.DB.Model(&container).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新貨櫃失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&container, container.ID)
+	c.JSON(http.StatusOK, container)
+}
+
+// 刪除貨櫃，已裝櫃的包裝改為未指定貨櫃
+func DeleteShipmentContainer(c *gin.Context) {
+	container, ok := findShipmentContainer(c)
+	if !ok {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ShipmentPackage{}).Where("container_id = ?", container.ID).
+			Update("container_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&container).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除貨櫃失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "貨櫃刪除成功"})
+}
+
+// 新增包裝：紙箱需指定每箱內容，可用 count 一次建立多個相同的箱子；
+// 各訂單明細的裝箱數量合計不可超過本次出貨數量
+func CreateShipmentPackages(c *gin.Context) {
+	shipment, _, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	var req models.PackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if req.Type == "" {
+		req.Type = models.PackageCarton
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+	if req.Count < 0 || req.Count > maxPackagesPerRequest {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count 需介於 1 到 " + strconv.Itoa(maxPackagesPerRequest)})
+		return
+	}
+	for _, v := range []float64{req.LengthCM, req.WidthCM, req.HeightCM, req.NetWeightKG, req.GrossWeightKG, req.TareWeightKG} {
+		if v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "尺寸與重量不可為負數"})
+			return
+		}
+	}
+	switch req.Type {
+	case models.PackagePallet:
+		if len(req.Contents) > 0 || req.PalletID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "棧板不可直接指定內容或放在其他棧板上"})
+			return
+		}
+	case models.PackageCarton:
+		if len(req.Contents) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "紙箱至少需要一筆內容"})
+			return
+		}
+		if req.GrossWeightKG < req.NetWeightKG {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "毛重不可小於淨重"})
+			return
+		}
+		if req.PalletID != nil && req.ContainerID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "上棧板的紙箱隨棧板裝櫃，不可另指定貨櫃"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "包裝類型需為 carton 或 pallet"})
+		return
+	}
+
+	shipped := make(map[uint]float64, len(shipment.Lines))
+	for _, l := range shipment.Lines {
+		shipped[l.SalesOrderLineID] += l.Quantity
+	}
+	var packages []models.ShipmentPackage
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// 鎖定出貨，避免同時裝箱超量或箱號重複
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.SalesOrderShipment{}, shipment.ID).Error; err != nil {
+			return err
+		}
+		if err := checkPackageRefs(tx, shipment.ID, req.PalletID, req.ContainerID); err != nil {
+			return err
+		}
+		packed, err := packedQuantities(tx, shipment.ID)
+		if err != nil {
+			return err
+		}
+		for _, content := range req.Contents {
+			if _, exists := shipped[content.SalesOrderLineID]; !exists {
+				return newRequestError(http.StatusBadRequest, "訂單明細 %d 不在此次出貨中", content.SalesOrderLineID)
+			}
+			if content.Quantity <= 0 {
+				return newRequestError(http.StatusBadRequest, "裝箱數量需大於 0")
+			}
+			packed[content.SalesOrderLineID] += content.Quantity * float64(req.Count)
+			if packed[content.SalesOrderLineID] > shipped[content.SalesOrderLineID] {
+				return newRequestError(http.StatusBadRequest, "訂單明細 %d 裝箱數量超過出貨數量 %g",
+					content.SalesOrderLineID, shipped[content.SalesOrderLineID])
+			}
+		}
+		var lastNo int
+		tx.Model(&models.ShipmentPackage{}).Where("shipment_id = ? AND type = ?", shipment.ID, req.Type).
+			Select("COALESCE(MAX(package_no), 0)").Scan(&lastNo)
+		for i := 0; i < req.Count; i++ {
+			pkg := models.ShipmentPackage{
+				ShipmentID:    shipment.ID,
+				Type:          req.Type,
+				PackageNo:     lastNo + i + 1,
+				PalletID:      req.PalletID,
+				ContainerID:   req.ContainerID,
+				LengthCM:      req.LengthCM,
+				WidthCM:       req.WidthCM,
+				HeightCM:      req.HeightCM,
+				NetWeightKG:   req.NetWeightKG,
+				GrossWeightKG: req.GrossWeightKG,
+				TareWeightKG:  req.TareWeightKG,
+				Remarks:       req.Remarks,
+			}
+			for _, content := range req.Contents {
+				pkg.Contents = append(pkg.Contents, models.ShipmentPackageLine{
+					SalesOrderLineID: content.SalesOrderLineID,
+					Quantity:         content.Quantity,
+				})
+			}
+			packages = append(packages, pkg)
+		}
+		return tx.Create(&packages).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "新增包裝")
+		return
+	}
+	c.JSON(http.StatusCreated, packages)
+}
+
+// 修改包裝尺寸、重量、所在棧板與貨櫃 (JSON Merge Patch)；內容請刪除後重建
+func PatchShipmentPackage(c *gin.Context) {
+	pkg, ok := findShipmentPackage(c)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &pkg, "pallet_id", "container_id", "length_cm", "width_cm", "height_cm",
+		"net_weight_kg", "gross_weight_kg", "tare_weight_kg", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	for _, key := range []string{"length_cm", "width_cm", "height_cm", "net_weight_kg", "gross_weight_kg", "tare_weight_kg"} {
+		if v, ok := updates[key].(float64); ok && v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "尺寸與重量不可為負數"})
+			return
+		}
+	}
+	palletID, containerID := pkg.PalletID, pkg.ContainerID
+	if v, ok := updates["pallet_id"]; ok {
+		palletID = v.(*uint)
+	}
+	if v, ok := updates["container_id"]; ok {
+		containerID = v.(*uint)
+	}
+	if palletID != nil && pkg.Type != models.PackageCarton {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有紙箱可以放在棧板上"})
+		return
+	}
+	if palletID != nil && containerID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "上棧板的紙箱隨棧板裝櫃，不可另指定貨櫃"})
+		return
+	}
+	if err := checkPackageRefs(db.DB, pkg.ShipmentID, palletID, containerID); err != nil {
+		respondTxError(c, err, "更新包裝")
+		return
+	}
+	if err := db.DB.Model(&pkg).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新包裝失敗: " + err.Error()})
+		return
+	}
+	db.DB.Preload("Contents").First(&pkg, pkg.ID)
+	c.JSON(http.StatusOK, pkg)
+}
+
+// 刪除包裝；刪除棧板時其上的紙箱改為未上棧板，其後的箱號依序遞補
+func DeleteShipmentPackage(c *gin.Context) {
+	pkg, ok := findShipmentPackage(c)
+	if !ok {
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.SalesOrderShipment{}, pkg.ShipmentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ShipmentPackage{}).Where("pallet_id = ?", pkg.ID).Update("pallet_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("package_id = ?", pkg.ID).Delete(&models.ShipmentPackageLine{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&pkg).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShipmentPackage{}).
+			Where("shipment_id = ? AND type = ? AND package_no > ?", pkg.ShipmentID, pkg.Type, pkg.PackageNo).
+			Update("package_no", gorm.Expr("package_no - 1")).Error
+	})
+	if err != nil {
+		respondTxError(c, err, "刪除包裝")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "包裝刪除成功"})
+}
+
+// 裝箱單：箱數、棧板數、淨重、毛重、材積與各明細的裝箱進度
+func GetPackingList(c *gin.Context) {
+	shipment, order, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, buildPackingList(shipment, order))
+}
+
+// 各紙箱嘜頭：依出貨嘜頭範本代入箱號、內容與重量
+func GetShippingMarks(c *gin.Context) {
+	shipment, order, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	marks, err := shippingMarks(shipment, order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生嘜頭失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, marks)
+}
+
+// findShipment 依路徑 :id 載入出貨（含明細、貨櫃、包裝）與所屬訂單，並檢查公司範圍
+func findShipment(c *gin.Context) (models.SalesOrderShipment, models.SalesOrder, bool) {
+	var shipment models.SalesOrderShipment
+	var order models.SalesOrder
+	err := db.DB.Preload("Lines").
+		Preload("Containers", func(q *gorm.DB) *gorm.DB { return q.Order("id") }).
+		Preload("Packages", func(q *gorm.DB) *gorm.DB { return q.Order("type DESC, package_no") }).
+		Preload("Packages.Contents").
+		First(&shipment, c.Param("id")).Error
+	if err == nil {
+		err = db.DB.Preload("Lines", func(q *gorm.DB) *gorm.DB { return q.Order("line_no") }).
+			First(&order, shipment.SalesOrderID).Error
+	}
+	if err != nil || !canAccessCompany(c, order.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的出貨"})
+		return shipment, order, false
+	}
+	return shipment, order, true
+}
+
+// canAccessShipment 出貨的公司範圍依所屬訂單的接單公司
+func canAccessShipment(c *gin.Context, shipmentID uint) bool {
+	var companyID uint
+	db.DB.Table("sales_order_shipments").
+		Joins("JOIN sales_orders ON sales_orders.id = sales_order_shipments.sales_order_id").
+		Where("sales_order_shipments.id = ?", shipmentID).
+		Select("sales_orders.company_id").Scan(&companyID)
+	return companyID != 0 && canAccessCompany(c, companyID)
+}
+
+func findShipmentContainer(c *gin.Context) (models.ShipmentContainer, bool) {
+	var container models.ShipmentContainer
+	if err := db.DB.First(&container, c.Param("containerId")).Error; err != nil || !canAccessShipment(c, container.ShipmentID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的貨櫃"})
+		return container, false
+	}
+	return container, true
+}
+
+func findShipmentPackage(c *gin.Context) (models.ShipmentPackage, bool) {
+	var pkg models.ShipmentPackage
+	if err := db.DB.Preload("Contents").First(&pkg, c.Param("packageId")).Error; err != nil || !canAccessShipment(c, pkg.ShipmentID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的包裝"})
+		return pkg, false
+	}
+	return pkg, true
+}
+
+// checkPackageRefs 棧板與貨櫃需屬於同一出貨
+func checkPackageRefs(tx *gorm.DB, shipmentID uint, palletID, containerID *uint) error {
+	if palletID != nil {
+		var pallet models.ShipmentPackage
+		if err := tx.First(&pallet, *palletID).Error; err != nil || pallet.ShipmentID != shipmentID || pallet.Type != models.PackagePallet {
+			return newRequestError(http.StatusBadRequest, "指定的棧板不屬於此出貨")
+		}
+	}
+	if containerID != nil {
+		var container models.ShipmentContainer
+		if err := tx.First(&container, *containerID).Error; err != nil || container.ShipmentID != shipmentID {
+			return newRequestError(http.StatusBadRequest, "指定的貨櫃不屬於此出貨")
+		}
+	}
+	return nil
+}
+
+// packedQuantities 出貨中各訂單明細已裝箱的數量
+func packedQuantities(tx *gorm.DB, shipmentID uint) (map[uint]float64, error) {
+	var rows []struct {
+		SalesOrderLineID uint
+		Quantity         float64
+	}
+	err := tx.Raw(`
+		SELECT l.sales_order_line_id, SUM(l.quantity) AS quantity
+		FROM shipment_package_lines l
+		JOIN shipment_packages p ON p.id = l.package_id
+		WHERE p.shipment_id = ?
+		GROUP BY l.sales_order_line_id
+	`, shipmentID).Scan(&rows).Error
+	packed := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		packed[r.SalesOrderLineID] = r.Quantity
+	}
+	return packed, err
+}
+
+// buildPackingList 彙總出貨包裝；棧板的淨重與毛重由其上的紙箱計算，
+// 材積以棧板計，未上棧板的紙箱另計
+func buildPackingList(shipment models.SalesOrderShipment, order models.SalesOrder) models.PackingList {
+	list := models.PackingList{Shipment: shipment, OrderNo: order.OrderNo, CustomerPONo: order.CustomerPONo}
+	pallets := make(map[uint]*models.ShipmentPackage)
+	for i := range shipment.Packages {
+		if p := &shipment.Packages[i]; p.Type == models.PackagePallet {
+			p.NetWeightKG, p.GrossWeightKG = 0, p.TareWeightKG
+			pallets[p.ID] = p
+		}
+	}
+	packed := make(map[uint]float64)
+	for _, p := range shipment.Packages {
+		if p.Type != models.PackageCarton {
+			continue
+		}
+		list.Cartons++
+		list.NetWeightKG += p.NetWeightKG
+		list.GrossWeightKG += p.GrossWeightKG
+		if pallet, onPallet := pallets[derefUint(p.PalletID)]; onPallet {
+			pallet.NetWeightKG += p.NetWeightKG
+			pallet.GrossWeightKG += p.GrossWeightKG
+		} else {
+			list.CBM += p.CBM()
+		}
+		for _, content := range p.Contents {
+			packed[content.SalesOrderLineID] += content.Quantity
+		}
+	}
+	for _, p := range shipment.Packages {
+		if p.Type == models.PackagePallet {
+			list.Pallets++
+			list.GrossWeightKG += p.TareWeightKG
+			list.CBM += p.CBM()
+		}
+	}
+	list.NetWeightKG = roundAmount(list.NetWeightKG)
+	list.GrossWeightKG = roundAmount(list.GrossWeightKG)
+	list.CBM = roundCBM(list.CBM)
+
+	orderLines := make(map[uint]models.SalesOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		orderLines[l.ID] = l
+	}
+	list.Complete = true
+	for _, sl := range shipment.Lines {
+		ol := orderLines[sl.SalesOrderLineID]
+		line := models.PackingListLine{
+			SalesOrderLineID: sl.SalesOrderLineID,
+			LineNo:           ol.LineNo,
+			Description:      ol.Description,
+			Unit:             ol.Unit,
+			ShippedQuantity:  sl.Quantity,
+			PackedQuantity:   packed[sl.SalesOrderLineID],
+		}
+		if line.PackedQuantity < line.ShippedQuantity {
+			list.Complete = false
+		}
+		list.Lines = append(list.Lines, line)
+	}
+	sort.Slice(list.Lines, func(i, j int) bool { return list.Lines[i].LineNo < list.Lines[j].LineNo })
+	return list
+}
+
+// shippingMarks 依嘜頭範本產生各紙箱嘜頭。可用欄位：
+// {customer} {order_no} {customer_po} {destination} {port_of_loading} {port_of_discharge}
+// {carton_no} {carton_total} {description} {quantity} {net_weight} {gross_weight} {measurement}
+func shippingMarks(shipment models.SalesOrderShipment, order models.SalesOrder) ([]models.ShippingMark, error) {
+	var customer models.Customer
+	if err := db.DB.First(&customer, order.CustomerID).Error; err != nil {
+		return nil, err
+	}
+	template := firstNonEmpty(shipment.ShippingMark, defaultShippingMark)
+	orderLines := make(map[uint]models.SalesOrderLine, len(order.Lines))
+	for _, l := range order.Lines {
+		orderLines[l.ID] = l
+	}
+	total := 0
+	for _, p := range shipment.Packages {
+		if p.Type == models.PackageCarton {
+			total++
+		}
+	}
+	marks := []models.ShippingMark{}
+	for _, p := range shipment.Packages {
+		if p.Type != models.PackageCarton {
+			continue
+		}
+		var descriptions, quantities []string
+		for _, content := range p.Contents {
+			ol := orderLines[content.SalesOrderLineID]
+			descriptions = append(descriptions, ol.Description)
+			quantities = append(quantities, strings.TrimSpace(formatQuantity(content.Quantity)+" "+ol.Unit))
+		}
+		text := strings.NewReplacer(
+			"{customer}", customer.GroupCustomerName,
+			"{order_no}", order.OrderNo,
+			"{customer_po}", order.CustomerPONo,
+			"{destination}", order.DestinationCountry,
+			"{port_of_loading}", shipment.PortOfLoading,
+			"{port_of_discharge}", firstNonEmpty(shipment.PortOfDischarge, order.DestinationCountry),
+			"{carton_no}", strconv.Itoa(p.PackageNo),
+			"{carton_total}", strconv.Itoa(total),
+			"{description}", strings.Join(descriptions, " / "),
+			"{quantity}", strings.Join(quantities, " / "),
+			"{net_weight}", formatQuantity(p.NetWeightKG),
+			"{gross_weight}", formatQuantity(p.GrossWeightKG),
+			"{measurement}", formatDimensions(p),
+		).Replace(template)
+		mark := models.ShippingMark{PackageID: p.ID, PackageNo: p.PackageNo}
+		for _, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				mark.Lines = append(mark.Lines, line)
+			}
+		}
+		marks = append(marks, mark)
+	}
+	return marks, nil
+}
+
+// formatDimensions 長 x 寬 x 高 (cm)
+func formatDimensions(p models.ShipmentPackage) string {
+	if p.LengthCM == 0 && p.WidthCM == 0 && p.HeightCM == 0 {
+		return ""
+	}
+	return formatQuantity(p.LengthCM) + "x" + formatQuantity(p.WidthCM) + "x" + formatQuantity(p.HeightCM) + " CM"
+}
+
+// roundCBM 材積取至小數 3 位
+func roundCBM(v float64) float64 {
+	return float64(int64(v*1000+0.5)) / 1000
+}
+
+func derefUint(p *uint) uint {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
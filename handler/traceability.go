@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 成品批號追溯：往前到製令、原料爐號與製程批次，往後到出貨與客戶
+func GetProductionLotTrace(c *gin.Context) {
+	lot, ok := findProductionLot(c)
+	if !ok {
+		return
+	}
+	trace, err := lotTrace(c, lot)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢追溯失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, trace)
+}
+
+// 原料爐號順向追溯：使用此爐號的成品批號及其出貨
+func GetMaterialHeatTrace(c *gin.Context) {
+	var heat models.MaterialHeat
+	if err := db.DB.First(&heat, c.Param("id")).Error; err != nil || !canAccessCompany(c, heat.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的爐號"})
+		return
+	}
+	lotIDs := db.DB.Model(&models.ProductionLotHeat{}).Select("lot_id").Where("material_heat_id = ?", heat.ID)
+	trace, err := forwardTrace(c, lotIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢追溯失敗: " + err.Error()})
+		return
+	}
+	trace.MaterialHeat = &heat
+	c.JSON(http.StatusOK, trace)
+}
+
+// 製程批次順向追溯：經過此熱處理 / 電鍍批次的成品批號及其出貨
+func GetProcessBatchTrace(c *gin.Context) {
+	var batch models.ProcessBatch
+	if err := db.DB.First(&batch, c.Param("id")).Error; err != nil || !canAccessCompany(c, batch.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的製程批次"})
+		return
+	}
+	lotIDs := db.DB.Model(&models.ProductionLotBatch{}).Select("lot_id").Where("process_batch_id = ?", batch.ID)
+	trace, err := forwardTrace(c, lotIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢追溯失敗: " + err.Error()})
+		return
+	}
+	trace.ProcessBatch = &batch
+	c.JSON(http.StatusOK, trace)
+}
+
+// 出貨逆向追溯：依出庫異動找出出貨的批號，再追溯到爐號與製程批次；
+// 查無成品批號資料的庫存批號仍會列出（lot.id 為 0）
+func GetShipmentTrace(c *gin.Context) {
+	shipment, _, ok := findShipment(c)
+	if !ok {
+		return
+	}
+	var issued []struct {
+		CompanyID              uint
+		ProductSpecificationID uint
+		LotNo                  string
+	}
+	query, ok := scopeByCompany(c, db.DB.Model(&models.StockMovement{}), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	err := query.Select("company_id, product_specification_id, lot_no").
+		Where("shipment_id = ? AND type = ?", shipment.ID, models.StockIssue).
+		Group("company_id, product_specification_id, lot_no").
+		Order("lot_no, product_specification_id").
+		Scan(&issued).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢追溯失敗: " + err.Error()})
+		return
+	}
+	result := models.ShipmentTrace{ShipmentID: shipment.ID, ShipmentNo: shipment.ShipmentNo, Lots: []models.LotTrace{}}
+	for _, m := range issued {
+		trace, err := lotTrace(c, findTraceLot(c, m.CompanyID, m.ProductSpecificationID, m.LotNo))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢追溯失敗: " + err.Error()})
+			return
+		}
+		result.Lots = append(result.Lots, trace)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// lotTrace 組出成品批號的追溯資料，只列出使用者可存取公司的製令、爐號與製程批次；lot.ID 為 0 時只查出貨
+func lotTrace(c *gin.Context, lot models.ProductionLot) (models.LotTrace, error) {
+	trace := models.LotTrace{Lot: lot, Heats: []models.MaterialHeat{}, Batches: []models.ProcessBatch{}}
+	if lot.ProductionOrderID != nil {
+		var order models.ProductionOrder
+		if err := db.DB.Preload("Materials").First(&order, *lot.ProductionOrderID).Error; err == nil && canAccessCompany(c, order.CompanyID) {
+			trace.ProductionOrder = &order
+		}
+	}
+	if lot.ID != 0 {
+		heats, ok := scopeByCompany(c, db.DB.Model(&models.MaterialHeat{}), "company_id")
+		if !ok {
+			return trace, nil
+		}
+		err := heats.Where("id IN (?)", db.DB.Model(&models.ProductionLotHeat{}).Select("material_heat_id").Where("lot_id = ?", lot.ID)).
+			Order("heat_no").Find(&trace.Heats).Error
+		if err != nil {
+			return trace, err
+		}
+		batches, _ := scopeByCompany(c, db.DB.Model(&models.ProcessBatch{}), "company_id")
+		err = batches.Where("id IN (?)", db.DB.Model(&models.ProductionLotBatch{}).Select("process_batch_id").Where("lot_id = ?", lot.ID)).
+			Order("processed_at, id").Find(&trace.Batches).Error
+		if err != nil {
+			return trace, err
+		}
+	}
+	shipments, err := lotShipments(c, lot)
+	trace.Shipments = shipments
+	return trace, err
+}
+
+// forwardTrace 依成品批號子查詢列出可存取的批號及其全部出貨
+func forwardTrace(c *gin.Context, lotIDs *gorm.DB) (models.ForwardTrace, error) {
+	trace := models.ForwardTrace{Lots: []models.ProductionLot{}, Shipments: []models.TraceShipment{}}
+	query, ok := scopeByCompany(c, db.DB.Where("id IN (?)", lotIDs).Order("lot_no"), "company_id")
+	if !ok {
+		return trace, nil
+	}
+	if err := query.Preload("Heats").Preload("Batches").Find(&trace.Lots).Error; err != nil {
+		return trace, err
+	}
+	for _, lot := range trace.Lots {
+		shipments, err := lotShipments(c, lot)
+		if err != nil {
+			return trace, err
+		}
+		trace.Shipments = append(trace.Shipments, shipments...)
+	}
+	return trace, nil
+}
+
+// lotShipments 批號的出貨：集團內任一公司以相同產品與批號出庫到出貨者（含調撥後出貨）
+func lotShipments(c *gin.Context, lot models.ProductionLot) ([]models.TraceShipment, error) {
+	shipments := []models.TraceShipment{}
+	query := db.DB.Table("stock_movements m").
+		Select(`m.shipment_id, s.shipment_no, s.shipped_at, o.id AS sales_order_id, o.order_no, o.customer_po_no,
+			o.customer_id, cu.group_customer_name AS customer_name, m.lot_no, -SUM(m.quantity) AS quantity, m.unit`).
+		Joins("JOIN sales_order_shipments s ON s.id = m.shipment_id").
+		Joins("JOIN sales_orders o ON o.id = s.sales_order_id").
+		Joins("JOIN customers cu ON cu.id = o.customer_id").
+		Where("m.type = ? AND m.product_specification_id = ? AND m.lot_no = ? AND m.company_id IN ?",
+			models.StockIssue, lot.ProductSpecificationID, lot.LotNo, groupCompanyIDs(lot.CompanyID)).
+		Group("m.shipment_id, s.shipment_no, s.shipped_at, o.id, o.order_no, o.customer_po_no, o.customer_id, cu.group_customer_name, m.lot_no, m.unit").
+		Order("s.shipped_at, m.shipment_id")
+	query, ok := scopeByCompany(c, query, "m.company_id")
+	if !ok {
+		return shipments, nil
+	}
+	err := query.Scan(&shipments).Error
+	return shipments, err
+}
+
+// findTraceLot 依庫存批號找出使用者可存取的成品批號資料；找不到時回傳只有批號的空資料（ID 為 0）
+func findTraceLot(c *gin.Context, companyID, specID uint, lotNo string) models.ProductionLot {
+	query, ok := scopeByCompany(c, db.DB, "company_id")
+	if !ok {
+		return models.ProductionLot{CompanyID: companyID, ProductSpecificationID: specID, LotNo: lotNo}
+	}
+	return findGroupLot(query, companyID, specID, lotNo)
+}
+
+// findGroupLot 依庫存批號找出集團內的成品批號資料（可能由集團內其他公司生產）；
+// 找不到時回傳只有批號的空資料（ID 為 0）
+func findGroupLot(query *gorm.DB, companyID, specID uint, lotNo string) models.ProductionLot {
+	lot := models.ProductionLot{CompanyID: companyID, ProductSpecificationID: specID, LotNo: lotNo}
+	query.Preload("Heats").Preload("Batches").
+		Where("lot_no = ? AND product_specification_id = ? AND company_id IN ?", lotNo, specID, groupCompanyIDs(companyID)).
+		Order("id").Limit(1).Find(&lot)
+	return lot
+}
+
+// groupCompanyIDs 與 companyID 同一集團（同一最上層公司）的所有公司
+func groupCompanyIDs(companyID uint) []uint {
+	ancestors := getAncestorCompanyIDs(companyID)
+	return getDescendantCompanyIDs(ancestors[len(ancestors)-1])
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+	"fastener-api/twofactor"
+)
+
+// 開始設定雙因素驗證：產生 TOTP 金鑰與 otpauth URI（需再呼叫啟用才生效）
+func EnrollTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	enrollment, err := twofactor.Enroll(db.DB, &user)
+	if err == twofactor.ErrAlreadyEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生金鑰失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// 以驗證器 App 的驗證碼確認並啟用雙因素驗證，回傳一次性復原碼
+func ActivateTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "請輸入驗證碼"})
+		return
+	}
+	codes, err := twofactor.Activate(db.DB, &user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已啟用雙因素驗證", "recovery_codes": codes})
+}
+
+// 重新產生復原碼（需提供目前的驗證碼或復原碼），舊的復原碼全部作廢
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	if !twofactor.Verify(db.DB, &user, req.Code, req.RecoveryCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "驗證碼錯誤"})
+		return
+	}
+	codes, err := twofactor.RegenerateRecoveryCodes(db.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "產生復原碼失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// 停用自己的雙因素驗證（需提供驗證碼或復原碼；角色要求雙因素驗證時不可停用）
+func DisableTwoFactor(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+		return
+	}
+	if twofactor.Required(db.DB, user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您的角色必須啟用雙因素驗證"})
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+		return
+	}
+	if !twofactor.Verify(db.DB, &user, req.Code, req.RecoveryCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "驗證碼錯誤"})
+		return
+	}
+	if err := twofactor.Disable(db.DB, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "停用雙因素驗證失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已停用雙因素驗證"})
+}
+
+// 管理員重設帳號的雙因素驗證（例如遺失手機），帳號下次登入需重新設定
+func ResetAccountTwoFactor(c *gin.Context) {
+	role, companyID, ok := getRoleAndCompanyID(c)
+	if !ok || (role != "superadmin" && role != "company_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	var user models.User
+	if err := db.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的帳號"})
+		return
+	}
+	if role == "company_admin" && !accountInScope(companyID, c.Param("id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法異動此公司帳號"})
+		return
+	}
+	// company_admin 不可重設擁有 superadmin 角色（含任一公司成員資格）的帳號
+	if role == "company_admin" && hasSuperAdminRole(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無法重設較高權限帳號的雙因素驗證"})
+		return
+	}
+	if err := twofactor.Disable(db.DB, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "重設雙因素驗證失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已重設雙因素驗證"})
+}
+
+// hasSuperAdminRole 帳號的主要角色或任一公司成員資格是否為 superadmin
+func hasSuperAdminRole(user models.User) bool {
+	var count int64
+	db.DB.Model(&models.Role{}).
+		Where("name = ? AND (id = ? OR id IN (?))", models.RoleSuperAdmin, user.RoleID,
+			db.DB.Model(&models.UserCompanyMembership{}).Select("role_id").Where("user_id = ?", user.ID)).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// 查詢倉庫（含儲位），可依 company_id 篩選
+func GetWarehouses(c *gin.Context) {
+	query, ok := scopeByCompany(c, db.DB.Order("company_id, code"), "company_id")
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+		return
+	}
+	if v := c.Query("company_id"); v != "" {
+		query = query.Where("company_id = ?", v)
+	}
+	var warehouses []models.Warehouse
+	err := query.Preload("Bins", func(q *gorm.DB) *gorm.DB { return q.Order("code") }).Find(&warehouses).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查詢倉庫失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, warehouses)
+}
+
+// 新增倉庫，company_id 未指定時為目前所在公司
+func CreateWarehouse(c *gin.Context) {
+	var warehouse models.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if warehouse.CompanyID == 0 {
+		_, warehouse.CompanyID, _ = getRoleAndCompanyID(c)
+	}
+	if !canAccessCompany(c, warehouse.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "無權限操作此公司"})
+		return
+	}
+	warehouse.ID = 0
+	warehouse.Bins = nil
+	warehouse.IsActive = true
+	warehouse.Code = strings.ToUpper(strings.TrimSpace(warehouse.Code))
+	warehouse.Name = strings.TrimSpace(warehouse.Name)
+	if warehouse.Code == "" || warehouse.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "倉庫代碼與名稱為必填"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.Warehouse{}).Where("company_id = ? AND code = ?", warehouse.CompanyID, warehouse.Code).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "倉庫代碼已存在"})
+		return
+	}
+	if err := db.DB.Create(&warehouse).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立倉庫失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+// 部分更新倉庫 (JSON Merge Patch)，停用請設 is_active = false
+func PatchWarehouse(c *gin.Context) {
+	warehouse, ok := findWarehouse(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &warehouse, "name", "address", "is_active", "remarks")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if name, ok := updates["name"].(string); ok {
+		if updates["name"] = strings.TrimSpace(name); updates["name"] == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "倉庫名稱為必填"})
+			return
+		}
+	}
+	if err := db.DB.Model(&warehouse).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新倉庫失敗: " + err.Error()})
+		return
+	}
+	warehouse, _ = findWarehouse(c, c.Param("id"))
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// 刪除倉庫；已有庫存異動時請改為停用
+func DeleteWarehouse(c *gin.Context) {
+	warehouse, ok := findWarehouse(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	var count int64
+	db.DB.Model(&models.StockMovement{}).Where("warehouse_id = ?", warehouse.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "倉庫已有庫存異動，請改為停用"})
+		return
+	}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("warehouse_id = ?", warehouse.ID).Delete(&models.WarehouseBin{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&warehouse).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除倉庫失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "倉庫刪除成功"})
+}
+
+// 新增儲位
+func CreateWarehouseBin(c *gin.Context) {
+	warehouse, ok := findWarehouse(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	var bin models.WarehouseBin
+	if err := c.ShouldBindJSON(&bin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	bin.ID = 0
+	bin.WarehouseID = warehouse.ID
+	bin.IsActive = true
+	bin.Code = strings.ToUpper(strings.TrimSpace(bin.Code))
+	if bin.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "儲位代碼為必填"})
+		return
+	}
+	var count int64
+	db.DB.Model(&models.WarehouseBin{}).Where("warehouse_id = ? AND code = ?", warehouse.ID, bin.Code).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "儲位代碼已存在"})
+		return
+	}
+	if err := db.DB.Create(&bin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "建立儲位失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, bin)
+}
+
+// 部分更新儲位 (JSON Merge Patch)
+func PatchWarehouseBin(c *gin.Context) {
+	bin, ok := findWarehouseBin(c)
+	if !ok {
+		return
+	}
+	updates, err := bindMergePatch(c, &bin, "description", "is_active")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式: " + err.Error()})
+		return
+	}
+	if err := db.DB.Model(&bin).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新儲位失敗: " + err.Error()})
+		return
+	}
+	db.DB.First(&bin, bin.ID)
+	c.JSON(http.StatusOK, bin)
+}
+
+// 刪除儲位；已有庫存異動時請改為停用
+func DeleteWarehouseBin(c *gin.Context) {
+	bin, ok := findWarehouseBin(c)
+	if !ok {
+		return
+	}
+	var count int64
+	db.DB.Model(&models.StockMovement{}).Where("bin_id = ?", bin.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "儲位已有庫存異動，請改為停用"})
+		return
+	}
+	if err := db.DB.Delete(&bin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "刪除儲位失敗: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "儲位刪除成功"})
+}
+
+// findWarehouse 載入倉庫（含儲位）並檢查公司範圍
+func findWarehouse(c *gin.Context, id interface{}) (models.Warehouse, bool) {
+	var warehouse models.Warehouse
+	err := db.DB.Preload("Bins", func(q *gorm.DB) *gorm.DB { return q.Order("code") }).First(&warehouse, id).Error
+	if err != nil || !canAccessCompany(c, warehouse.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的倉庫"})
+		return warehouse, false
+	}
+	return warehouse, true
+}
+
+func findWarehouseBin(c *gin.Context) (models.WarehouseBin, bool) {
+	var bin models.WarehouseBin
+	var warehouse models.Warehouse
+	err := db.DB.First(&bin, c.Param("binId")).Error
+	if err == nil {
+		err = db.DB.First(&warehouse, bin.WarehouseID).Error
+	}
+	if err != nil || !canAccessCompany(c, warehouse.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "找不到指定的儲位"})
+		return bin, false
+	}
+	return bin, true
+}
@@ -0,0 +1,246 @@
+// Package jwtkeys 管理 JWT 的非對稱簽章金鑰 (RS256 / EdDSA)。
+//
+// 金鑰設定方式（擇一）：
+//   - JWT_KEYS_DIR：目錄中每個 <kid>.pem 為一把私鑰（RSA 或 Ed25519，PKCS#1/PKCS#8），
+//     <kid>.pub.pem 為僅供驗證的公鑰（已停用簽章、等待舊 Token 過期的金鑰）
+//   - JWT_PRIVATE_KEY + JWT_KEY_ID：單一私鑰的 PEM 內容，適合只能設定環境變數的部署環境
+//
+// 簽章使用 JWT_ACTIVE_KID 指定的金鑰，未指定時使用 kid 排序最後的私鑰（以日期命名即可自然輪替）。
+// 其餘金鑰仍可驗證既有 Token，因此輪替時不會讓所有人被登出。
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key 一把具 kid 的簽章金鑰；Private 為 nil 代表僅供驗證
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  crypto.PublicKey
+	Private crypto.Signer
+}
+
+// Set 目前載入的金鑰組
+type Set struct {
+	keys   map[string]*Key
+	active *Key
+}
+
+var (
+	mu      sync.RWMutex
+	current *Set
+)
+
+// Load 依環境變數載入金鑰組並設為目前使用的金鑰，沒有可簽章的金鑰時回傳錯誤
+func Load() error {
+	set, err := loadFromEnv()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = set
+	mu.Unlock()
+	return nil
+}
+
+// ActiveKeyID 目前用來簽章的 kid
+func ActiveKeyID() string {
+	set := get()
+	if set == nil {
+		return ""
+	}
+	return set.active.ID
+}
+
+// Sign 以目前的簽章金鑰簽發 Token，並在 header 帶上 kid
+func Sign(claims jwt.Claims) (string, error) {
+	set := get()
+	if set == nil {
+		return "", errors.New("尚未載入 JWT 金鑰")
+	}
+	token := jwt.NewWithClaims(set.active.Method, claims)
+	token.Header["kid"] = set.active.ID
+	return token.SignedString(set.active.Private)
+}
+
+// Keyfunc 供 jwt.Parse 使用，依 header 的 kid 取得驗證用公鑰，並確認演算法與金鑰類型相符
+func Keyfunc(token *jwt.Token) (interface{}, error) {
+	set := get()
+	if set == nil {
+		return nil, errors.New("尚未載入 JWT 金鑰")
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := set.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的金鑰 kid=%q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("金鑰 %s 不接受演算法 %s", kid, token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// 登入流程中暫時 Token 的 aud。正式 Token 不帶 aud，
+// 以 JWKS 驗證正式 Token 的服務應拒絕帶有 aud 的 Token
+const (
+	AudienceLoginChallenge = "fastener-api/login-challenge"
+	AudienceOIDCState      = "fastener-api/oidc-state"
+)
+
+// ParserOptions 解析 Token 時應使用的選項（限定允許的演算法）
+func ParserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+	}
+}
+
+// Keys 目前所有可驗證的金鑰，依 kid 排序
+func Keys() []*Key {
+	set := get()
+	if set == nil {
+		return nil
+	}
+	keys := make([]*Key, 0, len(set.keys))
+	for _, k := range set.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+func get() *Set {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+func loadFromEnv() (*Set, error) {
+	set := &Set{keys: map[string]*Key{}}
+
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			name := filepath.Base(file)
+			kid := strings.TrimSuffix(strings.TrimSuffix(name, ".pem"), ".pub")
+			key, err := parseKey(kid, data, strings.HasSuffix(name, ".pub.pem"))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if existing, ok := set.keys[kid]; ok && existing.Private != nil {
+				continue // 同一 kid 同時有私鑰與公鑰時以私鑰為準
+			}
+			set.keys[kid] = key
+		}
+	}
+
+	if pemData := os.Getenv("JWT_PRIVATE_KEY"); pemData != "" {
+		kid := os.Getenv("JWT_KEY_ID")
+		if kid == "" {
+			return nil, errors.New("設定 JWT_PRIVATE_KEY 時必須一併設定 JWT_KEY_ID")
+		}
+		// 部分平台無法設定多行環境變數，允許以 \n 表示換行
+		key, err := parseKey(kid, []byte(strings.ReplaceAll(pemData, `\n`, "\n")), false)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: %w", err)
+		}
+		set.keys[kid] = key
+	}
+
+	var signers []string
+	for kid, k := range set.keys {
+		if k.Private != nil {
+			signers = append(signers, kid)
+		}
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("未設定任何 JWT 簽章私鑰（JWT_KEYS_DIR 或 JWT_PRIVATE_KEY）")
+	}
+	sort.Strings(signers)
+
+	activeID := os.Getenv("JWT_ACTIVE_KID")
+	if activeID == "" {
+		activeID = signers[len(signers)-1]
+	}
+	active, ok := set.keys[activeID]
+	if !ok || active.Private == nil {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID=%s 找不到對應的私鑰", activeID)
+	}
+	set.active = active
+	return set, nil
+}
+
+// parseKey 解析 PEM 格式的 RSA / Ed25519 金鑰
+func parseKey(kid string, data []byte, publicOnly bool) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("無法解析 PEM")
+	}
+
+	if publicOnly {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		method, err := methodFor(pub)
+		if err != nil {
+			return nil, err
+		}
+		return &Key{ID: kid, Method: method, Public: pub}, nil
+	}
+
+	var priv interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("不支援的私鑰類型")
+	}
+	method, err := methodFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: kid, Method: method, Public: signer.Public(), Private: signer}, nil
+}
+
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA 金鑰長度至少需 2048 bits")
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, errors.New("僅支援 RSA 與 Ed25519 金鑰")
+	}
+}
@@ -0,0 +1,314 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func rsaPEM(t *testing.T, bits int) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)})
+}
+
+func ed25519PEM(t *testing.T) (ed25519.PrivateKey, []byte, []byte) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv,
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+// useSet 於測試期間替換目前的金鑰組
+func useSet(t *testing.T, set *Set) {
+	t.Helper()
+	mu.Lock()
+	prev := current
+	current = set
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		current = prev
+		mu.Unlock()
+	})
+}
+
+func clearKeyEnv(t *testing.T) {
+	for _, name := range []string{"JWT_KEYS_DIR", "JWT_PRIVATE_KEY", "JWT_KEY_ID", "JWT_ACTIVE_KID"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	_, rsaKey := rsaPEM(t, 2048)
+	_, weakRSA := rsaPEM(t, 1024)
+	_, edKey, edPub := ed25519PEM(t)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		publicOnly  bool
+		wantAlg     string
+		wantPrivate bool
+		wantErr     bool
+	}{
+		{"RSA PKCS#1 私鑰", rsaKey, false, "RS256", true, false},
+		{"Ed25519 PKCS#8 私鑰", edKey, false, "EdDSA", true, false},
+		{"Ed25519 公鑰", edPub, true, "EdDSA", false, false},
+		{"RSA 金鑰長度不足", weakRSA, false, "", false, true},
+		{"私鑰當作公鑰", edKey, true, "", false, true},
+		{"非 PEM 內容", []byte("not a key"), false, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseKey("k1", tt.data, tt.publicOnly)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseKey() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseKey() error: %v", err)
+			}
+			if key.ID != "k1" || key.Method.Alg() != tt.wantAlg || (key.Private != nil) != tt.wantPrivate {
+				t.Errorf("parseKey() = {%s %s private=%v}", key.ID, key.Method.Alg(), key.Private != nil)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	_, rsaKey := rsaPEM(t, 2048)
+	_, edKey, edPub := ed25519PEM(t)
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("2024-01.pub.pem", edPub)
+	write("2024-06.pem", rsaKey)
+	write("2025-01.pem", edKey)
+	write("2025-01.pub.pem", edPub)
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantActive string
+		wantKids   []string
+		wantErr    bool
+	}{
+		{
+			name:       "目錄中 kid 最後的私鑰為簽章金鑰",
+			env:        map[string]string{"JWT_KEYS_DIR": dir},
+			wantActive: "2025-01",
+			wantKids:   []string{"2024-01", "2024-06", "2025-01"},
+		},
+		{
+			name:       "JWT_ACTIVE_KID 指定簽章金鑰",
+			env:        map[string]string{"JWT_KEYS_DIR": dir, "JWT_ACTIVE_KID": "2024-06"},
+			wantActive: "2024-06",
+			wantKids:   []string{"2024-01", "2024-06", "2025-01"},
+		},
+		{
+			name:    "JWT_ACTIVE_KID 只有公鑰",
+			env:     map[string]string{"JWT_KEYS_DIR": dir, "JWT_ACTIVE_KID": "2024-01"},
+			wantErr: true,
+		},
+		{
+			name:       "環境變數私鑰（以 \\n 表示換行）",
+			env:        map[string]string{"JWT_PRIVATE_KEY": strings.ReplaceAll(string(edKey), "\n", `\n`), "JWT_KEY_ID": "env"},
+			wantActive: "env",
+			wantKids:   []string{"env"},
+		},
+		{
+			name:    "環境變數私鑰缺少 kid",
+			env:     map[string]string{"JWT_PRIVATE_KEY": string(edKey)},
+			wantErr: true,
+		},
+		{
+			name:    "沒有任何私鑰",
+			env:     map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearKeyEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			set, err := loadFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadFromEnv() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadFromEnv() error: %v", err)
+			}
+			if set.active.ID != tt.wantActive {
+				t.Errorf("active kid = %s, want %s", set.active.ID, tt.wantActive)
+			}
+			if len(set.keys) != len(tt.wantKids) {
+				t.Errorf("got %d keys, want %d", len(set.keys), len(tt.wantKids))
+			}
+			for _, kid := range tt.wantKids {
+				if _, ok := set.keys[kid]; !ok {
+					t.Errorf("missing key %s", kid)
+				}
+			}
+			if set.keys["2025-01"] != nil && set.keys["2025-01"].Private == nil {
+				t.Error("同一 kid 有私鑰時不應只保留公鑰")
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	_, rsaKeyPEM := rsaPEM(t, 2048)
+	_, edKeyPEM, _ := ed25519PEM(t)
+	rsaKey, err := parseKey("rsa", rsaKeyPEM, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := parseKey("ed", edKeyPEM, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useSet(t, &Set{keys: map[string]*Key{"rsa": rsaKey, "ed": edKey}, active: edKey})
+
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		kid     interface{}
+		wantKey interface{}
+		wantErr bool
+	}{
+		{"EdDSA 與金鑰相符", jwt.SigningMethodEdDSA, "ed", edKey.Public, false},
+		{"RS256 與金鑰相符", jwt.SigningMethodRS256, "rsa", rsaKey.Public, false},
+		{"演算法與金鑰不符", jwt.SigningMethodRS256, "ed", nil, true},
+		{"HS256 不可使用公鑰", jwt.SigningMethodHS256, "rsa", nil, true},
+		{"未知的 kid", jwt.SigningMethodEdDSA, "other", nil, true},
+		{"沒有 kid", jwt.SigningMethodEdDSA, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.New(tt.method)
+			if tt.kid != nil {
+				token.Header["kid"] = tt.kid
+			}
+			key, err := Keyfunc(token)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Keyfunc() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Keyfunc() error: %v", err)
+			}
+			if !samePublicKey(key, tt.wantKey) {
+				t.Error("Keyfunc() returned the wrong key")
+			}
+		})
+	}
+
+	t.Run("簽發後可解析", func(t *testing.T) {
+		signed, err := Sign(jwt.RegisteredClaims{Subject: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims := &jwt.RegisteredClaims{}
+		if _, err := jwt.ParseWithClaims(signed, claims, Keyfunc, ParserOptions()...); err != nil || claims.Subject != "alice" {
+			t.Fatalf("parse signed token: %v", err)
+		}
+		// 以其他金鑰偽造相同 kid 的 Token 應驗證失敗
+		_, other, _ := ed25519.GenerateKey(rand.Reader)
+		forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.RegisteredClaims{Subject: "alice"})
+		forged.Header["kid"] = "ed"
+		forgedStr, err := forged.SignedString(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jwt.Parse(forgedStr, Keyfunc, ParserOptions()...); err == nil {
+			t.Error("forged token accepted")
+		}
+	})
+}
+
+func samePublicKey(a, b interface{}) bool {
+	switch ka := a.(type) {
+	case *rsa.PublicKey:
+		return ka.Equal(b)
+	case ed25519.PublicKey:
+		return ka.Equal(b)
+	}
+	return false
+}
+
+func TestPublicJWKS(t *testing.T) {
+	rsaPriv, rsaKeyPEM := rsaPEM(t, 2048)
+	edPriv, _, edPubPEM := ed25519PEM(t)
+	rsaKey, err := parseKey("a-rsa", rsaKeyPEM, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey, err := parseKey("b-ed", edPubPEM, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	useSet(t, &Set{keys: map[string]*Key{"a-rsa": rsaKey, "b-ed": edKey}, active: rsaKey})
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	want := []JWK{
+		{Kty: "RSA", Kid: "a-rsa", Use: "sig", Alg: "RS256",
+			N: b64(rsaPriv.N.Bytes()), E: b64(big.NewInt(int64(rsaPriv.E)).Bytes())},
+		{Kty: "OKP", Kid: "b-ed", Use: "sig", Alg: "EdDSA",
+			Crv: "Ed25519", X: b64(edPriv.Public().(ed25519.PublicKey))},
+	}
+	got := PublicJWKS().Keys
+	if len(got) != len(want) {
+		t.Fatalf("got %d keys, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("key %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPublicJWKSWithoutKeys(t *testing.T) {
+	useSet(t, nil)
+	if keys := PublicJWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Errorf("PublicJWKS().Keys = %v, want empty list", keys)
+	}
+}
@@ -2,79 +2,273 @@ package main
 
 import (
 	"log"
+	"net/http"
 	"os"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/wac0705/fastener-api/db"
 	"github.com/wac0705/fastener-api/handler"
+	"github.com/wac0705/fastener-api/jwtkeys"
 	"github.com/wac0705/fastener-api/middleware"
 	"github.com/wac0705/fastener-api/routes"
 )
 
-func setupRoutes(app *fiber.App) {
+func setupRoutes(r *gin.Engine) {
+	// Public keys for verifying our tokens (used by other internal services)
+	r.GET("/.well-known/jwks.json", routes.JWKSHandler())
+
 	// Auth routes
-	app.Post("/api/login", routes.Login)
+	r.POST("/api/login", routes.LoginHandler(db.DB))
+	r.POST("/api/login/2fa", routes.TwoFactorLoginHandler(db.DB))             // Second step: challenge token + TOTP/recovery code
+	r.POST("/api/login/2fa/enroll", routes.TwoFactorEnrollHandler(db.DB))     // Required 2FA not yet set up: get TOTP secret
+	r.POST("/api/login/2fa/activate", routes.TwoFactorActivateHandler(db.DB)) // Confirm enrollment and receive the real token
+	r.GET("/api/auth/oidc/login", routes.OIDCLoginHandler())                  // Start corporate SSO (authorization code + PKCE)
+	r.GET("/api/auth/oidc/callback", routes.OIDCCallbackHandler(db.DB))       // IdP redirect target
 
 	// API Group with JWT middleware protection
-	api := app.Group("/api", middleware.Protected())
+	api := r.Group("/api", middleware.JWTAuthMiddleware(), middleware.RequirePermission())
 
 	// A simple welcome route to test JWT
-	api.Get("/", func(c *fiber.Ctx) error {
-		return c.SendString("Welcome to the protected area!")
+	api.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "Welcome to the protected area!")
 	})
 
 	// Company Routes
-	api.Get("/companies", handler.GetCompanies)
-	api.Get("/companies/tree", handler.GetCompaniesTree) // Get companies as a tree structure
-	api.Get("/companies/:id", handler.GetCompany)
-	api.Post("/companies", handler.CreateCompany)
-	api.Put("/companies/:id", handler.UpdateCompany)
-	api.Delete("/companies/:id", handler.DeleteCompany)
+	api.GET("/companies", handler.GetCompanies)
+	api.GET("/companies/tree", handler.GetCompanies) // Get companies as a tree structure
+	api.GET("/companies/:id", handler.GetCompanyByID)
+	api.POST("/companies", handler.CreateCompany)
+	api.PUT("/companies/:id", handler.UpdateCompany)
+	api.PATCH("/companies/:id", handler.PatchCompany)
+	api.DELETE("/companies/:id", handler.DeleteCompany)
 
 	// Role Routes
-	api.Get("/roles", handler.GetRoles)
-	api.Get("/roles/:id", handler.GetRole)
-	api.Post("/roles", handler.CreateRole)
-	api.Put("/roles/:id", handler.UpdateRole)
-	api.Delete("/roles/:id", handler.DeleteRole)
+	api.GET("/roles", handler.GetRoles)
+	api.GET("/roles/:id", handler.GetRole)
+	api.POST("/roles", handler.CreateRole)
+	api.PUT("/roles/:id", handler.UpdateRole)
+	api.PATCH("/roles/:id", handler.PatchRole)
+	api.DELETE("/roles/:id", handler.DeleteRole)
+	api.POST("/roles/:id/clone", handler.CloneRole)
+	api.GET("/permissions", handler.GetPermissions)
 
 	// Menu Routes
-	api.Get("/menus", handler.GetMenus)             // Get flat list of menus
-	api.Get("/menus/tree", handler.GetAllMenusTree) // NEW: Get full menu tree for admin pages
-	api.Get("/menus/:id", handler.GetMenu)
-	api.Post("/menus", handler.CreateMenu)
-	api.Put("/menus/:id", handler.UpdateMenu)
-	api.Delete("/menus/:id", handler.DeleteMenu)
+	api.GET("/menus", handler.GetMenus)                         // Get flat list of menus
+	api.GET("/menus/tree", handler.GetAllMenusTree)             // NEW: Get full menu tree for admin pages
+	api.GET("/menus/consistency", handler.CheckMenuConsistency) // Flag menus whose routes a role cannot call
+	api.PUT("/menus/order", handler.ReorderMenus)               // Drag-and-drop reordering, rewrites order_no
+	api.GET("/menus/:id", handler.GetMenu)
+	api.POST("/menus", handler.CreateMenu)
+	api.PUT("/menus/:id", handler.UpdateMenu)
+	api.PATCH("/menus/:id", handler.PatchMenu)
+	api.DELETE("/menus/:id", handler.DeleteMenu)
 
 	// User-specific menu route
-	api.Get("/user-menus", handler.GetUserMenus) // NEW: Get menu tree for the logged-in user's sidebar
+	api.GET("/user-menus", handler.GetUserMenus) // NEW: Get menu tree for the logged-in user's sidebar
+
+	// Current user profile
+	api.GET("/me", handler.GetMe)
+	api.PUT("/me", handler.UpdateMe)
+	api.GET("/me/companies", handler.GetMyCompanies)
+	api.POST("/me/switch-company", routes.SwitchCompanyHandler(db.DB)) // Reissue the JWT for another accessible company
+	api.POST("/me/2fa/enroll", handler.EnrollTwoFactor)
+	api.POST("/me/2fa/activate", handler.ActivateTwoFactor)
+	api.POST("/me/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+	api.DELETE("/me/2fa", handler.DisableTwoFactor)
 
 	// Role-Menu Relation Routes
-	api.Get("/roles/:id/menus", handler.GetRoleMenus)
-	api.Put("/roles/:id/menus", handler.UpdateRoleMenus)
+	api.GET("/roles/:id/menus", handler.GetRoleMenus)
+	api.PUT("/roles/:id/menus", handler.UpdateRoleMenus)
+	api.PATCH("/roles/:id/menus/:menuId", handler.PatchRoleMenu) // Per-role visibility/order override
+	api.DELETE("/roles/:id/menus/:menuId", handler.DeleteRoleMenu)
+	api.GET("/roles/:id/menus/history", handler.GetRoleMenuHistory)
 
 	// Account Management Routes
-	api.Get("/manage-accounts", handler.GetAccounts)
-	api.Post("/manage-accounts", handler.CreateAccount)
-	api.Put("/manage-accounts/:id", handler.UpdateAccount)
-	api.Delete("/manage-accounts/:id", handler.DeleteAccount)
+	api.GET("/manage-accounts", handler.GetAccounts)
+	api.POST("/manage-accounts", handler.CreateAccount)
+	api.PUT("/manage-accounts/:id", handler.UpdateAccount)
+	api.PATCH("/manage-accounts/:id", handler.PatchAccount)
+	api.DELETE("/manage-accounts/:id", handler.DeleteAccount)
+	api.GET("/manage-accounts/:id/companies", handler.GetAccountMemberships)
+	api.PUT("/manage-accounts/:id/companies", handler.PutAccountMembership)
+	api.DELETE("/manage-accounts/:id/companies/:companyId", handler.DeleteAccountMembership)
+	api.DELETE("/manage-accounts/:id/2fa", handler.ResetAccountTwoFactor)
+
+	// API keys for machine-to-machine integrations
+	api.GET("/manage-accounts/api-keys", handler.GetAPIKeys)
+	api.POST("/manage-accounts/api-keys", handler.CreateAPIKey)
+	api.DELETE("/manage-accounts/api-keys/:keyId", handler.RevokeAPIKey)
 
 	// Customer Routes
-	api.Get("/customers", handler.GetCustomers)
-	api.Post("/customers", handler.CreateCustomer)
-	api.Get("/customers/:id", handler.GetCustomer)
-	api.Put("/customers/:id", handler.UpdateCustomer)
-	api.Delete("/customers/:id", handler.DeleteCustomer)
-	api.Get("/customers/:id/transaction-terms", handler.GetCustomerTransactionTerms)
-	api.Post("/customers/:id/transaction-terms", handler.CreateCustomerTransactionTerm)
-	api.Put("/customer-transaction-terms/:termId", handler.UpdateCustomerTransactionTerm)
-	api.Delete("/customer-transaction-terms/:termId", handler.DeleteCustomerTransactionTerm)
+	api.GET("/customers", handler.GetCustomers)
+	api.POST("/customers", handler.CreateCustomer)
+	api.GET("/customers/:id", handler.GetCustomerByID)
+	api.PUT("/customers/:id", handler.UpdateCustomer)
+	api.PATCH("/customers/:id", handler.PatchCustomer)
+	api.DELETE("/customers/:id", handler.DeleteCustomer)
+	api.GET("/customers/:id/sites", handler.GetCustomerSites) // Sold-to / ship-to / bill-to sites, ?role= filter
+	api.POST("/customers/:id/sites", handler.CreateCustomerSite)
+	api.GET("/customer-sites/:siteId", handler.GetCustomerSite)
+	api.PATCH("/customer-sites/:siteId", handler.PatchCustomerSite)
+	api.DELETE("/customer-sites/:siteId", handler.DeleteCustomerSite)
+	api.GET("/customers/:id/contacts", handler.GetCustomerContacts)
+	api.POST("/customers/:id/contacts", handler.CreateCustomerContact)
+	api.PATCH("/customer-contacts/:contactId", handler.PatchCustomerContact)
+	api.DELETE("/customer-contacts/:contactId", handler.DeleteCustomerContact)
+
+	// Customer activity log routes
+	api.GET("/customers/:id/activities", handler.GetCustomerActivities)
+	api.POST("/customers/:id/activities", handler.CreateCustomerActivity)
+	api.GET("/customer-activities", handler.GetCustomerActivities) // Filter by customer_id, user_id, type, from/to
+	api.GET("/customer-activities/:activityId", handler.GetCustomerActivity)
+	api.PUT("/customer-activities/:activityId", handler.UpdateCustomerActivity)
+	api.DELETE("/customer-activities/:activityId", handler.DeleteCustomerActivity)
+	api.POST("/customer-activities/:activityId/attachments", handler.UploadActivityAttachment)
+	api.GET("/customer-activities/:activityId/attachments/:attachmentId", handler.DownloadActivityAttachment)
+	api.DELETE("/customer-activities/:activityId/attachments/:attachmentId", handler.DeleteActivityAttachment)
+	api.GET("/customers/:id/transaction-terms", handler.GetCustomerTransactionTerms)
+	api.POST("/customers/:id/transaction-terms", handler.CreateCustomerTransactionTerm)
+	api.PUT("/customer-transaction-terms/:termId", handler.UpdateCustomerTransactionTerm)
+	api.PATCH("/customer-transaction-terms/:termId", handler.PatchCustomerTransactionTerm)
+	api.DELETE("/customer-transaction-terms/:termId", handler.DeleteCustomerTransactionTerm)
+
+	// Customer price list routes
+	api.GET("/customer-transaction-terms/:termId/price-lists", handler.GetPriceLists)
+	api.POST("/customer-transaction-terms/:termId/price-lists", handler.CreatePriceList)
+	api.GET("/price-lists/:id", handler.GetPriceList)
+	api.PUT("/price-lists/:id", handler.UpdatePriceList)
+	api.DELETE("/price-lists/:id", handler.DeletePriceList)
+	api.GET("/price-resolution", handler.ResolvePrice) // Applicable price for a product, quantity and date
 
 	// Product Definition Routes
-	api.Get("/definitions/product-categories", handler.GetProductCategories)
-	api.Post("/definitions/product-categories", handler.CreateProductCategory)
+	api.GET("/definitions/product-categories", handler.GetProductCategories)
+	api.POST("/definitions/product-categories", handler.CreateProductCategory)
+	api.PATCH("/definitions/product-categories/:id", handler.PatchProductCategory)
+
+	// Quotation routes
+	api.GET("/quotations", handler.GetQuotations)
+	api.GET("/quotations/:id", handler.GetQuotation)
+	api.POST("/quotations", handler.CreateQuotation)
+	api.PUT("/quotations/:id", handler.UpdateQuotation)
+	api.PUT("/quotations/:id/status", handler.UpdateQuotationStatus) // draft → sent → accepted / rejected
+	api.DELETE("/quotations/:id", handler.DeleteQuotation)
+
+	// Sales order routes
+	api.GET("/sales-orders", handler.GetSalesOrders)
+	api.GET("/sales-orders/:id", handler.GetSalesOrder)
+	api.GET("/sales-orders/:id/confirmation", handler.GetOrderConfirmation)
+	api.POST("/sales-orders", handler.CreateSalesOrder) // Directly or from an accepted quotation (quotation_id)
+	api.PUT("/sales-orders/:id", handler.UpdateSalesOrder)
+	api.DELETE("/sales-orders/:id", handler.DeleteSalesOrder)
+	api.POST("/sales-orders/:id/confirm", handler.ConfirmSalesOrder)
+	api.POST("/sales-orders/:id/cancel", handler.CancelSalesOrder)
+	api.POST("/sales-orders/:id/close", handler.CloseSalesOrder)
+	api.POST("/sales-orders/:id/shipments", handler.CreateSalesOrderShipment) // Partial shipments
+	api.POST("/sales-orders/:id/invoices", handler.CreateInvoice)             // Proforma from the order, or commercial per shipment
+
+	// Export shipment routes
+	api.GET("/shipments", handler.GetShipments) // ETD / ETA tracking
+	api.GET("/shipments/:id", handler.GetShipment)
+	api.PATCH("/shipments/:id", handler.PatchShipment) // Ports, vessel, ETD / ETA, shipping mark
+	api.GET("/shipments/:id/packing-list", handler.GetPackingList)
+	api.GET("/shipments/:id/shipping-marks", handler.GetShippingMarks)
+	api.POST("/shipments/:id/containers", handler.CreateShipmentContainer)
+	api.PATCH("/shipment-containers/:containerId", handler.PatchShipmentContainer)
+	api.DELETE("/shipment-containers/:containerId", handler.DeleteShipmentContainer)
+	api.POST("/shipments/:id/packages", handler.CreateShipmentPackages) // Cartons (count identical ones at once) or pallets
+	api.PATCH("/shipment-packages/:packageId", handler.PatchShipmentPackage)
+	api.DELETE("/shipment-packages/:packageId", handler.DeleteShipmentPackage)
+
+	// Invoice routes
+	api.GET("/invoices", handler.GetInvoices)
+	api.GET("/invoices/:id", handler.GetInvoice)
+	api.POST("/invoices/:id/payments", handler.RecordInvoicePayment)
+	api.POST("/invoices/:id/cancel", handler.CancelInvoice)
+	api.GET("/document-sequences", handler.GetDocumentSequences)
+	api.PUT("/document-sequences", handler.PutDocumentSequence) // Per-company invoice number prefix / next number
+
+	// Sales agent & commission routes
+	api.GET("/sales-agents", handler.GetSalesAgents)
+	api.POST("/sales-agents", handler.CreateSalesAgent)
+	api.PATCH("/sales-agents/:id", handler.PatchSalesAgent)
+	api.DELETE("/sales-agents/:id", handler.DeleteSalesAgent)
+	api.GET("/commissions", handler.GetCommissions)
+	api.GET("/commissions/statements", handler.GetCommissionStatements) // Monthly statement per agent and currency
+	api.GET("/commissions/summary", handler.GetCommissionSummary)       // Totals by company subtree
+	api.POST("/commissions/pay", handler.PayCommissions)
+
+	// Credit control routes
+	api.GET("/credit-limits", handler.GetCreditLimits)
+	api.PUT("/credit-limits", handler.PutCreditLimit) // Upsert per customer + selling company
+	api.DELETE("/credit-limits/:id", handler.DeleteCreditLimit)
+	api.GET("/customers/:id/credit-exposure", handler.GetCreditExposure)
+	api.GET("/credit-holds", handler.GetCreditHolds)
+	api.POST("/sales-orders/:id/release-credit-hold", handler.ReleaseCreditHold)
+	api.GET("/exchange-rates", handler.GetExchangeRates)
+	api.POST("/exchange-rates", handler.CreateExchangeRate)
+
+	// Document rendering routes (quotation / invoice / packing_list / order_confirmation)
+	api.GET("/document-templates", handler.GetDocumentTemplates)
+	api.PUT("/document-templates", handler.PutDocumentTemplate) // Upsert per company + doc type
+	api.PUT("/document-templates/:id/logo", handler.UploadDocumentTemplateLogo)
+	api.DELETE("/document-templates/:id", handler.DeleteDocumentTemplate)
+	api.GET("/documents/:docType/:id", handler.GetRenderedDocuments)
+	api.POST("/documents/:docType/:id", handler.RenderDocument)              // Render a new version and download the PDF
+	api.GET("/rendered-documents/:id/pdf", handler.DownloadRenderedDocument) // Re-render a stored version byte-for-byte
+
+	// Warehouse and stock routes
+	api.GET("/warehouses", handler.GetWarehouses)
+	api.POST("/warehouses", handler.CreateWarehouse)
+	api.PATCH("/warehouses/:id", handler.PatchWarehouse)
+	api.DELETE("/warehouses/:id", handler.DeleteWarehouse)
+	api.POST("/warehouses/:id/bins", handler.CreateWarehouseBin)
+	api.PATCH("/warehouse-bins/:binId", handler.PatchWarehouseBin)
+	api.DELETE("/warehouse-bins/:binId", handler.DeleteWarehouseBin)
+	api.GET("/stock", handler.GetStockBalances)
+	api.GET("/stock/movements", handler.GetStockMovements)
+	api.GET("/stock/availability", handler.GetStockAvailability) // On-hand minus open order commitments
+	api.POST("/stock/receipts", handler.CreateStockReceipt)
+	api.POST("/stock/issues", handler.CreateStockIssue)
+	api.POST("/stock/transfers", handler.CreateStockTransfer) // Also between companies in the same group
+	api.POST("/stock/adjustments", handler.CreateStockAdjustment)
+	api.GET("/sales-orders/:id/availability", handler.GetSalesOrderAvailability)
+
+	// Lot and heat-number traceability routes
+	api.GET("/material-heats", handler.GetMaterialHeats)
+	api.POST("/material-heats", handler.CreateMaterialHeat)
+	api.PATCH("/material-heats/:id", handler.PatchMaterialHeat)
+	api.GET("/material-heats/:id/trace", handler.GetMaterialHeatTrace) // Forward: heat -> lots -> shipments
+	api.GET("/process-batches", handler.GetProcessBatches)
+	api.POST("/process-batches", handler.CreateProcessBatch) // Heat treatment or plating batch
+	api.PATCH("/process-batches/:id", handler.PatchProcessBatch)
+	api.GET("/process-batches/:id/trace", handler.GetProcessBatchTrace) // Forward: batch -> lots -> shipments
+	api.GET("/production-orders", handler.GetProductionOrders)
+	api.GET("/production-orders/:id", handler.GetProductionOrder)
+	api.POST("/production-orders", handler.CreateProductionOrder)
+	api.POST("/production-orders/:id/start", handler.StartProductionOrder)
+	api.POST("/production-orders/:id/complete", handler.CompleteProductionOrder)
+	api.POST("/production-orders/:id/cancel", handler.CancelProductionOrder)
+	api.GET("/production-lots", handler.GetProductionLots)
+	api.GET("/production-lots/:id", handler.GetProductionLot)
+	api.POST("/production-lots", handler.CreateProductionLot)
+	api.PUT("/production-lots/:id", handler.UpdateProductionLot)
+	api.GET("/production-lots/:id/trace", handler.GetProductionLotTrace) // Backward to heats and batches, forward to shipments
+	api.GET("/shipments/:id/trace", handler.GetShipmentTrace)            // Backward from a shipment through issued lots
+
+	// Mill certificate and inspection certificate routes
+	// (EN 10204 3.1 PDFs are rendered via /documents/inspection_certificate/:shipmentLineId)
+	api.GET("/mill-certificates", handler.GetMillCertificates)
+	api.POST("/mill-certificates", handler.CreateMillCertificate)
+	api.PUT("/mill-certificates/:id", handler.UpdateMillCertificate)
+	api.DELETE("/mill-certificates/:id", handler.DeleteMillCertificate)
+	api.PUT("/mill-certificates/:id/file", handler.UploadMillCertificateFile) // PDF / JPEG / PNG from the mill
+	api.GET("/mill-certificates/:id/file", handler.DownloadMillCertificateFile)
+	api.GET("/production-lots/:id/inspections", handler.GetLotInspections)
+	api.POST("/production-lots/:id/inspections", handler.CreateLotInspection) // Hardness, tensile, coating thickness...
+	api.PUT("/inspection-results/:id", handler.UpdateInspectionResult)
+	api.DELETE("/inspection-results/:id", handler.DeleteInspectionResult)
+	api.GET("/sales-orders/:id/certificates", handler.GetSalesOrderCertificates) // Latest certificate per shipped order line
 	// Add other product definition routes here if needed
 }
 
@@ -85,21 +279,23 @@ func main() {
 		log.Println("Note: .env file not found, using environment variables")
 	}
 
-	app := fiber.New()
+	r := gin.Default()
 
 	// CORS Middleware
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: os.Getenv("FRONTEND_URL"),
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
-	}))
+	r.Use(middleware.CORS(os.Getenv("FRONTEND_URL")))
+
+	// Load JWT signing keys; refuse to start without one
+	if err := jwtkeys.Load(); err != nil {
+		log.Fatalf("JWT key configuration error: %v", err)
+	}
+	log.Printf("JWT signing key: %s", jwtkeys.ActiveKeyID())
 
 	// Connect to the database
-	db.ConnectDB()
+	db.Init()
 
 	// Setup routes
-	setupRoutes(app)
+	setupRoutes(r)
 
 	// Start server
-	log.Fatal(app.Listen(":3001"))
+	log.Fatal(r.Run(":3001"))
 }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// lastUsedInterval 同一把金鑰最多每分鐘更新一次最後使用時間，避免每個請求都寫入資料庫
+const lastUsedInterval = time.Minute
+
+// apiKeyFromRequest 取出請求中的 API Key，沒有則回傳空字串
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(token, models.APIKeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey 驗證 API Key，成功時以金鑰的公司與權限作為身分繼續處理請求
+func authenticateAPIKey(c *gin.Context, key string) {
+	prefix, ok := models.SplitAPIKey(key)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 API Key"})
+		c.Abort()
+		return
+	}
+
+	var apiKey models.APIKey
+	if err := db.DB.Where("prefix = ?", prefix).First(&apiKey).Error; err != nil ||
+		subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(models.HashAPIKey(key))) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 API Key"})
+		c.Abort()
+		return
+	}
+	now := time.Now()
+	if !apiKey.Usable(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API Key 已過期或已撤銷"})
+		c.Abort()
+		return
+	}
+
+	db.DB.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", apiKey.ID, now.Add(-lastUsedInterval)).
+		Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": c.ClientIP()})
+
+	// API Key 沒有角色，權限完全由金鑰本身的設定決定
+	c.Set("username", "apikey:"+apiKey.Prefix)
+	c.Set("role", "")
+	c.Set("role_id", uint(0))
+	c.Set("company_id", apiKey.CompanyID)
+	c.Set("api_key_id", apiKey.ID)
+	c.Set("permissions", apiKey.Permissions)
+
+	c.Next()
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyFromRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"X-API-Key", map[string]string{"X-API-Key": "fk_abcd1234_secret"}, "fk_abcd1234_secret"},
+		{"Bearer API Key", map[string]string{"Authorization": "Bearer fk_abcd1234_secret"}, "fk_abcd1234_secret"},
+		{"X-API-Key 優先", map[string]string{"X-API-Key": "fk_a_1", "Authorization": "Bearer fk_b_2"}, "fk_a_1"},
+		{"Bearer JWT 不是 API Key", map[string]string{"Authorization": "Bearer eyJhbGciOi.x.y"}, ""},
+		{"缺少 Bearer 前綴", map[string]string{"Authorization": "fk_abcd1234_secret"}, "fk_abcd1234_secret"},
+		{"沒有任何 Header", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/customers", nil)
+			for k, v := range tt.headers {
+				c.Request.Header.Set(k, v)
+			}
+			if got := apiKeyFromRequest(c); got != tt.want {
+				t.Errorf("apiKeyFromRequest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 格式不符的金鑰在查詢資料庫前就應被拒絕
+func TestJWTAuthMiddlewareRejectsMalformedAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, key := range []string{"fk_", "fk__secret", "fk_noseparator", "not-a-key"} {
+		t.Run(key, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/api/customers", JWTAuthMiddleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/customers", nil)
+			req.Header.Set("X-API-Key", key)
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS 是一個 Gin 中介軟體，允許 allowOrigin（前端網址）跨域呼叫 API；預檢請求直接回應 204
+func CORS(allowOrigin string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if allowOrigin != "" && c.GetHeader("Origin") == allowOrigin {
+			c.Header("Access-Control-Allow-Origin", allowOrigin)
+			c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-API-Key")
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Vary", "Origin")
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
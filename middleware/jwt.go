@@ -3,27 +3,33 @@ package middleware
 import (
 	"errors"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"fastener-api/jwtkeys"
 )
 
 // Claims 定義了 JWT token 中儲存的資訊
 type Claims struct {
 	Username  string `json:"username"`
 	Role      string `json:"role"`
+	RoleID    uint   `json:"role_id"`
 	CompanyID int    `json:"company_id"`
+	Purpose   string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// jwtKey 從環境變數讀取密鑰
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
-
 // JWTAuthMiddleware 是一個 Gin 中介軟體，用於驗證 JWT
+// 也接受 API Key（X-API-Key Header，或 Authorization: Bearer fk_...）作為系統間整合的替代驗證方式
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := apiKeyFromRequest(c); apiKey != "" {
+			authenticateAPIKey(c, apiKey)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "缺少 Authorization Header"})
@@ -39,9 +45,8 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		}
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		// 依 header 的 kid 選擇公鑰驗證，輪替中的舊金鑰仍可驗證既有 Token
+		token, err := jwt.ParseWithClaims(tokenStr, claims, jwtkeys.Keyfunc, jwtkeys.ParserOptions()...)
 
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
@@ -53,7 +58,8 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if !token.Valid {
+		// 登入流程中的暫時 Token（例如雙因素驗證挑戰、SSO state）帶有 aud，不可用來呼叫 API
+		if !token.Valid || claims.Purpose != "" || len(claims.Audience) > 0 {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "無效的 Token"})
 			c.Abort()
 			return
@@ -62,6 +68,7 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		// 將驗證後的使用者資訊存入 context，供後續 handler 使用
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		c.Set("role_id", claims.RoleID)
 		c.Set("company_id", claims.CompanyID)
 
 		c.Next()
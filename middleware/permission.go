@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"fastener-api/db"
+	"fastener-api/models"
+)
+
+// RequirePermission 是一個 Gin 中介軟體，依 models.RoutePermissions 檢查登入者的角色是否擁有該路由所需的權限。
+// 必須放在 JWTAuthMiddleware 之後；驗證通過後將權限清單存入 context 的 "permissions"。
+func RequirePermission() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		roleID := c.GetUint("role_id")
+
+		// API Key 的權限已由驗證中介軟體載入；只能呼叫有對應權限設定的路由
+		if _, isAPIKey := c.Get("api_key_id"); isAPIKey {
+			permissions, _ := c.Get("permissions")
+			granted, _ := permissions.([]string)
+			required := models.RoutePermissions[c.Request.Method+" "+c.FullPath()]
+			if required == "" || !models.HasPermission(role, granted, required) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "權限不足", "required_permission": required})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// 舊版 Token 沒有 role_id，改以角色名稱查詢
+		var r models.Role
+		var err error
+		if roleID != 0 {
+			err = db.DB.First(&r, roleID).Error
+		} else {
+			err = db.DB.Where("name = ?", role).First(&r).Error
+		}
+		var permissions []string
+		if err == nil {
+			permissions = r.Permissions
+		}
+		c.Set("permissions", permissions)
+
+		required := models.RoutePermissions[c.Request.Method+" "+c.FullPath()]
+		if !models.HasPermission(role, permissions, required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "權限不足", "required_permission": required})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,206 @@
+// mockidp/main.go
+//
+// 本機測試用的 OIDC IdP，支援 discovery、授權碼 + PKCE (S256)、ID Token 與 JWKS。
+// 不做任何帳號驗證：在登入頁輸入 email 即可以該身分登入，僅供開發與測試 SSO 流程使用。
+//
+//	go run ./mockidp
+//	OIDC_ISSUER=http://localhost:9000 OIDC_CLIENT_ID=fastener-api \
+//	OIDC_REDIRECT_URL=http://localhost:3001/api/auth/oidc/callback go run .
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-idp"
+
+type authRequest struct {
+	ClientID    string
+	RedirectURI string
+	Nonce       string
+	Challenge   string
+	Email       string
+	ExpiresAt   time.Time
+}
+
+var (
+	issuer  = envOr("MOCK_IDP_ISSUER", "http://localhost:9000")
+	addr    = envOr("MOCK_IDP_ADDR", ":9000")
+	pubKey  ed25519.PublicKey
+	privKey ed25519.PrivateKey
+
+	mu    sync.Mutex
+	codes = map[string]authRequest{}
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><body>
+<h3>Mock IdP 登入</h3>
+<form method="post">
+{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">{{end}}
+<label>Email <input name="email" value="{{index .login_hint 0}}" autofocus></label>
+<button type="submit">登入</button>
+</form>
+</body></html>`))
+
+func main() {
+	var err error
+	pubKey, privKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatalf("無法產生金鑰: %v", err)
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", discovery)
+	http.HandleFunc("/authorize", authorize)
+	http.HandleFunc("/token", token)
+	http.HandleFunc("/jwks", jwks)
+
+	log.Printf("🧪 Mock IdP 啟動於 %s（issuer: %s）", addr, issuer)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+func discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"EdDSA"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize GET 顯示登入頁（帶 login_hint 可預填 email），POST 產生授權碼後導回 redirect_uri
+func authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	if params.Get("response_type") != "code" || params.Get("code_challenge_method") != "S256" ||
+		params.Get("client_id") == "" || params.Get("redirect_uri") == "" || params.Get("code_challenge") == "" {
+		http.Error(w, "需要 response_type=code 與 PKCE S256", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(params.Get("email"))
+	if r.Method != http.MethodPost || email == "" {
+		if params.Get("login_hint") == "" {
+			params.Set("login_hint", "")
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		loginPage.Execute(w, params)
+		return
+	}
+
+	code := randomString()
+	mu.Lock()
+	codes[code] = authRequest{
+		ClientID:    params.Get("client_id"),
+		RedirectURI: params.Get("redirect_uri"),
+		Nonce:       params.Get("nonce"),
+		Challenge:   params.Get("code_challenge"),
+		Email:       email,
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	mu.Unlock()
+
+	q := url.Values{}
+	q.Set("code", code)
+	q.Set("state", params.Get("state"))
+	http.Redirect(w, r, params.Get("redirect_uri")+"?"+q.Encode(), http.StatusFound)
+}
+
+// token 驗證授權碼與 PKCE verifier 後簽發 ID Token
+func token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.Form.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.Form.Get("code")
+	mu.Lock()
+	req, ok := codes[code]
+	delete(codes, code) // 授權碼只能使用一次
+	mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || time.Now().After(req.ExpiresAt) ||
+		req.ClientID != r.Form.Get("client_id") || req.RedirectURI != r.Form.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != req.Challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"iss":            issuer,
+		"sub":            "mock|" + strings.ToLower(req.Email),
+		"aud":            req.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          req.Nonce,
+		"email":          req.Email,
+		"email_verified": true,
+		"name":           strings.Split(req.Email, "@")[0],
+	})
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(privKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "EdDSA",
+			"x":   base64.RawURLEncoding.EncodeToString(pubKey),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -4,14 +4,28 @@ import "time"
 
 // GORM ORM 用的 User struct，對應 users 資料表
 type User struct {
-	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"password_hash"` // ★ 必須對應資料庫欄位
-	RoleID       uint      `json:"role_id"`
-	CompanyID    uint      `json:"company_id"`    // tenant_id
-	IsActive     bool      `json:"is_active"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"` // ★ 必須對應資料庫欄位
+	RoleID       uint   `json:"role_id"`
+	CompanyID    uint   `json:"company_id" gorm:"column:tenant_id"`
+	IsActive     bool   `json:"is_active"`
+	// 個人資料，偏好語言/幣別空白時沿用公司設定
+	DisplayName       string `json:"display_name"`
+	Email             string `json:"email"`
+	PreferredLanguage string `json:"preferred_language"`
+	PreferredCurrency string `json:"preferred_currency"`
+	// 雙因素驗證 (TOTP)；金鑰在啟用前即寫入，TOTPEnabled 為 true 才生效
+	TOTPSecret      string `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled     bool   `json:"totp_enabled" gorm:"column:totp_enabled"`
+	TOTPLastCounter int64  `json:"-" gorm:"column:totp_last_counter"`
+	// 登入失敗次數與鎖定期限，見 MaxFailedLogins / LockoutDuration
+	FailedLoginCount int        `json:"failed_login_count"`
+	LockedUntil      *time.Time `json:"locked_until"`
+	// SSO (OIDC) 綁定的 IdP subject；null 代表僅使用本機密碼登入
+	OIDCSubject *string   `json:"-" gorm:"column:oidc_subject;uniqueIndex"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // 用於 API 回傳給前端的帳號資訊
@@ -38,3 +52,44 @@ type UpdateAccountRequest struct {
 	IsActive  bool   `json:"is_active"`
 	CompanyID uint   `json:"company_id"`
 }
+
+// 前端部分更新帳號請求 (JSON Merge Patch)，未提供的欄位不更新
+type PatchAccountRequest struct {
+	Role      *string `json:"role"`
+	IsActive  *bool   `json:"is_active"`
+	CompanyID *uint   `json:"company_id"`
+}
+
+// 目前登入者的個人資料
+type UserProfile struct {
+	ID                uint   `json:"id"`
+	Username          string `json:"username"`
+	DisplayName       string `json:"display_name"`
+	Email             string `json:"email"`
+	PreferredLanguage string `json:"preferred_language"`
+	PreferredCurrency string `json:"preferred_currency"`
+}
+
+// /api/me 回傳內容
+type MeResponse struct {
+	User    UserProfile `json:"user"`
+	Role    Role        `json:"role"`
+	Company Company     `json:"company"`
+	// 由最上層公司到目前公司的路徑
+	CompanyPath []Company `json:"company_path"`
+	// 可存取的公司（目前公司及其下層，樹狀結構）
+	AccessibleCompanies []*Company `json:"accessible_companies"`
+	Permissions         []string   `json:"permissions"`
+	Menus               []Menu     `json:"menus"`
+	// 實際生效的語言與幣別（個人偏好優先，否則為公司設定）
+	Language string `json:"language"`
+	Currency string `json:"currency"`
+}
+
+// 更新個人資料請求
+type UpdateProfileRequest struct {
+	DisplayName       string `json:"display_name"`
+	Email             string `json:"email"`
+	PreferredLanguage string `json:"preferred_language"`
+	PreferredCurrency string `json:"preferred_currency"`
+}
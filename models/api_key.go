@@ -0,0 +1,80 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix 所有 API Key 的開頭，方便在紀錄或程式碼中辨識
+const APIKeyPrefix = "fk_"
+
+// APIKey 供系統間整合（EDI、報表程式）使用的金鑰，屬於單一公司。
+// 金鑰本體只在建立時回傳一次，資料庫僅保存 SHA-256 雜湊；Prefix 用於查詢與辨識。
+type APIKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID   uint       `json:"company_id" gorm:"index"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix" gorm:"uniqueIndex"`
+	KeyHash     string     `json:"-"`
+	Permissions []string   `json:"permissions" gorm:"type:jsonb;serializer:json"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Usable 金鑰是否仍可使用（未撤銷且未過期）
+func (k APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// 建立 API Key 請求
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name"`
+	CompanyID   uint       `json:"company_id"`
+	Permissions []string   `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// 建立 API Key 的回應，Key 為完整金鑰，僅此一次
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// GenerateAPIKey 產生新的金鑰，格式為 fk_<prefix>_<secret>，回傳完整金鑰與 prefix
+func GenerateAPIKey() (key string, prefix string, err error) {
+	buf := make([]byte, 30)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(buf)
+	// prefix 只取英數字，避免與分隔用的底線混淆
+	idPart := strings.NewReplacer("-", "x", "_", "y").Replace(encoded[:8])
+	prefix = APIKeyPrefix + idPart
+	return prefix + "_" + encoded[8:], prefix, nil
+}
+
+// SplitAPIKey 由完整金鑰取出 prefix，格式不符時回傳 false
+func SplitAPIKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return "", false
+	}
+	idx := strings.Index(key[len(APIKeyPrefix):], "_")
+	if idx <= 0 {
+		return "", false
+	}
+	return key[:len(APIKeyPrefix)+idx], true
+}
+
+// HashAPIKey 計算金鑰的 SHA-256 雜湊（金鑰為高熵隨機值，不需 bcrypt）
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
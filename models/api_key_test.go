@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitAPIKey(t *testing.T) {
+	tests := []struct {
+		key        string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{"fk_abcd1234_secret", "fk_abcd1234", true},
+		{"fk_abcd1234_sec_ret", "fk_abcd1234", true},
+		{"fk_abcd1234_", "fk_abcd1234", true},
+		{"fk__secret", "", false},
+		{"fk_abcd1234", "", false},
+		{"fk_", "", false},
+		{"xx_abcd1234_secret", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			prefix, ok := SplitAPIKey(tt.key)
+			if prefix != tt.wantPrefix || ok != tt.wantOK {
+				t.Errorf("SplitAPIKey(%q) = (%q, %v), want (%q, %v)", tt.key, prefix, ok, tt.wantPrefix, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		key, prefix, err := GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(key, prefix+"_") || len(prefix) != len(APIKeyPrefix)+8 {
+			t.Fatalf("GenerateAPIKey() = (%q, %q)", key, prefix)
+		}
+		// 產生的金鑰必須能以 SplitAPIKey 取回相同的 prefix（secret 部分可能含底線）
+		if got, ok := SplitAPIKey(key); !ok || got != prefix {
+			t.Fatalf("SplitAPIKey(%q) = (%q, %v), want %q", key, got, ok, prefix)
+		}
+		if seen[key] {
+			t.Fatalf("duplicate key %q", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, tt := range tests {
+		if got := HashAPIKey(tt.key); got != tt.want {
+			t.Errorf("HashAPIKey(%q) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+	if HashAPIKey("fk_a_1") == HashAPIKey("fk_a_2") {
+		t.Error("different keys produced the same hash")
+	}
+}
+
+func TestAPIKeyUsable(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"無期限", APIKey{}, true},
+		{"尚未過期", APIKey{ExpiresAt: &future}, true},
+		{"已過期", APIKey{ExpiresAt: &past}, false},
+		{"到期時間當下", APIKey{ExpiresAt: &now}, false},
+		{"已撤銷", APIKey{RevokedAt: &past, ExpiresAt: &future}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Usable(now); got != tt.want {
+				t.Errorf("Usable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
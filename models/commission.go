@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// 佣金狀態
+const (
+	CommissionAccrued = "accrued"
+	CommissionPaid    = "paid"
+)
+
+// 佣金，開立發票時依訂單的業務與佣金比例（百分比，3 代表 3%）提列
+type Commission struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID    uint       `json:"company_id" gorm:"index"`
+	AgentID      uint       `json:"agent_id" gorm:"index"`
+	InvoiceID    uint       `json:"invoice_id" gorm:"uniqueIndex"`
+	SalesOrderID uint       `json:"sales_order_id"`
+	CustomerID   uint       `json:"customer_id"`
+	Period       string     `json:"period" gorm:"index"` // 發票所屬月份 YYYY-MM
+	CurrencyCode string     `json:"currency_code"`
+	BaseAmount   float64    `json:"base_amount"`
+	Rate         float64    `json:"rate"`
+	Amount       float64    `json:"amount"`
+	Status       string     `json:"status"`
+	PaidAt       *time.Time `json:"paid_at"`
+	PaidBy       string     `json:"paid_by"`
+	PaymentRef   string     `json:"payment_ref"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// 佣金對帳單：業務 + 月份 + 幣別
+type CommissionStatement struct {
+	AgentID      uint         `json:"agent_id"`
+	AgentName    string       `json:"agent_name"`
+	Period       string       `json:"period"`
+	CurrencyCode string       `json:"currency_code"`
+	Accrued      float64      `json:"accrued"`
+	Paid         float64      `json:"paid"`
+	Outstanding  float64      `json:"outstanding"`
+	Commissions  []Commission `json:"commissions"`
+}
+
+// 依公司彙總的佣金報表列
+type CommissionSummary struct {
+	CompanyID    uint    `json:"company_id"`
+	CompanyName  string  `json:"company_name"`
+	AgentID      uint    `json:"agent_id"`
+	AgentName    string  `json:"agent_name"`
+	CurrencyCode string  `json:"currency_code"`
+	Accrued      float64 `json:"accrued"`
+	Paid         float64 `json:"paid"`
+	Outstanding  float64 `json:"outstanding"`
+}
+
+// 標記佣金已付款請求：指定 commission_ids，或以 agent_id + period + currency_code 整張對帳單付款
+type PayCommissionsRequest struct {
+	CommissionIDs []uint `json:"commission_ids"`
+	AgentID       uint   `json:"agent_id"`
+	Period        string `json:"period"`
+	CurrencyCode  string `json:"currency_code"`
+	PaymentRef    string `json:"payment_ref"`
+}
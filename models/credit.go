@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// 客戶在各接單公司的信用額度
+type CreditLimit struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CustomerID   uint      `json:"customer_id" gorm:"uniqueIndex:idx_credit_limit"`
+	CompanyID    uint      `json:"company_id" gorm:"uniqueIndex:idx_credit_limit"`
+	CurrencyCode string    `json:"currency_code"`
+	LimitAmount  float64   `json:"limit_amount"`
+	Remarks      string    `json:"remarks"`
+	UpdatedBy    string    `json:"updated_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 新增 / 修改信用額度請求（同一客戶 + 公司僅一筆）
+type CreditLimitRequest struct {
+	CustomerID   uint    `json:"customer_id"`
+	CompanyID    uint    `json:"company_id"`
+	CurrencyCode string  `json:"currency_code"`
+	LimitAmount  float64 `json:"limit_amount"`
+	Remarks      string  `json:"remarks"`
+}
+
+// 信用曝險，金額皆已換算為額度幣別
+type CreditExposure struct {
+	CustomerID     uint    `json:"customer_id"`
+	CompanyID      uint    `json:"company_id"`
+	CurrencyCode   string  `json:"currency_code"`
+	LimitAmount    float64 `json:"limit_amount"`
+	OpenOrders     float64 `json:"open_orders"`     // 已確認未開立發票的訂單金額
+	UnpaidInvoices float64 `json:"unpaid_invoices"` // 發票未收款金額
+	Exposure       float64 `json:"exposure"`
+	Available      float64 `json:"available"`
+}
+
+// 信用凍結狀態
+const (
+	CreditHoldHeld     = "held"
+	CreditHoldReleased = "released"
+)
+
+// 訂單確認時超出信用額度而凍結的紀錄
+type CreditHold struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	SalesOrderID  uint       `json:"sales_order_id" gorm:"index"`
+	CustomerID    uint       `json:"customer_id"`
+	CompanyID     uint       `json:"company_id" gorm:"index"`
+	CurrencyCode  string     `json:"currency_code"`
+	LimitAmount   float64    `json:"limit_amount"`
+	Exposure      float64    `json:"exposure"`     // 不含本訂單
+	OrderAmount   float64    `json:"order_amount"` // 本訂單金額（額度幣別）
+	Status        string     `json:"status"`
+	HeldBy        string     `json:"held_by"`
+	HeldAt        time.Time  `json:"held_at"`
+	ReleasedBy    string     `json:"released_by"`
+	ReleasedAt    *time.Time `json:"released_at"`
+	ReleaseReason string     `json:"release_reason"`
+}
+
+// 解除信用凍結請求
+type ReleaseCreditHoldRequest struct {
+	Reason string `json:"reason"`
+}
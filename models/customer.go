@@ -11,6 +11,7 @@ type Customer struct {
 	CreatedAt         time.Time                 `json:"created_at"`
 	UpdatedAt         time.Time                 `json:"updated_at"`
 	TransactionTerms  []CustomerTransactionTerm `json:"transaction_terms,omitempty" gorm:"-"`
+	Sites             []CustomerSite            `json:"sites,omitempty" gorm:"-"`
 }
 
 // 客戶交易條件
@@ -20,9 +21,14 @@ type CustomerTransactionTerm struct {
 	CompanyID          uint    `json:"company_id" binding:"required"`
 	Incoterm           string  `json:"incoterm"`
 	CurrencyCode       string  `json:"currency_code"`
-	CommissionRate     float64 `json:"commission_rate"`
+	CommissionRate     float64 `json:"commission_rate"` // 百分比，3 代表 3%
+	AgentID            *uint   `json:"agent_id"`        // 負責業務 / 代理商，佣金歸屬對象
 	ExportPort         string  `json:"export_port"`
 	DestinationCountry string  `json:"destination_country"`
+	SoldToSiteID       *uint   `json:"sold_to_site_id"` // 預設下單據點，null 代表集團客戶本身
+	ShipToSiteID       *uint   `json:"ship_to_site_id"` // 預設送貨據點
+	BillToSiteID       *uint   `json:"bill_to_site_id"` // 預設請款據點
+	ShippingMark       string  `json:"shipping_mark"`   // 外箱嘜頭範本，可用 {customer_po}、{carton_no} 等代入欄位
 	IsPrimary          bool    `json:"is_primary"`
 	Remarks            string  `json:"remarks"`
 }
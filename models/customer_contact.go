@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// 客戶聯絡人
+type CustomerContact struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CustomerID uint      `json:"customer_id" gorm:"index"`
+	SiteID     *uint     `json:"site_id"` // 所屬據點，null 代表集團客戶
+	Name       string    `json:"name"`
+	Title      string    `json:"title"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone"`
+	Language   string    `json:"language"`
+	IsPrimary  bool      `json:"is_primary"` // 每個客戶僅一位主要聯絡人
+	Remarks    string    `json:"remarks"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// 往來紀錄類型
+const (
+	ActivityCall  = "call"
+	ActivityVisit = "visit"
+	ActivityEmail = "email"
+	ActivityNote  = "note"
+)
+
+// ActivityTypes 所有往來紀錄類型
+var ActivityTypes = []string{ActivityCall, ActivityVisit, ActivityEmail, ActivityNote}
+
+// 客戶往來紀錄，歸屬於記錄當下的公司
+type CustomerActivity struct {
+	ID          uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	CustomerID  uint                 `json:"customer_id" gorm:"index"`
+	CompanyID   uint                 `json:"company_id" gorm:"index"`
+	ContactID   *uint                `json:"contact_id"`
+	UserID      uint                 `json:"user_id" gorm:"index"` // 負責業務
+	Type        string               `json:"type"`
+	Subject     string               `json:"subject"`
+	Content     string               `json:"content"`
+	OccurredAt  time.Time            `json:"occurred_at" gorm:"index"`
+	CreatedBy   string               `json:"created_by"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	Attachments []ActivityAttachment `json:"attachments,omitempty" gorm:"foreignKey:ActivityID"`
+}
+
+// 往來紀錄附件，檔案內容存於資料庫
+type ActivityAttachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ActivityID  uint      `json:"activity_id" gorm:"index"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Data        []byte    `json:"-"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 新增往來紀錄請求；user_id 未指定時為目前登入者
+type CustomerActivityRequest struct {
+	ContactID  *uint      `json:"contact_id"`
+	UserID     *uint      `json:"user_id"`
+	Type       string     `json:"type"`
+	Subject    string     `json:"subject"`
+	Content    string     `json:"content"`
+	OccurredAt *time.Time `json:"occurred_at"`
+}
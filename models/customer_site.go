@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// 客戶據點角色
+const (
+	SiteRoleSoldTo      = "sold_to"
+	SiteRoleShipTo      = "ship_to"
+	SiteRoleBillTo      = "bill_to"
+	SiteRoleEndCustomer = "end_customer"
+)
+
+// SiteRoles 所有可指定的據點角色
+var SiteRoles = []string{SiteRoleSoldTo, SiteRoleShipTo, SiteRoleBillTo, SiteRoleEndCustomer}
+
+// 客戶據點 / 分公司，隸屬於集團客戶
+type CustomerSite struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CustomerID   uint      `json:"customer_id" gorm:"uniqueIndex:idx_customer_site_code"`
+	SiteCode     string    `json:"site_code" gorm:"uniqueIndex:idx_customer_site_code"`
+	Name         string    `json:"name"`
+	Roles        []string  `json:"roles" gorm:"type:jsonb;serializer:json"`
+	AddressLine1 string    `json:"address_line1"`
+	AddressLine2 string    `json:"address_line2"`
+	City         string    `json:"city"`
+	State        string    `json:"state"`
+	PostalCode   string    `json:"postal_code"`
+	Country      string    `json:"country"`
+	TaxID        string    `json:"tax_id"`
+	ContactName  string    `json:"contact_name"`
+	ContactPhone string    `json:"contact_phone"`
+	ContactEmail string    `json:"contact_email"`
+	IsActive     bool      `json:"is_active"`
+	Remarks      string    `json:"remarks"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// HasRole 據點是否具有指定角色
+func (s CustomerSite) HasRole(role string) bool {
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
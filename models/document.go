@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// 可產生 PDF 的單據類型
+const (
+	DocQuotation         = "quotation"              // 來源：報價單
+	DocInvoice           = "invoice"                // 來源：發票（依發票類型顯示形式發票 / 商業發票）
+	DocPackingList       = "packing_list"           // 來源：訂單出貨
+	DocOrderConfirmation = "order_confirmation"     // 來源：銷售訂單
+	DocInspectionCert    = "inspection_certificate" // 來源：出貨明細，EN 10204 3.1 檢驗證明書
+)
+
+// DocumentTypes 所有單據類型
+var DocumentTypes = []string{DocQuotation, DocInvoice, DocPackingList, DocOrderConfirmation, DocInspectionCert}
+
+// 公司單據範本；DocType 為空字串時為該公司所有單據的預設範本
+type DocumentTemplate struct {
+	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID       uint      `json:"company_id" gorm:"uniqueIndex:idx_document_template"`
+	DocType         string    `json:"doc_type" gorm:"uniqueIndex:idx_document_template"`
+	Language        string    `json:"language"`     // 空白時沿用公司語言
+	CompanyName     string    `json:"company_name"` // 空白時沿用公司名稱
+	Address         string    `json:"address"`      // 可多行
+	Contact         string    `json:"contact"`      // 電話、Email 等
+	TaxID           string    `json:"tax_id"`
+	Footer          string    `json:"footer"`
+	Logo            []byte    `json:"-"`
+	LogoContentType string    `json:"logo_content_type"`
+	UpdatedBy       string    `json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// 新增 / 修改單據範本請求（Logo 另以上傳 API 設定）
+type DocumentTemplateRequest struct {
+	CompanyID   uint   `json:"company_id"`
+	DocType     string `json:"doc_type"`
+	Language    string `json:"language"`
+	CompanyName string `json:"company_name"`
+	Address     string `json:"address"`
+	Contact     string `json:"contact"`
+	TaxID       string `json:"tax_id"`
+	Footer      string `json:"footer"`
+}
+
+// 已產生的單據版本；保存產生時的 PDF 供日後原樣下載，排版輸入快照則保留產生當時的內容
+type RenderedDocument struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID  uint      `json:"company_id" gorm:"index"`
+	DocType    string    `json:"doc_type" gorm:"uniqueIndex:idx_rendered_document"`
+	SourceID   uint      `json:"source_id" gorm:"uniqueIndex:idx_rendered_document"`
+	Version    int       `json:"version" gorm:"uniqueIndex:idx_rendered_document"`
+	DocumentNo string    `json:"document_no"`
+	FileName   string    `json:"file_name"`
+	Language   string    `json:"language"`
+	Snapshot   []byte    `json:"-"`        // docrender.Template + docrender.Document 的 JSON
+	PDF        []byte    `json:"-"`        // 產生時的 PDF；排版程式更新後仍可下載原始文件
+	Checksum   string    `json:"checksum"` // PDF 的 SHA-256
+	Size       int       `json:"size"`
+	RenderedBy string    `json:"rendered_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// 匯率：1 單位 FromCurrency = Rate 單位 ToCurrency，自 EffectiveDate 起生效
+type ExchangeRate struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FromCurrency  string    `json:"from_currency" gorm:"uniqueIndex:idx_exchange_rate"`
+	ToCurrency    string    `json:"to_currency" gorm:"uniqueIndex:idx_exchange_rate"`
+	EffectiveDate time.Time `json:"effective_date" gorm:"type:date;uniqueIndex:idx_exchange_rate"`
+	Rate          float64   `json:"rate"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+// 庫存異動類型，同時作為公司流水號的單據類型
+const (
+	StockReceipt    = "stock_receipt"    // 入庫
+	StockIssue      = "stock_issue"      // 出庫
+	StockTransfer   = "stock_transfer"   // 調撥（可跨集團內公司），產生一出一入兩筆異動
+	StockAdjustment = "stock_adjustment" // 盤點 / 報廢等調整，需填原因
+)
+
+// 倉庫，隸屬於公司（工廠）
+type Warehouse struct {
+	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID uint           `json:"company_id" gorm:"uniqueIndex:idx_warehouse_code"`
+	Code      string         `json:"code" gorm:"uniqueIndex:idx_warehouse_code"`
+	Name      string         `json:"name"`
+	Address   string         `json:"address"`
+	IsActive  bool           `json:"is_active"`
+	Remarks   string         `json:"remarks"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Bins      []WarehouseBin `json:"bins,omitempty" gorm:"foreignKey:WarehouseID"`
+}
+
+// 儲位
+type WarehouseBin struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	WarehouseID uint      `json:"warehouse_id" gorm:"uniqueIndex:idx_warehouse_bin_code"`
+	Code        string    `json:"code" gorm:"uniqueIndex:idx_warehouse_bin_code"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 庫存餘額：倉庫 + 儲位 + 產品規格 + 批號 一筆；BinID 為 0 代表未指定儲位
+type StockBalance struct {
+	ID                     uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID              uint      `json:"company_id" gorm:"index"`
+	WarehouseID            uint      `json:"warehouse_id" gorm:"uniqueIndex:idx_stock_balance"`
+	BinID                  uint      `json:"bin_id" gorm:"uniqueIndex:idx_stock_balance"`
+	ProductSpecificationID uint      `json:"product_specification_id" gorm:"uniqueIndex:idx_stock_balance;index"`
+	LotNo                  string    `json:"lot_no" gorm:"uniqueIndex:idx_stock_balance"`
+	Unit                   string    `json:"unit"`
+	Quantity               float64   `json:"quantity"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// 庫存異動紀錄；Quantity 入庫為正、出庫為負
+type StockMovement struct {
+	ID                     uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	DocumentNo             string    `json:"document_no" gorm:"index"`
+	Type                   string    `json:"type"`
+	CompanyID              uint      `json:"company_id" gorm:"index"`
+	WarehouseID            uint      `json:"warehouse_id" gorm:"index"`
+	BinID                  uint      `json:"bin_id"`
+	ProductSpecificationID uint      `json:"product_specification_id" gorm:"index"`
+	LotNo                  string    `json:"lot_no" gorm:"index"`
+	Unit                   string    `json:"unit"`
+	Quantity               float64   `json:"quantity"`
+	BalanceAfter           float64   `json:"balance_after"`                    // 異動後該餘額列的數量
+	ShipmentID             *uint     `json:"shipment_id" gorm:"index"`         // 出貨出庫時對應的訂單出貨
+	SalesOrderLineID       *uint     `json:"sales_order_line_id" gorm:"index"` // 出貨出庫時對應的訂單明細
+	Reason                 string    `json:"reason"`
+	Reference              string    `json:"reference"`
+	MovedAt                time.Time `json:"moved_at"`
+	CreatedBy              string    `json:"created_by"`
+	CreatedAt              time.Time `json:"created_at"`
+}
+
+// 入庫 / 出庫 / 調撥 / 調整請求
+//   - 入庫、出庫、調整：warehouse_id (+ bin_id)
+//   - 調撥：warehouse_id (+ bin_id) 為調出，to_warehouse_id (+ to_bin_id) 為調入
+//   - 調整：quantity 為增減量（可為負），reason 必填
+type StockPostingRequest struct {
+	WarehouseID   uint               `json:"warehouse_id"`
+	BinID         uint               `json:"bin_id"`
+	ToWarehouseID uint               `json:"to_warehouse_id"`
+	ToBinID       uint               `json:"to_bin_id"`
+	ShipmentID    *uint              `json:"shipment_id"`
+	Reason        string             `json:"reason"`
+	Reference     string             `json:"reference"`
+	MovedAt       *time.Time         `json:"moved_at"`
+	Lines         []StockLineRequest `json:"lines"`
+}
+
+// 出貨出庫時 sales_order_line_id 為出貨中的訂單明細；出貨中僅一筆相同產品規格的明細時可省略
+type StockLineRequest struct {
+	ProductSpecificationID uint    `json:"product_specification_id"`
+	SalesOrderLineID       *uint   `json:"sales_order_line_id"`
+	LotNo                  string  `json:"lot_no"`
+	Quantity               float64 `json:"quantity"`
+	Unit                   string  `json:"unit"`
+}
+
+// 可承諾量 (ATP)：現有庫存扣除已確認訂單尚未出貨的數量
+type StockAvailability struct {
+	CompanyID              uint    `json:"company_id"`
+	ProductSpecificationID uint    `json:"product_specification_id"`
+	Unit                   string  `json:"unit"`
+	OnHand                 float64 `json:"on_hand"`
+	Committed              float64 `json:"committed"`
+	Available              float64 `json:"available"`
+}
+
+// 訂單明細的可承諾量檢查
+type OrderLineAvailability struct {
+	SalesOrderLineID       uint    `json:"sales_order_line_id"`
+	LineNo                 int     `json:"line_no"`
+	ProductSpecificationID uint    `json:"product_specification_id"`
+	Unit                   string  `json:"unit"`
+	Required               float64 `json:"required"`  // 尚未出貨數量
+	Available              float64 `json:"available"` // 扣除其他訂單後的可承諾量
+	Shortage               float64 `json:"shortage"`
+}
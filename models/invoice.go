@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// 發票類型
+const (
+	InvoiceProforma   = "proforma"   // 形式發票：出貨前依訂單開立，供客戶預付款，不計入應收
+	InvoiceCommercial = "commercial" // 商業發票：依出貨開立，計入應收與佣金
+)
+
+// 發票狀態
+const (
+	InvoiceIssued        = "issued"
+	InvoicePartiallyPaid = "partially_paid"
+	InvoicePaid          = "paid"
+	InvoiceCancelled     = "cancelled"
+)
+
+// 發票，單號依開立公司各自編號
+type Invoice struct {
+	ID             uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	Type           string           `json:"type"`
+	InvoiceNo      string           `json:"invoice_no" gorm:"uniqueIndex:idx_invoice_no"`
+	CompanyID      uint             `json:"company_id" gorm:"index;uniqueIndex:idx_invoice_no"`
+	CustomerID     uint             `json:"customer_id" gorm:"index"`
+	BillToSiteID   *uint            `json:"bill_to_site_id"`
+	SalesOrderID   uint             `json:"sales_order_id" gorm:"index"`
+	ShipmentID     *uint            `json:"shipment_id" gorm:"index"` // 商業發票對應的出貨，一次出貨僅一張有效發票
+	InvoiceDate    time.Time        `json:"invoice_date"`
+	DueDate        *time.Time       `json:"due_date"`
+	CurrencyCode   string           `json:"currency_code"`
+	SubtotalAmount float64          `json:"subtotal_amount"`
+	TaxRate        float64          `json:"tax_rate"` // 百分比，5 代表 5%
+	TaxAmount      float64          `json:"tax_amount"`
+	TotalAmount    float64          `json:"total_amount"`
+	PaidAmount     float64          `json:"paid_amount"` // 已收款金額，商業發票未收部分計入客戶信用曝險
+	Status         string           `json:"status"`
+	Remarks        string           `json:"remarks"`
+	CancelledAt    *time.Time       `json:"cancelled_at"`
+	CancelledBy    string           `json:"cancelled_by"`
+	CancelReason   string           `json:"cancel_reason"`
+	CreatedBy      string           `json:"created_by"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	Lines          []InvoiceLine    `json:"lines" gorm:"foreignKey:InvoiceID"`
+	Payments       []InvoicePayment `json:"payments,omitempty" gorm:"foreignKey:InvoiceID"`
+}
+
+// OutstandingAmount 未收款金額
+func (i Invoice) OutstandingAmount() float64 {
+	return i.TotalAmount - i.PaidAmount
+}
+
+// 發票明細
+type InvoiceLine struct {
+	ID               uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	InvoiceID        uint    `json:"invoice_id" gorm:"index"`
+	LineNo           int     `json:"line_no"`
+	SalesOrderLineID uint    `json:"sales_order_line_id"`
+	Description      string  `json:"description"`
+	Quantity         float64 `json:"quantity"`
+	Unit             string  `json:"unit"`
+	UnitPrice        float64 `json:"unit_price"`
+	Amount           float64 `json:"amount"`
+}
+
+// 發票收款紀錄
+type InvoicePayment struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	InvoiceID uint      `json:"invoice_id" gorm:"index"`
+	Amount    float64   `json:"amount"`
+	PaidAt    time.Time `json:"paid_at"`
+	Reference string    `json:"reference"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 開立發票請求：商業發票需指定 shipment_id；形式發票依訂單全部明細開立
+type InvoiceRequest struct {
+	Type        string     `json:"type"` // proforma / commercial，預設 commercial
+	ShipmentID  uint       `json:"shipment_id"`
+	InvoiceDate *time.Time `json:"invoice_date"`
+	DueDate     *time.Time `json:"due_date"`
+	TaxRate     float64    `json:"tax_rate"`
+	Remarks     string     `json:"remarks"`
+}
+
+// 登錄收款請求
+type InvoicePaymentRequest struct {
+	Amount    float64    `json:"amount"`
+	PaidAt    *time.Time `json:"paid_at"`
+	Reference string     `json:"reference"`
+}
+
+// 作廢發票請求
+type CancelInvoiceRequest struct {
+	Reason string `json:"reason"`
+}
+
+// 各公司單據流水號，依類型與年度分別編號
+type DocumentSequence struct {
+	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID uint   `json:"company_id" gorm:"uniqueIndex:idx_document_sequence"`
+	DocType   string `json:"doc_type" gorm:"uniqueIndex:idx_document_sequence"`
+	Year      int    `json:"year" gorm:"uniqueIndex:idx_document_sequence"`
+	Prefix    string `json:"prefix"`
+	NextNo    int    `json:"next_no"`
+}
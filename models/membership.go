@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// UserCompanyMembership 使用者可存取的其他公司，以及在該公司的角色
+// users.company_id / role_id 為帳號的主要公司與角色，不需另建 membership
+type UserCompanyMembership struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_company"`
+	CompanyID uint      `json:"company_id" gorm:"uniqueIndex:idx_user_company"`
+	RoleID    uint      `json:"role_id"`
+	IsDefault bool      `json:"is_default"` // 登入時預設進入的公司
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 使用者可切換的公司（含主要公司）
+type CompanyMembershipInfo struct {
+	CompanyID   uint   `json:"company_id"`
+	CompanyName string `json:"company_name"`
+	RoleID      uint   `json:"role_id"`
+	Role        string `json:"role"`
+	IsPrimary   bool   `json:"is_primary"`
+	IsDefault   bool   `json:"is_default"`
+	IsActive    bool   `json:"is_active"` // 是否為目前 Token 所在的公司
+}
+
+// 新增或更新帳號的公司 membership 請求
+type MembershipRequest struct {
+	CompanyID uint   `json:"company_id"`
+	Role      string `json:"role"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// 切換公司請求
+type SwitchCompanyRequest struct {
+	CompanyID uint `json:"company_id"`
+	// 切換後的角色要求雙因素驗證時，需提供驗證器 App 的驗證碼
+	Code string `json:"code"`
+}
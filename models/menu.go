@@ -1,11 +1,32 @@
 package models
 
 type Menu struct {
-    ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
-    Name     string `json:"name"`
-    Path     string `json:"path"`
-    Icon     string `json:"icon"`
-    ParentID *uint  `json:"parent_id"`  // 支援 null
-    OrderNo  int    `json:"order_no"`
-    IsActive bool   `json:"is_active"`
+	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Icon     string `json:"icon"`
+	ParentID *uint  `json:"parent_id"` // 支援 null
+	OrderNo  int    `json:"order_no"`
+	IsActive bool   `json:"is_active"`
+	// 顯示此選單所需的權限，全部具備才會出現在使用者側欄
+	RequiredPermissions []string `json:"required_permissions" gorm:"type:jsonb;serializer:json"`
+	Children            []Menu   `json:"children,omitempty" gorm:"-"`
+}
+
+// 拖拉排序請求的單一項目，項目在陣列中的先後即為同層的顯示順序
+type MenuOrderItem struct {
+	ID       uint  `json:"id"`
+	ParentID *uint `json:"parent_id"`
+}
+
+// 選單與角色權限不一致的項目
+type MenuConsistencyIssue struct {
+	MenuID   uint   `json:"menu_id"`
+	MenuName string `json:"menu_name"`
+	Path     string `json:"path"`
+	RoleID   uint   `json:"role_id,omitempty"`
+	RoleName string `json:"role_name,omitempty"`
+	Problem  string `json:"problem"`
+	// 相關的權限代碼或 API 路由
+	Details []string `json:"details"`
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密碼政策，API 與管理工具共用
+const (
+	MinPasswordLength = 8
+	PasswordHashCost  = bcrypt.DefaultCost
+
+	// 連續登入失敗達 MaxFailedLogins 次即鎖定帳號 LockoutDuration
+	MaxFailedLogins = 5
+	LockoutDuration = 15 * time.Minute
+)
+
+var ErrPasswordTooShort = errors.New("密碼長度至少需 8 個字元")
+
+// HashPassword 檢查密碼政策後以 bcrypt 加密
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), PasswordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// CheckPassword 比對密碼與雜湊
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// IsLocked 帳號目前是否因登入失敗過多而鎖定
+func (u User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
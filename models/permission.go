@@ -0,0 +1,303 @@
+package models
+
+// 權限代碼（格式為 資源:動作）
+const (
+	PermCompaniesRead    = "companies:read"
+	PermCompaniesWrite   = "companies:write"
+	PermRolesRead        = "roles:read"
+	PermRolesWrite       = "roles:write"
+	PermMenusRead        = "menus:read"
+	PermMenusWrite       = "menus:write"
+	PermAccountsRead     = "accounts:read"
+	PermAccountsWrite    = "accounts:write"
+	PermCustomersRead    = "customers:read"
+	PermCustomersWrite   = "customers:write"
+	PermProductsRead     = "products:read"
+	PermProductsWrite    = "products:write"
+	PermSalesRead        = "sales:read"
+	PermSalesWrite       = "sales:write"
+	PermInvoicesRead     = "invoices:read"
+	PermInvoicesWrite    = "invoices:write"
+	PermCommissionsRead  = "commissions:read"
+	PermCommissionsWrite = "commissions:write"
+	PermCreditRead       = "credit:read"
+	PermCreditWrite      = "credit:write"
+	PermCreditApprove    = "credit:approve"
+	PermDocumentsRead    = "documents:read"
+	PermDocumentsWrite   = "documents:write"
+	PermInventoryRead    = "inventory:read"
+	PermInventoryWrite   = "inventory:write"
+	PermInventoryAdjust  = "inventory:adjust"
+	PermTraceRead        = "traceability:read"
+	PermTraceWrite       = "traceability:write"
+	PermQualityRead      = "quality:read"
+	PermQualityWrite     = "quality:write"
+)
+
+// PermissionInfo 權限說明，供前端設定角色權限時顯示
+type PermissionInfo struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// PermissionCatalog 系統中所有可授予的權限
+var PermissionCatalog = []PermissionInfo{
+	{PermCompaniesRead, "查詢公司"},
+	{PermCompaniesWrite, "維護公司"},
+	{PermRolesRead, "查詢角色"},
+	{PermRolesWrite, "維護角色與角色選單"},
+	{PermMenusRead, "查詢選單"},
+	{PermMenusWrite, "維護選單"},
+	{PermAccountsRead, "查詢帳號"},
+	{PermAccountsWrite, "維護帳號"},
+	{PermCustomersRead, "查詢客戶"},
+	{PermCustomersWrite, "維護客戶與交易條件"},
+	{PermProductsRead, "查詢產品定義"},
+	{PermProductsWrite, "維護產品定義"},
+	{PermSalesRead, "查詢報價單與訂單"},
+	{PermSalesWrite, "維護報價單與訂單、出貨"},
+	{PermInvoicesRead, "查詢發票"},
+	{PermInvoicesWrite, "開立、作廢發票與登錄收款"},
+	{PermCommissionsRead, "查詢佣金與對帳單"},
+	{PermCommissionsWrite, "維護業務、佣金付款"},
+	{PermCreditRead, "查詢信用額度與曝險"},
+	{PermCreditWrite, "維護信用額度與匯率"},
+	{PermCreditApprove, "核准解除信用凍結"},
+	{PermDocumentsRead, "查詢與下載單據 PDF"},
+	{PermDocumentsWrite, "產生單據 PDF、維護單據範本"},
+	{PermInventoryRead, "查詢倉庫、庫存與可承諾量"},
+	{PermInventoryWrite, "維護倉庫儲位、入庫、出庫與調撥"},
+	{PermInventoryAdjust, "庫存調整"},
+	{PermTraceRead, "查詢爐號、製令、製程批次、成品批號與追溯"},
+	{PermTraceWrite, "維護爐號、製令、製程批次與成品批號"},
+	{PermQualityRead, "查詢材質證明、檢驗結果與檢驗證明書"},
+	{PermQualityWrite, "維護材質證明與檢驗結果"},
+}
+
+// RoutePermissions 各 API 路由（方法 + 路由樣板）所需的權限。
+// 權限中介軟體與選單一致性檢查共用此表；未列出的路由只需登入即可呼叫。
+var RoutePermissions = map[string]string{
+	// 公司
+	"GET /api/companies":        PermCompaniesRead,
+	"GET /api/companies/tree":   PermCompaniesRead,
+	"GET /api/companies/:id":    PermCompaniesRead,
+	"POST /api/companies":       PermCompaniesWrite,
+	"PUT /api/companies/:id":    PermCompaniesWrite,
+	"PATCH /api/companies/:id":  PermCompaniesWrite,
+	"DELETE /api/companies/:id": PermCompaniesWrite,
+
+	// 角色與角色選單
+	"GET /api/roles":                      PermRolesRead,
+	"GET /api/roles/:id":                  PermRolesRead,
+	"POST /api/roles":                     PermRolesWrite,
+	"PUT /api/roles/:id":                  PermRolesWrite,
+	"PATCH /api/roles/:id":                PermRolesWrite,
+	"DELETE /api/roles/:id":               PermRolesWrite,
+	"POST /api/roles/:id/clone":           PermRolesWrite,
+	"GET /api/roles/:id/menus":            PermRolesRead,
+	"PUT /api/roles/:id/menus":            PermRolesWrite,
+	"PATCH /api/roles/:id/menus/:menuId":  PermRolesWrite,
+	"DELETE /api/roles/:id/menus/:menuId": PermRolesWrite,
+	"GET /api/roles/:id/menus/history":    PermRolesRead,
+	"GET /api/permissions":                PermRolesRead,
+
+	// 選單
+	"GET /api/menus":             PermMenusRead,
+	"GET /api/menus/tree":        PermMenusRead,
+	"GET /api/menus/consistency": PermMenusRead,
+	"GET /api/menus/:id":         PermMenusRead,
+	"POST /api/menus":            PermMenusWrite,
+	"PUT /api/menus/order":       PermMenusWrite,
+	"PUT /api/menus/:id":         PermMenusWrite,
+	"PATCH /api/menus/:id":       PermMenusWrite,
+	"DELETE /api/menus/:id":      PermMenusWrite,
+
+	// 帳號
+	"GET /api/manage-accounts":        PermAccountsRead,
+	"POST /api/manage-accounts":       PermAccountsWrite,
+	"PUT /api/manage-accounts/:id":    PermAccountsWrite,
+	"PATCH /api/manage-accounts/:id":  PermAccountsWrite,
+	"DELETE /api/manage-accounts/:id": PermAccountsWrite,
+
+	"GET /api/manage-accounts/:id/companies":               PermAccountsRead,
+	"PUT /api/manage-accounts/:id/companies":               PermAccountsWrite,
+	"DELETE /api/manage-accounts/:id/companies/:companyId": PermAccountsWrite,
+	"DELETE /api/manage-accounts/:id/2fa":                  PermAccountsWrite,
+	"GET /api/manage-accounts/api-keys":                    PermAccountsRead,
+	"POST /api/manage-accounts/api-keys":                   PermAccountsWrite,
+	"DELETE /api/manage-accounts/api-keys/:keyId":          PermAccountsWrite,
+
+	// 客戶與交易條件
+	"GET /api/customers":                                                    PermCustomersRead,
+	"GET /api/customers/:id":                                                PermCustomersRead,
+	"POST /api/customers":                                                   PermCustomersWrite,
+	"PUT /api/customers/:id":                                                PermCustomersWrite,
+	"PATCH /api/customers/:id":                                              PermCustomersWrite,
+	"DELETE /api/customers/:id":                                             PermCustomersWrite,
+	"GET /api/customers/:id/sites":                                          PermCustomersRead,
+	"POST /api/customers/:id/sites":                                         PermCustomersWrite,
+	"GET /api/customer-sites/:siteId":                                       PermCustomersRead,
+	"PATCH /api/customer-sites/:siteId":                                     PermCustomersWrite,
+	"DELETE /api/customer-sites/:siteId":                                    PermCustomersWrite,
+	"GET /api/customers/:id/contacts":                                       PermCustomersRead,
+	"POST /api/customers/:id/contacts":                                      PermCustomersWrite,
+	"PATCH /api/customer-contacts/:contactId":                               PermCustomersWrite,
+	"DELETE /api/customer-contacts/:contactId":                              PermCustomersWrite,
+	"GET /api/customers/:id/activities":                                     PermCustomersRead,
+	"POST /api/customers/:id/activities":                                    PermCustomersWrite,
+	"GET /api/customer-activities":                                          PermCustomersRead,
+	"GET /api/customer-activities/:activityId":                              PermCustomersRead,
+	"PUT /api/customer-activities/:activityId":                              PermCustomersWrite,
+	"DELETE /api/customer-activities/:activityId":                           PermCustomersWrite,
+	"POST /api/customer-activities/:activityId/attachments":                 PermCustomersWrite,
+	"GET /api/customer-activities/:activityId/attachments/:attachmentId":    PermCustomersRead,
+	"DELETE /api/customer-activities/:activityId/attachments/:attachmentId": PermCustomersWrite,
+	"GET /api/customers/:id/transaction-terms":                              PermCustomersRead,
+	"POST /api/customers/:id/transaction-terms":                             PermCustomersWrite,
+	"PUT /api/customer-transaction-terms/:termId":                           PermCustomersWrite,
+	"PATCH /api/customer-transaction-terms/:termId":                         PermCustomersWrite,
+	"DELETE /api/customer-transaction-terms/:termId":                        PermCustomersWrite,
+	"GET /api/customer-transaction-terms/:termId/price-lists":               PermCustomersRead,
+	"POST /api/customer-transaction-terms/:termId/price-lists":              PermCustomersWrite,
+	"GET /api/price-lists/:id":                                              PermCustomersRead,
+	"PUT /api/price-lists/:id":                                              PermCustomersWrite,
+	"DELETE /api/price-lists/:id":                                           PermCustomersWrite,
+
+	// 產品定義
+	"GET /api/definitions/product-categories":       PermProductsRead,
+	"POST /api/definitions/product-categories":      PermProductsWrite,
+	"PATCH /api/definitions/product-categories/:id": PermProductsWrite,
+
+	// 報價單與訂單
+	"GET /api/price-resolution":              PermSalesRead,
+	"GET /api/quotations":                    PermSalesRead,
+	"GET /api/quotations/:id":                PermSalesRead,
+	"POST /api/quotations":                   PermSalesWrite,
+	"PUT /api/quotations/:id":                PermSalesWrite,
+	"PUT /api/quotations/:id/status":         PermSalesWrite,
+	"DELETE /api/quotations/:id":             PermSalesWrite,
+	"GET /api/sales-orders":                  PermSalesRead,
+	"GET /api/sales-orders/:id":              PermSalesRead,
+	"GET /api/sales-orders/:id/confirmation": PermSalesRead,
+	"POST /api/sales-orders":                 PermSalesWrite,
+	"PUT /api/sales-orders/:id":              PermSalesWrite,
+	"DELETE /api/sales-orders/:id":           PermSalesWrite,
+	"POST /api/sales-orders/:id/confirm":     PermSalesWrite,
+	"POST /api/sales-orders/:id/cancel":      PermSalesWrite,
+	"POST /api/sales-orders/:id/close":       PermSalesWrite,
+	"POST /api/sales-orders/:id/shipments":   PermSalesWrite,
+
+	// 外銷出貨、包裝與嘜頭
+	"GET /api/shipments":                           PermSalesRead,
+	"GET /api/shipments/:id":                       PermSalesRead,
+	"PATCH /api/shipments/:id":                     PermSalesWrite,
+	"GET /api/shipments/:id/packing-list":          PermSalesRead,
+	"GET /api/shipments/:id/shipping-marks":        PermSalesRead,
+	"POST /api/shipments/:id/containers":           PermSalesWrite,
+	"PATCH /api/shipment-containers/:containerId":  PermSalesWrite,
+	"DELETE /api/shipment-containers/:containerId": PermSalesWrite,
+	"POST /api/shipments/:id/packages":             PermSalesWrite,
+	"PATCH /api/shipment-packages/:packageId":      PermSalesWrite,
+	"DELETE /api/shipment-packages/:packageId":     PermSalesWrite,
+
+	// 發票與佣金
+	"GET /api/invoices":                   PermInvoicesRead,
+	"GET /api/invoices/:id":               PermInvoicesRead,
+	"POST /api/sales-orders/:id/invoices": PermInvoicesWrite,
+	"POST /api/invoices/:id/payments":     PermInvoicesWrite,
+	"POST /api/invoices/:id/cancel":       PermInvoicesWrite,
+	"GET /api/document-sequences":         PermInvoicesRead,
+	"PUT /api/document-sequences":         PermInvoicesWrite,
+	"GET /api/sales-agents":               PermCommissionsRead,
+	"POST /api/sales-agents":              PermCommissionsWrite,
+	"PATCH /api/sales-agents/:id":         PermCommissionsWrite,
+	"DELETE /api/sales-agents/:id":        PermCommissionsWrite,
+	"GET /api/commissions":                PermCommissionsRead,
+	"GET /api/commissions/statements":     PermCommissionsRead,
+	"GET /api/commissions/summary":        PermCommissionsRead,
+	"POST /api/commissions/pay":           PermCommissionsWrite,
+
+	// 信用額度與匯率
+	"GET /api/credit-limits":                         PermCreditRead,
+	"PUT /api/credit-limits":                         PermCreditWrite,
+	"DELETE /api/credit-limits/:id":                  PermCreditWrite,
+	"GET /api/customers/:id/credit-exposure":         PermCreditRead,
+	"GET /api/credit-holds":                          PermCreditRead,
+	"POST /api/sales-orders/:id/release-credit-hold": PermCreditApprove,
+	"GET /api/exchange-rates":                        PermCreditRead,
+	"POST /api/exchange-rates":                       PermCreditWrite,
+
+	// 單據 PDF 與範本（另需來源單據的查詢權限）
+	"GET /api/document-templates":          PermDocumentsRead,
+	"PUT /api/document-templates":          PermDocumentsWrite,
+	"PUT /api/document-templates/:id/logo": PermDocumentsWrite,
+	"DELETE /api/document-templates/:id":   PermDocumentsWrite,
+	"GET /api/documents/:docType/:id":      PermDocumentsRead,
+	"POST /api/documents/:docType/:id":     PermDocumentsWrite,
+	"GET /api/rendered-documents/:id/pdf":  PermDocumentsRead,
+
+	// 倉庫與庫存
+	"GET /api/warehouses":                    PermInventoryRead,
+	"POST /api/warehouses":                   PermInventoryWrite,
+	"PATCH /api/warehouses/:id":              PermInventoryWrite,
+	"DELETE /api/warehouses/:id":             PermInventoryWrite,
+	"POST /api/warehouses/:id/bins":          PermInventoryWrite,
+	"PATCH /api/warehouse-bins/:binId":       PermInventoryWrite,
+	"DELETE /api/warehouse-bins/:binId":      PermInventoryWrite,
+	"GET /api/stock":                         PermInventoryRead,
+	"GET /api/stock/movements":               PermInventoryRead,
+	"GET /api/stock/availability":            PermInventoryRead,
+	"POST /api/stock/receipts":               PermInventoryWrite,
+	"POST /api/stock/issues":                 PermInventoryWrite,
+	"POST /api/stock/transfers":              PermInventoryWrite,
+	"POST /api/stock/adjustments":            PermInventoryAdjust,
+	"GET /api/sales-orders/:id/availability": PermInventoryRead,
+
+	// 批號與爐號追溯
+	"GET /api/material-heats":                  PermTraceRead,
+	"POST /api/material-heats":                 PermTraceWrite,
+	"PATCH /api/material-heats/:id":            PermTraceWrite,
+	"GET /api/material-heats/:id/trace":        PermTraceRead,
+	"GET /api/process-batches":                 PermTraceRead,
+	"POST /api/process-batches":                PermTraceWrite,
+	"PATCH /api/process-batches/:id":           PermTraceWrite,
+	"GET /api/process-batches/:id/trace":       PermTraceRead,
+	"GET /api/production-orders":               PermTraceRead,
+	"GET /api/production-orders/:id":           PermTraceRead,
+	"POST /api/production-orders":              PermTraceWrite,
+	"POST /api/production-orders/:id/start":    PermTraceWrite,
+	"POST /api/production-orders/:id/complete": PermTraceWrite,
+	"POST /api/production-orders/:id/cancel":   PermTraceWrite,
+	"GET /api/production-lots":                 PermTraceRead,
+	"GET /api/production-lots/:id":             PermTraceRead,
+	"POST /api/production-lots":                PermTraceWrite,
+	"PUT /api/production-lots/:id":             PermTraceWrite,
+	"GET /api/production-lots/:id/trace":       PermTraceRead,
+	"GET /api/shipments/:id/trace":             PermTraceRead,
+
+	// 材質證明與檢驗證明書（證明書 PDF 由單據 API 以 inspection_certificate 產生）
+	"GET /api/mill-certificates":                PermQualityRead,
+	"POST /api/mill-certificates":               PermQualityWrite,
+	"PUT /api/mill-certificates/:id":            PermQualityWrite,
+	"DELETE /api/mill-certificates/:id":         PermQualityWrite,
+	"PUT /api/mill-certificates/:id/file":       PermQualityWrite,
+	"GET /api/mill-certificates/:id/file":       PermQualityRead,
+	"GET /api/production-lots/:id/inspections":  PermQualityRead,
+	"POST /api/production-lots/:id/inspections": PermQualityWrite,
+	"PUT /api/inspection-results/:id":           PermQualityWrite,
+	"DELETE /api/inspection-results/:id":        PermQualityWrite,
+	"GET /api/sales-orders/:id/certificates":    PermQualityRead,
+}
+
+// HasPermission 判斷角色是否擁有指定權限，superadmin 視為擁有全部權限
+func HasPermission(roleName string, granted []string, perm string) bool {
+	if roleName == RoleSuperAdmin || perm == "" {
+		return true
+	}
+	for _, p := range granted {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
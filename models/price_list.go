@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// 客戶價格表，掛在客戶 + 公司的交易條件下，幣別沿用交易條件
+type PriceList struct {
+	ID                uint            `json:"id" gorm:"primaryKey;autoIncrement"`
+	TransactionTermID uint            `json:"transaction_term_id" gorm:"index"`
+	CustomerID        uint            `json:"customer_id" gorm:"index"`
+	CompanyID         uint            `json:"company_id" gorm:"index"`
+	Name              string          `json:"name"`
+	CurrencyCode      string          `json:"currency_code"`
+	ValidFrom         time.Time       `json:"valid_from"`
+	ValidTo           *time.Time      `json:"valid_to"` // null 代表無到期日
+	IsActive          bool            `json:"is_active"`
+	Remarks           string          `json:"remarks"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Items             []PriceListItem `json:"items" gorm:"foreignKey:PriceListID"`
+}
+
+// 價格表明細：同一產品可依起訂數量設多個級距
+type PriceListItem struct {
+	ID                     uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	PriceListID            uint    `json:"price_list_id" gorm:"uniqueIndex:idx_price_list_item"`
+	ProductSpecificationID uint    `json:"product_specification_id" gorm:"uniqueIndex:idx_price_list_item"`
+	MinQuantity            float64 `json:"min_quantity" gorm:"uniqueIndex:idx_price_list_item"`
+	UnitPrice              float64 `json:"unit_price"`
+	Unit                   string  `json:"unit"`
+}
+
+// 新增 / 修改價格表請求，明細整批取代
+type PriceListRequest struct {
+	Name      string          `json:"name"`
+	ValidFrom *time.Time      `json:"valid_from"`
+	ValidTo   *time.Time      `json:"valid_to"`
+	IsActive  *bool           `json:"is_active"`
+	Remarks   string          `json:"remarks"`
+	Items     []PriceListItem `json:"items"`
+}
+
+// 價格查詢結果
+type ResolvedPrice struct {
+	PriceListID            uint       `json:"price_list_id"`
+	PriceListName          string     `json:"price_list_name"`
+	TransactionTermID      uint       `json:"transaction_term_id"`
+	ProductSpecificationID uint       `json:"product_specification_id"`
+	Quantity               float64    `json:"quantity"`
+	MinQuantity            float64    `json:"min_quantity"`
+	UnitPrice              float64    `json:"unit_price"`
+	Unit                   string     `json:"unit"`
+	CurrencyCode           string     `json:"currency_code"`
+	ValidFrom              time.Time  `json:"valid_from"`
+	ValidTo                *time.Time `json:"valid_to"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+// 檢驗項目類型
+const (
+	InspectionHardness  = "hardness"          // 硬度
+	InspectionTensile   = "tensile"           // 抗拉強度
+	InspectionCoating   = "coating_thickness" // 鍍層膜厚
+	InspectionDimension = "dimension"         // 尺寸
+	InspectionOther     = "other"
+)
+
+// InspectionTypes 可記錄的檢驗項目類型
+var InspectionTypes = []string{InspectionHardness, InspectionTensile, InspectionCoating, InspectionDimension, InspectionOther}
+
+// 原料材質證明（鋼廠 mill certificate），依爐號保存；原始檔案另以上傳 API 存放
+type MillCertificate struct {
+	ID              uint                     `json:"id" gorm:"primaryKey;autoIncrement"`
+	MaterialHeatID  uint                     `json:"material_heat_id" gorm:"index"`
+	CertificateNo   string                   `json:"certificate_no"`
+	Standard        string                   `json:"standard"`  // 例如 EN 10204 3.1
+	IssuedBy        string                   `json:"issued_by"` // 鋼廠
+	IssuedAt        *time.Time               `json:"issued_at"`
+	Remarks         string                   `json:"remarks"`
+	File            []byte                   `json:"-"`
+	FileName        string                   `json:"file_name"`
+	FileContentType string                   `json:"file_content_type"`
+	FileSize        int                      `json:"file_size"`
+	UpdatedBy       string                   `json:"updated_by"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+	Elements        []MillCertificateElement `json:"elements" gorm:"foreignKey:MillCertificateID"`
+}
+
+// 材質證明上的化學成分 (%)
+type MillCertificateElement struct {
+	ID                uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	MillCertificateID uint    `json:"mill_certificate_id" gorm:"index"`
+	Element           string  `json:"element"` // C、Si、Mn、P、S、B 等
+	Percent           float64 `json:"percent"`
+}
+
+// 新增 / 修改材質證明請求，化學成分整批取代
+type MillCertificateRequest struct {
+	MaterialHeatID uint                 `json:"material_heat_id"`
+	CertificateNo  string               `json:"certificate_no"`
+	Standard       string               `json:"standard"`
+	IssuedBy       string               `json:"issued_by"`
+	IssuedAt       *time.Time           `json:"issued_at"`
+	Remarks        string               `json:"remarks"`
+	Elements       []ChemicalElementReq `json:"elements"`
+}
+
+type ChemicalElementReq struct {
+	Element string  `json:"element"`
+	Percent float64 `json:"percent"`
+}
+
+// 成品批號的自主檢驗結果；Passed 依規格上下限與實測最小 / 最大值判定
+type InspectionResult struct {
+	ID             uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	LotID          uint      `json:"lot_id" gorm:"index"`
+	Type           string    `json:"type"`
+	Characteristic string    `json:"characteristic"` // 例如 表面硬度、心部硬度、保證荷重
+	Method         string    `json:"method"`         // 例如 ISO 6508-1、ISO 898-1
+	Requirement    string    `json:"requirement"`    // 證明書上顯示的規格，空白時依上下限產生
+	SpecMin        *float64  `json:"spec_min"`
+	SpecMax        *float64  `json:"spec_max"`
+	Unit           string    `json:"unit"` // HRC、HV、MPa、μm 等
+	SampleSize     int       `json:"sample_size"`
+	MeasuredMin    float64   `json:"measured_min"`
+	MeasuredMax    float64   `json:"measured_max"`
+	MeasuredAvg    float64   `json:"measured_avg"`
+	Passed         bool      `json:"passed"`
+	InspectedAt    time.Time `json:"inspected_at"`
+	InspectedBy    string    `json:"inspected_by"`
+	Remarks        string    `json:"remarks"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// 新增 / 修改檢驗結果請求
+type InspectionResultRequest struct {
+	Type           string     `json:"type"`
+	Characteristic string     `json:"characteristic"`
+	Method         string     `json:"method"`
+	Requirement    string     `json:"requirement"`
+	SpecMin        *float64   `json:"spec_min"`
+	SpecMax        *float64   `json:"spec_max"`
+	Unit           string     `json:"unit"`
+	SampleSize     int        `json:"sample_size"`
+	MeasuredMin    *float64   `json:"measured_min"` // 實測值未提供時為 nil，0 為有效的實測值
+	MeasuredMax    *float64   `json:"measured_max"`
+	MeasuredAvg    *float64   `json:"measured_avg"`
+	InspectedAt    *time.Time `json:"inspected_at"`
+	Remarks        string     `json:"remarks"`
+}
+
+// 訂單明細的檢驗證明書：每筆出貨明細一份，Latest 為最新產生的版本
+type OrderLineCertificate struct {
+	SalesOrderLineID uint              `json:"sales_order_line_id"`
+	LineNo           int               `json:"line_no"`
+	ShipmentID       uint              `json:"shipment_id"`
+	ShipmentNo       string            `json:"shipment_no"`
+	ShipmentLineID   uint              `json:"shipment_line_id"` // 產生證明書時的來源 ID
+	Quantity         float64           `json:"quantity"`
+	Latest           *RenderedDocument `json:"latest"`
+}
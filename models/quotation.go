@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// 報價單狀態
+const (
+	QuotationDraft    = "draft"
+	QuotationSent     = "sent"
+	QuotationAccepted = "accepted"
+	QuotationRejected = "rejected"
+)
+
+// 報價單號使用的公司流水號單據類型
+const QuotationSequence = "quotation"
+
+// 報價單
+type Quotation struct {
+	ID                uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	QuoteNo           string `json:"quote_no" gorm:"uniqueIndex:idx_quote_no"`
+	CompanyID         uint   `json:"company_id" gorm:"index;uniqueIndex:idx_quote_no"` // 報價公司
+	CustomerID        uint   `json:"customer_id" gorm:"index"`
+	TransactionTermID *uint  `json:"transaction_term_id"`
+	TradeTerms        `gorm:"embedded"`
+	ValidUntil        *time.Time      `json:"valid_until"`
+	Status            string          `json:"status"`
+	TotalAmount       float64         `json:"total_amount"`
+	Remarks           string          `json:"remarks"`
+	CreatedBy         string          `json:"created_by"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Lines             []QuotationLine `json:"lines" gorm:"foreignKey:QuotationID"`
+}
+
+// 報價單明細
+type QuotationLine struct {
+	ID                     uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	QuotationID            uint       `json:"quotation_id" gorm:"index"`
+	LineNo                 int        `json:"line_no"`
+	ProductCategoryID      *uint      `json:"product_category_id"`
+	ProductSpecificationID *uint      `json:"product_specification_id"`
+	Description            string     `json:"description"`
+	Quantity               float64    `json:"quantity"`
+	Unit                   string     `json:"unit"`
+	UnitPrice              float64    `json:"unit_price"`
+	Amount                 float64    `json:"amount"`
+	DeliveryDate           *time.Time `json:"delivery_date"`
+}
+
+// 新增 / 修改報價單請求
+type QuotationRequest struct {
+	CompanyID         uint  `json:"company_id"`
+	CustomerID        uint  `json:"customer_id"`
+	TransactionTermID *uint `json:"transaction_term_id"`
+	TradeTermsRequest
+	ValidUntil *time.Time         `json:"valid_until"`
+	Remarks    string             `json:"remarks"`
+	Lines      []SalesLineRequest `json:"lines"`
+}
+
+// 報價單狀態變更請求
+type QuotationStatusRequest struct {
+	Status string `json:"status"`
+}
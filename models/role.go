@@ -1,8 +1,31 @@
 package models
 
 type Role struct {
-    ID   uint   `json:"id" gorm:"primaryKey"`
-    Name string `json:"name" gorm:"unique"` // <-- 在這裡添加 gorm:"unique"
-    // 你有 permissions 欄位的話也可以加
-    Permissions []string `json:"permissions" gorm:"type:jsonb"`
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"unique"` // <-- 在這裡添加 gorm:"unique"
+	// 所屬公司；null 代表全域角色，否則僅該公司及其下層公司可見
+	CompanyID *uint `json:"company_id"`
+	// 權限清單以 JSON 陣列存放於 jsonb 欄位
+	Permissions []string `json:"permissions" gorm:"type:jsonb;serializer:json"`
+	// 此角色的帳號是否必須啟用雙因素驗證
+	RequireTwoFactor bool `json:"require_two_factor"`
+	// 內建角色已套用的預設權限版本，見 db.Seed
+	SeedVersion int `json:"-"`
+}
+
+// 系統內建角色，不可改名或刪除
+const (
+	RoleSuperAdmin   = "superadmin"
+	RoleCompanyAdmin = "company_admin"
+)
+
+// IsSystemRole 判斷是否為系統內建角色
+func IsSystemRole(name string) bool {
+	return name == RoleSuperAdmin || name == RoleCompanyAdmin
+}
+
+// 複製角色請求
+type CloneRoleRequest struct {
+	Name      string `json:"name"`
+	CompanyID *uint  `json:"company_id"`
 }
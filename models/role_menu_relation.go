@@ -1,10 +1,35 @@
 package models
 
+import "time"
+
 // RoleMenuRelation 代表角色與菜單的關聯
 type RoleMenuRelation struct {
-    RoleID uint `gorm:"primaryKey"` // 角色 ID，作為複合主鍵的一部分
-    MenuID uint `gorm:"primaryKey"` // 菜單 ID，作為複合主鍵的一部分
-    // 您可以根據需要在此處添加其他字段，例如：
-    // IsActive  bool `gorm:"default:true"` // 表示此關聯是否啟用
-    // OrderNo   int  // 如果菜單在特定角色下有顯示順序
+	RoleID uint `gorm:"primaryKey" json:"role_id"` // 角色 ID，作為複合主鍵的一部分
+	MenuID uint `gorm:"primaryKey" json:"menu_id"` // 菜單 ID，作為複合主鍵的一部分
+	// 以下為角色層級的覆寫設定
+	IsActive bool `gorm:"default:true" json:"is_active"` // 此角色是否顯示該菜單
+	OrderNo  *int `json:"order_no"`                      // 此角色下的顯示順序，null 代表沿用菜單預設
+}
+
+// RoleMenuChangeLog 記錄每次角色選單授權的異動
+type RoleMenuChangeLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	RoleID    uint      `json:"role_id" gorm:"index"`
+	ChangedBy string    `json:"changed_by"`
+	Added     []uint    `json:"added" gorm:"type:jsonb;serializer:json"`
+	Removed   []uint    `json:"removed" gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 批次更新角色選單請求
+type UpdateRoleMenusRequest struct {
+	MenuIDs []uint `json:"menu_ids"`
+}
+
+// 角色選單授權結果
+type RoleMenuAssignment struct {
+	RoleID  uint   `json:"role_id"`
+	MenuIDs []uint `json:"menu_ids"`
+	Added   []uint `json:"added"`
+	Removed []uint `json:"removed"`
 }
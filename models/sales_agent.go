@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// 業務 / 代理商，佣金依客戶交易條件指派的業務計算
+type SalesAgent struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID uint      `json:"company_id" gorm:"index"`
+	Name      string    `json:"name"`
+	UserID    *uint     `json:"user_id"` // 公司內部業務對應的帳號；外部代理商為 null
+	Email     string    `json:"email"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
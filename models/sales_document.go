@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// TradeTerms 銷售單據表頭的交易條件，建立時由客戶交易條件帶入
+type TradeTerms struct {
+	Incoterm           string  `json:"incoterm"`
+	CurrencyCode       string  `json:"currency_code"`
+	CommissionRate     float64 `json:"commission_rate"`
+	AgentID            *uint   `json:"agent_id"`
+	ExportPort         string  `json:"export_port"`
+	DestinationCountry string  `json:"destination_country"`
+	SoldToSiteID       *uint   `json:"sold_to_site_id"`
+	ShipToSiteID       *uint   `json:"ship_to_site_id"`
+	BillToSiteID       *uint   `json:"bill_to_site_id"`
+}
+
+// TradeTermsRequest 覆寫帶入的交易條件，未提供的欄位沿用客戶交易條件
+type TradeTermsRequest struct {
+	Incoterm           *string  `json:"incoterm"`
+	CurrencyCode       *string  `json:"currency_code"`
+	CommissionRate     *float64 `json:"commission_rate"`
+	AgentID            *uint    `json:"agent_id"`
+	ExportPort         *string  `json:"export_port"`
+	DestinationCountry *string  `json:"destination_country"`
+	SoldToSiteID       *uint    `json:"sold_to_site_id"`
+	ShipToSiteID       *uint    `json:"ship_to_site_id"`
+	BillToSiteID       *uint    `json:"bill_to_site_id"`
+}
+
+// SalesLineRequest 報價單 / 訂單明細；未提供 unit_price 時依客戶價格表查價
+type SalesLineRequest struct {
+	ProductCategoryID      *uint      `json:"product_category_id"`
+	ProductSpecificationID *uint      `json:"product_specification_id"`
+	Description            string     `json:"description"`
+	Quantity               float64    `json:"quantity"`
+	Unit                   string     `json:"unit"`
+	UnitPrice              *float64   `json:"unit_price"`
+	DeliveryDate           *time.Time `json:"delivery_date"`
+}
@@ -0,0 +1,137 @@
+package models
+
+import "time"
+
+// 訂單狀態：draft → confirmed → partially_shipped → shipped → closed
+// 確認時超出信用額度轉為 credit_hold，核准解除後才成為 confirmed
+// draft / credit_hold / confirmed 且尚未出貨時可取消；部分出貨後可結案（短交）
+const (
+	OrderDraft            = "draft"
+	OrderCreditHold       = "credit_hold"
+	OrderConfirmed        = "confirmed"
+	OrderPartiallyShipped = "partially_shipped"
+	OrderShipped          = "shipped"
+	OrderClosed           = "closed"
+	OrderCancelled        = "cancelled"
+)
+
+// 訂單與出貨單號使用的公司流水號單據類型
+const (
+	SalesOrderSequence = "sales_order"
+	ShipmentSequence   = "shipment"
+)
+
+// 銷售訂單
+type SalesOrder struct {
+	ID                uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	OrderNo           string    `json:"order_no" gorm:"uniqueIndex:idx_sales_order_no"`
+	CompanyID         uint      `json:"company_id" gorm:"index;uniqueIndex:idx_sales_order_no"` // 接單公司
+	CustomerID        uint      `json:"customer_id" gorm:"index"`
+	TransactionTermID *uint     `json:"transaction_term_id"`
+	QuotationID       *uint     `json:"quotation_id" gorm:"uniqueIndex"` // 一張報價單只能轉一張訂單
+	CustomerPONo      string    `json:"customer_po_no" gorm:"column:customer_po_no"`
+	OrderDate         time.Time `json:"order_date"`
+	TradeTerms        `gorm:"embedded"`
+	Status            string               `json:"status"`
+	TotalAmount       float64              `json:"total_amount"`
+	Remarks           string               `json:"remarks"`
+	ConfirmedAt       *time.Time           `json:"confirmed_at"`
+	ConfirmedBy       string               `json:"confirmed_by"`
+	CreatedBy         string               `json:"created_by"`
+	CreatedAt         time.Time            `json:"created_at"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+	Lines             []SalesOrderLine     `json:"lines" gorm:"foreignKey:SalesOrderID"`
+	Shipments         []SalesOrderShipment `json:"shipments,omitempty" gorm:"foreignKey:SalesOrderID"`
+}
+
+// 訂單明細
+type SalesOrderLine struct {
+	ID                     uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	SalesOrderID           uint       `json:"sales_order_id" gorm:"index"`
+	LineNo                 int        `json:"line_no"`
+	ProductCategoryID      *uint      `json:"product_category_id"`
+	ProductSpecificationID *uint      `json:"product_specification_id"`
+	Description            string     `json:"description"`
+	Quantity               float64    `json:"quantity"`
+	Unit                   string     `json:"unit"`
+	UnitPrice              float64    `json:"unit_price"`
+	Amount                 float64    `json:"amount"`
+	DeliveryDate           *time.Time `json:"delivery_date"`
+	ShippedQuantity        float64    `json:"shipped_quantity"`
+}
+
+// RemainingQuantity 尚未出貨數量
+func (l SalesOrderLine) RemainingQuantity() float64 {
+	return l.Quantity - l.ShippedQuantity
+}
+
+// 訂單出貨（可分批）；外銷出貨另記錄裝船資訊、貨櫃與包裝
+type SalesOrderShipment struct {
+	ID              uint                     `json:"id" gorm:"primaryKey;autoIncrement"`
+	SalesOrderID    uint                     `json:"sales_order_id" gorm:"index"`
+	CompanyID       uint                     `json:"company_id" gorm:"uniqueIndex:idx_shipment_no"` // 同訂單的接單公司，單號依公司編號
+	ShipmentNo      string                   `json:"shipment_no" gorm:"uniqueIndex:idx_shipment_no"`
+	ShippedAt       time.Time                `json:"shipped_at"`
+	PortOfLoading   string                   `json:"port_of_loading"` // 預設為訂單出口港
+	PortOfDischarge string                   `json:"port_of_discharge"`
+	Vessel          string                   `json:"vessel"`
+	VoyageNo        string                   `json:"voyage_no"`
+	ETD             *time.Time               `json:"etd" gorm:"column:etd;index"`
+	ETA             *time.Time               `json:"eta" gorm:"column:eta;index"`
+	ShippingMark    string                   `json:"shipping_mark"` // 嘜頭範本，預設由客戶交易條件帶入
+	Remarks         string                   `json:"remarks"`
+	CreatedBy       string                   `json:"created_by"`
+	CreatedAt       time.Time                `json:"created_at"`
+	Lines           []SalesOrderShipmentLine `json:"lines" gorm:"foreignKey:ShipmentID"`
+	Containers      []ShipmentContainer      `json:"containers,omitempty" gorm:"foreignKey:ShipmentID"`
+	Packages        []ShipmentPackage        `json:"packages,omitempty" gorm:"foreignKey:ShipmentID"`
+}
+
+// 出貨明細
+type SalesOrderShipmentLine struct {
+	ID               uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ShipmentID       uint    `json:"shipment_id" gorm:"index"`
+	SalesOrderLineID uint    `json:"sales_order_line_id" gorm:"index"`
+	Quantity         float64 `json:"quantity"`
+}
+
+// 新增 / 修改訂單請求；指定 quotation_id 且未提供明細時，由已接受的報價單帶入
+type SalesOrderRequest struct {
+	CompanyID         uint       `json:"company_id"`
+	CustomerID        uint       `json:"customer_id"`
+	TransactionTermID *uint      `json:"transaction_term_id"`
+	QuotationID       *uint      `json:"quotation_id"`
+	CustomerPONo      string     `json:"customer_po_no"`
+	OrderDate         *time.Time `json:"order_date"`
+	TradeTermsRequest
+	Remarks string             `json:"remarks"`
+	Lines   []SalesLineRequest `json:"lines"`
+}
+
+// 出貨請求；未提供的裝船港與嘜頭沿用訂單出口港與客戶交易條件
+type ShipmentRequest struct {
+	ShippedAt       *time.Time            `json:"shipped_at"`
+	PortOfLoading   string                `json:"port_of_loading"`
+	PortOfDischarge string                `json:"port_of_discharge"`
+	Vessel          string                `json:"vessel"`
+	VoyageNo        string                `json:"voyage_no"`
+	ETD             *time.Time            `json:"etd"`
+	ETA             *time.Time            `json:"eta"`
+	ShippingMark    string                `json:"shipping_mark"`
+	Remarks         string                `json:"remarks"`
+	Lines           []ShipmentLineRequest `json:"lines"`
+}
+
+type ShipmentLineRequest struct {
+	SalesOrderLineID uint    `json:"sales_order_line_id"`
+	Quantity         float64 `json:"quantity"`
+}
+
+// 訂單確認書內容
+type OrderConfirmation struct {
+	Order       SalesOrder `json:"order"`
+	Seller      Company    `json:"seller"`
+	Customer    Customer   `json:"customer"`
+	Language    string     `json:"language"`
+	GeneratedAt time.Time  `json:"generated_at"`
+}
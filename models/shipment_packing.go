@@ -0,0 +1,99 @@
+package models
+
+import "time"
+
+// 包裝類型：紙箱可放在棧板上；棧板與未上棧板的紙箱可指定貨櫃
+const (
+	PackageCarton = "carton"
+	PackagePallet = "pallet"
+)
+
+// 出貨貨櫃
+type ShipmentContainer struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ShipmentID    uint      `json:"shipment_id" gorm:"index"`
+	ContainerNo   string    `json:"container_no"`
+	SealNo        string    `json:"seal_no"`
+	ContainerType string    `json:"container_type"` // 20GP / 40GP / 40HQ 等
+	Remarks       string    `json:"remarks"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// 出貨包裝（紙箱 / 棧板）
+type ShipmentPackage struct {
+	ID            uint                  `json:"id" gorm:"primaryKey;autoIncrement"`
+	ShipmentID    uint                  `json:"shipment_id" gorm:"index"`
+	Type          string                `json:"type"`
+	PackageNo     int                   `json:"package_no"` // 同一出貨內依類型連續編號，即嘜頭的 C/NO.
+	PalletID      *uint                 `json:"pallet_id" gorm:"index"`
+	ContainerID   *uint                 `json:"container_id" gorm:"index"`
+	LengthCM      float64               `json:"length_cm"`
+	WidthCM       float64               `json:"width_cm"`
+	HeightCM      float64               `json:"height_cm"`
+	NetWeightKG   float64               `json:"net_weight_kg"`   // 紙箱：內容物重量；棧板：上方紙箱加總
+	GrossWeightKG float64               `json:"gross_weight_kg"` // 紙箱：含箱重量；棧板：棧板自重 + 上方紙箱毛重
+	TareWeightKG  float64               `json:"tare_weight_kg"`  // 棧板自重
+	Remarks       string                `json:"remarks"`
+	CreatedAt     time.Time             `json:"created_at"`
+	Contents      []ShipmentPackageLine `json:"contents,omitempty" gorm:"foreignKey:PackageID"`
+}
+
+// CBM 材積（立方公尺）
+func (p ShipmentPackage) CBM() float64 {
+	return p.LengthCM * p.WidthCM * p.HeightCM / 1e6
+}
+
+// 紙箱內容
+type ShipmentPackageLine struct {
+	ID               uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	PackageID        uint    `json:"package_id" gorm:"index"`
+	SalesOrderLineID uint    `json:"sales_order_line_id" gorm:"index"`
+	Quantity         float64 `json:"quantity"`
+}
+
+// 新增包裝請求；紙箱可一次建立 count 個相同內容的箱子，contents 為每箱內容
+type PackageRequest struct {
+	Type          string                `json:"type"`
+	Count         int                   `json:"count"`
+	PalletID      *uint                 `json:"pallet_id"`
+	ContainerID   *uint                 `json:"container_id"`
+	LengthCM      float64               `json:"length_cm"`
+	WidthCM       float64               `json:"width_cm"`
+	HeightCM      float64               `json:"height_cm"`
+	NetWeightKG   float64               `json:"net_weight_kg"`
+	GrossWeightKG float64               `json:"gross_weight_kg"`
+	TareWeightKG  float64               `json:"tare_weight_kg"`
+	Remarks       string                `json:"remarks"`
+	Contents      []ShipmentLineRequest `json:"contents"`
+}
+
+// 裝箱單彙總
+type PackingList struct {
+	Shipment      SalesOrderShipment `json:"shipment"`
+	OrderNo       string             `json:"order_no"`
+	CustomerPONo  string             `json:"customer_po_no"`
+	Cartons       int                `json:"cartons"`
+	Pallets       int                `json:"pallets"`
+	NetWeightKG   float64            `json:"net_weight_kg"`
+	GrossWeightKG float64            `json:"gross_weight_kg"`
+	CBM           float64            `json:"cbm"`
+	Lines         []PackingListLine  `json:"lines"`
+	Complete      bool               `json:"complete"` // 出貨數量皆已裝箱
+}
+
+// 裝箱單明細：各訂單明細的出貨與已裝箱數量
+type PackingListLine struct {
+	SalesOrderLineID uint    `json:"sales_order_line_id"`
+	LineNo           int     `json:"line_no"`
+	Description      string  `json:"description"`
+	Unit             string  `json:"unit"`
+	ShippedQuantity  float64 `json:"shipped_quantity"`
+	PackedQuantity   float64 `json:"packed_quantity"`
+}
+
+// 單一紙箱的嘜頭
+type ShippingMark struct {
+	PackageID uint     `json:"package_id"`
+	PackageNo int      `json:"package_no"`
+	Lines     []string `json:"lines"`
+}
@@ -0,0 +1,178 @@
+package models
+
+import "time"
+
+// 製令狀態
+const (
+	ProductionPlanned    = "planned"
+	ProductionInProgress = "in_progress"
+	ProductionCompleted  = "completed"
+	ProductionCancelled  = "cancelled"
+)
+
+// 製令單號使用的公司流水號單據類型
+const ProductionOrderSequence = "production_order"
+
+// 製程批次類型
+const (
+	BatchHeatTreatment = "heat_treatment" // 熱處理
+	BatchPlating       = "plating"        // 電鍍 / 表面處理
+)
+
+// ProcessBatchTypes 可建立的製程批次類型
+var ProcessBatchTypes = []string{BatchHeatTreatment, BatchPlating}
+
+// 原料爐號（盤元 heat number），同一公司內爐號不可重複
+type MaterialHeat struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID     uint       `json:"company_id" gorm:"uniqueIndex:idx_material_heat"`
+	HeatNo        string     `json:"heat_no" gorm:"uniqueIndex:idx_material_heat"`
+	MaterialGrade string     `json:"material_grade"` // 鋼種，例如 SWCH10A、10B21
+	Supplier      string     `json:"supplier"`       // 鋼廠 / 供應商
+	DiameterMM    float64    `json:"diameter_mm"`
+	WeightKG      float64    `json:"weight_kg"`
+	ReceivedAt    *time.Time `json:"received_at"`
+	Remarks       string     `json:"remarks"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// 製令；Materials 記錄投入的原料爐號
+type ProductionOrder struct {
+	ID                     uint                      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID              uint                      `json:"company_id" gorm:"index;uniqueIndex:idx_production_order_no"`
+	OrderNo                string                    `json:"order_no" gorm:"uniqueIndex:idx_production_order_no"`
+	ProductSpecificationID uint                      `json:"product_specification_id" gorm:"index"`
+	SalesOrderLineID       *uint                     `json:"sales_order_line_id" gorm:"index"` // 依訂單生產時對應的訂單明細
+	Quantity               float64                   `json:"quantity"`
+	Unit                   string                    `json:"unit"`
+	Status                 string                    `json:"status"`
+	StartedAt              *time.Time                `json:"started_at"`
+	CompletedAt            *time.Time                `json:"completed_at"`
+	Remarks                string                    `json:"remarks"`
+	CreatedBy              string                    `json:"created_by"`
+	CreatedAt              time.Time                 `json:"created_at"`
+	UpdatedAt              time.Time                 `json:"updated_at"`
+	Materials              []ProductionOrderMaterial `json:"materials" gorm:"foreignKey:ProductionOrderID"`
+}
+
+// 製令投料
+type ProductionOrderMaterial struct {
+	ID                uint    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ProductionOrderID uint    `json:"production_order_id" gorm:"index"`
+	MaterialHeatID    uint    `json:"material_heat_id" gorm:"index"`
+	QuantityKG        float64 `json:"quantity_kg"`
+}
+
+// 製程批次（熱處理爐次、電鍍批次），外包時記錄加工廠
+type ProcessBatch struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID   uint       `json:"company_id" gorm:"uniqueIndex:idx_process_batch"`
+	Type        string     `json:"type" gorm:"uniqueIndex:idx_process_batch"`
+	BatchNo     string     `json:"batch_no" gorm:"uniqueIndex:idx_process_batch"`
+	Vendor      string     `json:"vendor"`     // 外包加工廠，自製時留空
+	Process     string     `json:"process"`    // 例如 調質、滲碳、鍍鋅、達克銹
+	Parameters  string     `json:"parameters"` // 溫度、時間、硬度、膜厚等
+	ProcessedAt *time.Time `json:"processed_at"`
+	Remarks     string     `json:"remarks"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// 成品批號；LotNo 與庫存批號相同，出貨追溯依出庫異動的 shipment_id
+type ProductionLot struct {
+	ID                     uint                 `json:"id" gorm:"primaryKey;autoIncrement"`
+	CompanyID              uint                 `json:"company_id" gorm:"uniqueIndex:idx_production_lot"`
+	LotNo                  string               `json:"lot_no" gorm:"uniqueIndex:idx_production_lot"`
+	ProductSpecificationID uint                 `json:"product_specification_id" gorm:"index"`
+	ProductionOrderID      *uint                `json:"production_order_id" gorm:"index"`
+	Quantity               float64              `json:"quantity"`
+	Unit                   string               `json:"unit"`
+	ProducedAt             *time.Time           `json:"produced_at"`
+	Remarks                string               `json:"remarks"`
+	CreatedBy              string               `json:"created_by"`
+	CreatedAt              time.Time            `json:"created_at"`
+	Heats                  []ProductionLotHeat  `json:"heats" gorm:"foreignKey:LotID"`
+	Batches                []ProductionLotBatch `json:"batches" gorm:"foreignKey:LotID"`
+}
+
+// 成品批號使用的原料爐號
+type ProductionLotHeat struct {
+	ID             uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	LotID          uint `json:"lot_id" gorm:"index"`
+	MaterialHeatID uint `json:"material_heat_id" gorm:"index"`
+}
+
+// 成品批號經過的製程批次
+type ProductionLotBatch struct {
+	ID             uint `json:"id" gorm:"primaryKey;autoIncrement"`
+	LotID          uint `json:"lot_id" gorm:"index"`
+	ProcessBatchID uint `json:"process_batch_id" gorm:"index"`
+}
+
+// 新增製令請求
+type ProductionOrderRequest struct {
+	CompanyID              uint                        `json:"company_id"`
+	ProductSpecificationID uint                        `json:"product_specification_id"`
+	SalesOrderLineID       *uint                       `json:"sales_order_line_id"`
+	Quantity               float64                     `json:"quantity"`
+	Unit                   string                      `json:"unit"`
+	Remarks                string                      `json:"remarks"`
+	Materials              []ProductionMaterialRequest `json:"materials"`
+}
+
+type ProductionMaterialRequest struct {
+	MaterialHeatID uint    `json:"material_heat_id"`
+	QuantityKG     float64 `json:"quantity_kg"`
+}
+
+// 新增 / 修改成品批號請求；未提供 material_heat_ids 時由製令投料帶入
+type ProductionLotRequest struct {
+	CompanyID              uint       `json:"company_id"`
+	LotNo                  string     `json:"lot_no"`
+	ProductSpecificationID uint       `json:"product_specification_id"`
+	ProductionOrderID      *uint      `json:"production_order_id"`
+	Quantity               float64    `json:"quantity"`
+	Unit                   string     `json:"unit"`
+	ProducedAt             *time.Time `json:"produced_at"`
+	Remarks                string     `json:"remarks"`
+	MaterialHeatIDs        []uint     `json:"material_heat_ids"`
+	ProcessBatchIDs        []uint     `json:"process_batch_ids"`
+}
+
+// 追溯結果中的出貨（依出庫異動彙總）
+type TraceShipment struct {
+	ShipmentID   uint      `json:"shipment_id"`
+	ShipmentNo   string    `json:"shipment_no"`
+	ShippedAt    time.Time `json:"shipped_at"`
+	SalesOrderID uint      `json:"sales_order_id"`
+	OrderNo      string    `json:"order_no"`
+	CustomerPONo string    `json:"customer_po_no"`
+	CustomerID   uint      `json:"customer_id"`
+	CustomerName string    `json:"customer_name"`
+	LotNo        string    `json:"lot_no"`
+	Quantity     float64   `json:"quantity"`
+	Unit         string    `json:"unit"`
+}
+
+// 成品批號的完整追溯：往前到製令、爐號與製程批次，往後到出貨
+type LotTrace struct {
+	Lot             ProductionLot    `json:"lot"`
+	ProductionOrder *ProductionOrder `json:"production_order"`
+	Heats           []MaterialHeat   `json:"heats"`
+	Batches         []ProcessBatch   `json:"batches"`
+	Shipments       []TraceShipment  `json:"shipments"`
+}
+
+// 順向追溯（爐號 / 製程批次 → 成品批號 → 出貨），供客訴或召回時圈定影響範圍
+type ForwardTrace struct {
+	MaterialHeat *MaterialHeat   `json:"material_heat,omitempty"`
+	ProcessBatch *ProcessBatch   `json:"process_batch,omitempty"`
+	Lots         []ProductionLot `json:"lots"`
+	Shipments    []TraceShipment `json:"shipments"`
+}
+
+// 出貨的逆向追溯
+type ShipmentTrace struct {
+	ShipmentID uint       `json:"shipment_id"`
+	ShipmentNo string     `json:"shipment_no"`
+	Lots       []LotTrace `json:"lots"`
+}
@@ -1,15 +1,18 @@
 package routes
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"fastener-api/jwtkeys"
 	"fastener-api/models"
+	"fastener-api/twofactor"
 	"log"
 	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -23,11 +26,35 @@ type LoginRequest struct {
 type Claims struct {
 	Username  string `json:"username"`
 	Role      string `json:"role"`
+	RoleID    uint   `json:"role_id"`
 	CompanyID uint   `json:"company_id"`
+	// 非空白代表是登入流程中的暫時 Token（例如雙因素驗證挑戰），不可用來呼叫 API
+	Purpose string `json:"purpose,omitempty"`
 	jwt.RegisteredClaims
 }
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
+// 雙因素驗證挑戰 Token 的用途
+const (
+	purposeTwoFactor       = "2fa"        // 已啟用雙因素驗證，需輸入驗證碼
+	purposeTwoFactorEnroll = "2fa_enroll" // 角色要求雙因素驗證但尚未設定，需先完成設定
+)
+
+// challengeTTL 挑戰 Token 的有效時間
+const challengeTTL = 5 * time.Minute
+
+// maxChallengeAttempts 每個挑戰 Token 可使用的次數，超過後需重新以密碼登入；
+// 次數記錄在各台 API 的記憶體中，跨機器的暴力嘗試由帳號鎖定限制
+const maxChallengeAttempts = 5
+
+var (
+	challengeMu       sync.Mutex
+	challengeAttempts = map[string]challengeUse{}
+)
+
+type challengeUse struct {
+	count   int
+	expires time.Time
+}
 
 // LoginHandler 處理登入邏輯 (GORM ORM)
 func LoginHandler(db *gorm.DB) gin.HandlerFunc {
@@ -56,42 +83,356 @@ func LoginHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// 查詢角色名稱（role name）
-		var roleName string
-		db.Raw("SELECT name FROM roles WHERE id = ?", user.RoleID).Scan(&roleName)
+		if user.IsLocked(time.Now()) {
+			log.Printf("⚠️ 登入失敗: 帳號鎖定中 - %s", req.Username)
+			c.JSON(http.StatusForbidden, gin.H{"error": "登入失敗次數過多，帳號暫時鎖定，請稍後再試或聯絡管理員"})
+			return
+		}
 
 		// 驗證密碼 (用 PasswordHash)
-		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		if !models.CheckPassword(user.PasswordHash, req.Password) {
 			log.Printf("⚠️ 登入失敗: 密碼錯誤 - %s", req.Username)
+			recordFailedLogin(db, user)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "帳號或密碼錯誤"})
 			return
 		}
+		// 失敗次數在完成登入（含雙因素驗證）後才歸零，見 loginResponse
+
+		// 強制 SSO 時，已綁定 SSO 的帳號只能透過 SSO 登入，本機密碼僅保留給緊急帳號
+		if user.OIDCSubject != nil && oidcEnforced() {
+			log.Printf("⚠️ 登入失敗: 帳號已綁定 SSO，不可使用密碼登入 - %s", req.Username)
+			c.JSON(http.StatusForbidden, gin.H{"error": "此帳號請使用 SSO 登入"})
+			return
+		}
+
+		// 需要雙因素驗證時先回傳挑戰 Token，待第二步驟完成後才簽發正式 Token
+		if challenge, ok, err := twoFactorChallenge(db, user); err != nil {
+			log.Printf("❌ 無法產生 Token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 Token"})
+			return
+		} else if ok {
+			log.Printf("🔐 密碼驗證成功，等待雙因素驗證 - 使用者: %s", req.Username)
+			c.JSON(http.StatusOK, challenge)
+			return
+		}
+
+		resp, err := loginResponse(db, user)
+		if err != nil {
+			log.Printf("❌ 無法產生 Token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 Token"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// twoFactorChallenge 帳號已啟用雙因素驗證，或登入後 Token 使用的角色要求雙因素驗證時，
+// 回傳含挑戰 Token 的回應；ok 為 false 代表可直接簽發正式 Token
+func twoFactorChallenge(db *gorm.DB, user models.User) (resp gin.H, ok bool, err error) {
+	_, roleID, _ := loginRole(db, user)
+	if !user.TOTPEnabled && !twofactor.RoleRequired(db, roleID) {
+		return nil, false, nil
+	}
+	purpose := purposeTwoFactor
+	if !user.TOTPEnabled {
+		purpose = purposeTwoFactorEnroll
+	}
+	challenge, err := issueChallengeToken(user.Username, purpose)
+	if err != nil {
+		return nil, false, err
+	}
+	return gin.H{
+		"two_factor_required": true,
+		"enrollment_required": !user.TOTPEnabled,
+		"challenge_token":     challenge,
+	}, true, nil
+}
+
+// recordFailedLogin 累計登入失敗次數，達上限時鎖定帳號
+func recordFailedLogin(db *gorm.DB, user models.User) {
+	count := user.FailedLoginCount + 1
+	updates := map[string]interface{}{"failed_login_count": count}
+	if count >= models.MaxFailedLogins {
+		updates["failed_login_count"] = 0
+		updates["locked_until"] = time.Now().Add(models.LockoutDuration)
+		log.Printf("🔒 登入失敗 %d 次，帳號已鎖定 - %s", count, user.Username)
+	}
+	db.Model(&user).Updates(updates)
+}
+
+// TwoFactorLoginHandler 登入第二步驟：以挑戰 Token + 驗證碼（或復原碼）換取正式 Token
+func TwoFactorLoginHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+			return
+		}
+		user, ok := challengeUser(c, db, req.ChallengeToken, purposeTwoFactor)
+		if !ok {
+			return
+		}
+		if user.IsLocked(time.Now()) {
+			log.Printf("⚠️ 雙因素驗證失敗: 帳號鎖定中 - %s", user.Username)
+			c.JSON(http.StatusForbidden, gin.H{"error": "登入失敗次數過多，帳號暫時鎖定，請稍後再試或聯絡管理員"})
+			return
+		}
+		if !twofactor.Verify(db, &user, req.Code, req.RecoveryCode) {
+			log.Printf("⚠️ 雙因素驗證失敗 - %s", user.Username)
+			recordFailedLogin(db, user)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "驗證碼錯誤"})
+			return
+		}
+
+		resp, err := loginResponse(db, user)
+		if err != nil {
+			log.Printf("❌ 無法產生 Token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 Token"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// TwoFactorEnrollHandler 角色要求但尚未設定雙因素驗證時，以挑戰 Token 取得 TOTP 金鑰
+func TwoFactorEnrollHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+			return
+		}
+		user, ok := challengeUser(c, db, req.ChallengeToken, purposeTwoFactorEnroll)
+		if !ok {
+			return
+		}
+		enrollment, err := twofactor.Enroll(db, &user)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, enrollment)
+	}
+}
+
+// TwoFactorActivateHandler 以驗證碼完成設定，回傳正式 Token 與一次性復原碼
+func TwoFactorActivateHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.TwoFactorLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+			return
+		}
+		user, ok := challengeUser(c, db, req.ChallengeToken, purposeTwoFactorEnroll)
+		if !ok {
+			return
+		}
+		codes, err := twofactor.Activate(db, &user, req.Code)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		resp, err := loginResponse(db, user)
+		if err != nil {
+			log.Printf("❌ 無法產生 Token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 Token"})
+			return
+		}
+		log.Printf("✅ 已啟用雙因素驗證 - 使用者: %s", user.Username)
+		resp["recovery_codes"] = codes
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// loginRole 登入後 Token 使用的公司與角色：有設定預設 membership 時直接進入該公司，
+// 否則為帳號的主要公司與角色
+func loginRole(db *gorm.DB, user models.User) (companyID, roleID uint, roleName string) {
+	companyID, roleID = user.CompanyID, user.RoleID
+	var membership models.UserCompanyMembership
+	if err := db.Where("user_id = ? AND is_default = true", user.ID).First(&membership).Error; err == nil {
+		companyID, roleID = membership.CompanyID, membership.RoleID
+	}
+	db.Raw("SELECT name FROM roles WHERE id = ?", roleID).Scan(&roleName)
+	return companyID, roleID, roleName
+}
+
+// loginResponse 簽發正式 Token 並組成登入回應，同時將登入失敗次數歸零
+func loginResponse(db *gorm.DB, user models.User) (gin.H, error) {
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		db.Model(&user).Updates(map[string]interface{}{"failed_login_count": 0, "locked_until": nil})
+	}
+	companyID, roleID, roleName := loginRole(db, user)
+
+	tokenStr, err := issueToken(user.Username, roleName, roleID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ 登入成功，已產生 Token - 使用者: %s, 角色: %s, 公司: %d", user.Username, roleName, companyID)
+	return gin.H{
+		"token":      tokenStr,
+		"role":       roleName,
+		"company_id": companyID,
+	}, nil
+}
+
+// challengeUser 驗證挑戰 Token 並取得對應的使用者，失敗時已回應錯誤
+func challengeUser(c *gin.Context, db *gorm.DB, tokenStr, purpose string) (models.User, bool) {
+	var user models.User
+	claims := &Claims{}
+	opts := append(jwtkeys.ParserOptions(), jwt.WithAudience(jwtkeys.AudienceLoginChallenge))
+	token, err := jwt.ParseWithClaims(tokenStr, claims, jwtkeys.Keyfunc, opts...)
+	if err != nil || !token.Valid || claims.Purpose != purpose || claims.ID == "" || claims.ExpiresAt == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "驗證已逾時，請重新登入"})
+		return user, false
+	}
+	if !useChallenge(claims.ID, claims.ExpiresAt.Time) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "嘗試次數過多，請重新登入"})
+		return user, false
+	}
+	if err := db.Where("username = ? AND is_active = true", claims.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "帳號或密碼錯誤"})
+		return user, false
+	}
+	return user, true
+}
 
-		now := time.Now()
-		expiration := now.Add(24 * time.Hour)
-		claims := &Claims{
-			Username:  req.Username,
-			Role:      roleName,
-			CompanyID: user.CompanyID,
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(expiration),
-				IssuedAt:  jwt.NewNumericDate(now),
-				NotBefore: jwt.NewNumericDate(now),
-			},
+// useChallenge 記錄挑戰 Token 的使用次數，超過 maxChallengeAttempts 時回傳 false
+func useChallenge(id string, expires time.Time) bool {
+	challengeMu.Lock()
+	defer challengeMu.Unlock()
+	now := time.Now()
+	for k, u := range challengeAttempts {
+		if now.After(u.expires) {
+			delete(challengeAttempts, k)
 		}
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenStr, err := token.SignedString(jwtKey)
+	}
+	u := challengeAttempts[id]
+	if u.count >= maxChallengeAttempts {
+		return false
+	}
+	challengeAttempts[id] = challengeUse{count: u.count + 1, expires: expires}
+	return true
+}
+
+// issueChallengeToken 簽發登入流程用的短效挑戰 Token，jti 用來限制嘗試次數
+func issueChallengeToken(username, purpose string) (string, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := &Claims{
+		Username: username,
+		Purpose:  purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Audience:  jwt.ClaimStrings{jwtkeys.AudienceLoginChallenge},
+			ExpiresAt: jwt.NewNumericDate(now.Add(challengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	return jwtkeys.Sign(claims)
+}
+
+// randomID 產生 URL-safe 的隨機字串，作為 Token 的 jti
+func randomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// SwitchCompanyHandler 切換目前使用的公司，重新簽發以該公司與對應角色為準的 Token
+// 可切換的公司為帳號的主要公司，以及 user_company_memberships 中的公司；
+// 切換後的角色要求雙因素驗證時，帳號需已啟用雙因素驗證並提供驗證碼
+func SwitchCompanyHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.SwitchCompanyRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.CompanyID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的請求格式"})
+			return
+		}
+
+		username := c.GetString("username")
+		var user models.User
+		if err := db.Where("username = ? AND is_active = true", username).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "找不到登入的使用者"})
+			return
+		}
+
+		roleID := user.RoleID
+		if req.CompanyID != user.CompanyID {
+			var membership models.UserCompanyMembership
+			if err := db.Where("user_id = ? AND company_id = ?", user.ID, req.CompanyID).First(&membership).Error; err != nil {
+				log.Printf("⚠️ 切換公司失敗: %s 無權存取公司 %d", username, req.CompanyID)
+				c.JSON(http.StatusForbidden, gin.H{"error": "無權存取此公司"})
+				return
+			}
+			roleID = membership.RoleID
+		}
+
+		if twofactor.RoleRequired(db, roleID) {
+			if !user.TOTPEnabled {
+				c.JSON(http.StatusForbidden, gin.H{"error": "此公司的角色必須啟用雙因素驗證，請先完成設定"})
+				return
+			}
+			if user.IsLocked(time.Now()) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "登入失敗次數過多，帳號暫時鎖定，請稍後再試或聯絡管理員"})
+				return
+			}
+			if !twofactor.Verify(db, &user, req.Code, "") {
+				log.Printf("⚠️ 切換公司失敗: 雙因素驗證失敗 - %s", username)
+				recordFailedLogin(db, user)
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "驗證碼錯誤", "two_factor_required": true})
+				return
+			}
+		}
+
+		var roleName string
+		db.Raw("SELECT name FROM roles WHERE id = ?", roleID).Scan(&roleName)
+
+		tokenStr, err := issueToken(username, roleName, roleID, req.CompanyID)
 		if err != nil {
 			log.Printf("❌ 無法產生 Token: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 Token"})
 			return
 		}
 
-		log.Printf("✅ 登入成功，已產生 Token - 使用者: %s, 角色: %s, 公司: %d", req.Username, roleName, user.CompanyID)
+		log.Printf("✅ 切換公司成功 - 使用者: %s, 角色: %s, 公司: %d", username, roleName, req.CompanyID)
 		c.JSON(http.StatusOK, gin.H{
 			"token":      tokenStr,
 			"role":       roleName,
-			"company_id": user.CompanyID,
+			"company_id": req.CompanyID,
 		})
 	}
 }
+
+// issueToken 簽發 24 小時有效的 JWT
+func issueToken(username, roleName string, roleID, companyID uint) (string, error) {
+	now := time.Now()
+	expiration := now.Add(24 * time.Hour)
+	claims := &Claims{
+		Username:  username,
+		Role:      roleName,
+		RoleID:    roleID,
+		CompanyID: companyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiration),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+	return jwtkeys.Sign(claims)
+}
+
+// JWKSHandler 公開目前可驗證 Token 的公鑰 (/.well-known/jwks.json)，供其他內部服務驗證
+func JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtkeys.PublicJWKS())
+	}
+}
@@ -0,0 +1,264 @@
+package routes
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"fastener-api/jwtkeys"
+	"fastener-api/models"
+	"fastener-api/oidc"
+)
+
+// oidcStateCookie 保存 state / nonce / PKCE verifier 的 Cookie 名稱
+const oidcStateCookie = "oidc_state"
+
+// oidcStateTTL 使用者在 IdP 登入頁停留的最長時間
+const oidcStateTTL = 10 * time.Minute
+
+// purposeOIDCState 僅用於 SSO 流程的暫時 Token，不可用來呼叫 API
+const purposeOIDCState = "oidc_state"
+
+// oidcStateClaims 以我們的金鑰簽章後放在 Cookie 中，多台 API 之間不需共享 session
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *oidc.Provider
+)
+
+// getOIDCProvider 第一次使用時才讀取 IdP discovery，IdP 暫時無法連線不影響 API 啟動
+func getOIDCProvider(ctx context.Context) (*oidc.Provider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	cfg, ok := oidc.ConfigFromEnv()
+	if !ok {
+		return nil, errOIDCDisabled
+	}
+	p, err := oidc.NewProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider = p
+	return p, nil
+}
+
+type oidcError string
+
+func (e oidcError) Error() string { return string(e) }
+
+const errOIDCDisabled = oidcError("未啟用 SSO 登入")
+
+// oidcEnforced 是否限制已綁定 SSO 的帳號只能透過 SSO 登入
+func oidcEnforced() bool {
+	cfg, ok := oidc.ConfigFromEnv()
+	return ok && cfg.Enforce
+}
+
+// OIDCLoginHandler 開始 SSO 登入：產生 state / nonce / PKCE verifier 後導向 IdP
+func OIDCLoginHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := getOIDCProvider(c.Request.Context())
+		if err != nil {
+			log.Printf("❌ SSO 無法使用: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SSO 登入目前無法使用"})
+			return
+		}
+
+		state, err1 := oidc.RandomString()
+		nonce, err2 := oidc.RandomString()
+		verifier, err3 := oidc.RandomString()
+		if err1 != nil || err2 != nil || err3 != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 SSO 參數"})
+			return
+		}
+		now := time.Now()
+		cookie, err := jwtkeys.Sign(&oidcStateClaims{
+			State:    state,
+			Nonce:    nonce,
+			Verifier: verifier,
+			Purpose:  purposeOIDCState,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Audience:  jwt.ClaimStrings{jwtkeys.AudienceOIDCState},
+				ExpiresAt: jwt.NewNumericDate(now.Add(oidcStateTTL)),
+				IssuedAt:  jwt.NewNumericDate(now),
+			},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "無法產生 SSO 參數"})
+			return
+		}
+		secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcStateCookie, cookie, int(oidcStateTTL.Seconds()), "/api/auth/oidc", "", secure, true)
+
+		c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, verifier))
+	}
+}
+
+// OIDCCallbackHandler IdP 登入完成後的回呼：驗證 state、以授權碼換取 ID Token、對應帳號並簽發我們的 Token。
+// 雙因素驗證一律由本系統執行，不採信 IdP 的 amr/acr（各 IdP 的值不一致，且無法確認是否與角色要求相符）：
+// 帳號已啟用或角色要求雙因素驗證時，與密碼登入相同回傳挑戰 Token，由前端接續 /api/login/2fa 流程
+// 設定 FRONTEND_URL 時導回前端 /sso-callback，Token 放在 URL fragment 避免進入伺服器紀錄
+func OIDCCallbackHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := getOIDCProvider(c.Request.Context())
+		if err != nil {
+			oidcFail(c, http.StatusServiceUnavailable, "SSO 登入目前無法使用", err)
+			return
+		}
+		if idpErr := c.Query("error"); idpErr != "" {
+			oidcFail(c, http.StatusUnauthorized, "SSO 登入已取消或失敗", oidcError(idpErr))
+			return
+		}
+
+		raw, err := c.Cookie(oidcStateCookie)
+		c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", false, true)
+		if err != nil {
+			oidcFail(c, http.StatusBadRequest, "SSO 登入已逾時，請重新登入", err)
+			return
+		}
+		state := &oidcStateClaims{}
+		opts := append(jwtkeys.ParserOptions(), jwt.WithAudience(jwtkeys.AudienceOIDCState))
+		token, err := jwt.ParseWithClaims(raw, state, jwtkeys.Keyfunc, opts...)
+		if err != nil || !token.Valid || state.Purpose != purposeOIDCState || state.State != c.Query("state") {
+			oidcFail(c, http.StatusBadRequest, "SSO 登入已逾時，請重新登入", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+		defer cancel()
+		idToken, err := provider.Exchange(ctx, c.Query("code"), state.Verifier, state.Nonce)
+		if err != nil {
+			oidcFail(c, http.StatusUnauthorized, "SSO 驗證失敗", err)
+			return
+		}
+
+		user, err := findOrProvisionOIDCUser(db, provider.Config(), idToken)
+		if err != nil {
+			oidcFail(c, http.StatusForbidden, err.Error(), err)
+			return
+		}
+
+		frontend := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+		challenge, needTwoFactor, err := twoFactorChallenge(db, user)
+		if err != nil {
+			oidcFail(c, http.StatusInternalServerError, "無法產生 Token", err)
+			return
+		}
+		if needTwoFactor {
+			log.Printf("🔐 SSO 驗證成功，等待雙因素驗證 - 使用者: %s, subject: %s", user.Username, idToken.Subject)
+			if frontend == "" {
+				c.JSON(http.StatusOK, challenge)
+				return
+			}
+			fragment := url.Values{}
+			fragment.Set("two_factor_required", "true")
+			fragment.Set("enrollment_required", strconv.FormatBool(challenge["enrollment_required"].(bool)))
+			fragment.Set("challenge_token", challenge["challenge_token"].(string))
+			c.Redirect(http.StatusFound, frontend+"/sso-callback#"+fragment.Encode())
+			return
+		}
+
+		resp, err := loginResponse(db, user)
+		if err != nil {
+			oidcFail(c, http.StatusInternalServerError, "無法產生 Token", err)
+			return
+		}
+		log.Printf("✅ SSO 登入成功 - 使用者: %s, subject: %s", user.Username, idToken.Subject)
+
+		if frontend == "" {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+		fragment := url.Values{}
+		fragment.Set("token", resp["token"].(string))
+		fragment.Set("role", resp["role"].(string))
+		fragment.Set("company_id", strconv.Itoa(int(resp["company_id"].(uint))))
+		c.Redirect(http.StatusFound, frontend+"/sso-callback#"+fragment.Encode())
+	}
+}
+
+// findOrProvisionOIDCUser 依序以 subject、已驗證的 email 對應帳號；都找不到時依設定自動建立帳號
+func findOrProvisionOIDCUser(db *gorm.DB, cfg oidc.Config, idToken *oidc.IDTokenClaims) (models.User, error) {
+	var user models.User
+	if err := db.Where("oidc_subject = ?", idToken.Subject).First(&user).Error; err == nil {
+		if !user.IsActive {
+			return user, oidcError("帳號未啟用")
+		}
+		return user, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(idToken.Email))
+	if email != "" && (idToken.EmailVerified || cfg.TrustEmail) {
+		err := db.Where("LOWER(email) = ? AND oidc_subject IS NULL", email).First(&user).Error
+		if err == nil {
+			if !user.IsActive {
+				return user, oidcError("帳號未啟用")
+			}
+			// 第一次以 SSO 登入，綁定 subject
+			if err := db.Model(&user).Update("oidc_subject", idToken.Subject).Error; err != nil {
+				return user, err
+			}
+			log.Printf("🔗 已將帳號 %s 綁定 SSO subject %s", user.Username, idToken.Subject)
+			return user, nil
+		}
+	}
+
+	if !cfg.JITEnabled() || email == "" {
+		return user, oidcError("找不到對應的帳號，請聯絡管理員")
+	}
+	var roleID uint
+	db.Raw("SELECT id FROM roles WHERE name = ?", cfg.JITRole).Scan(&roleID)
+	if roleID == 0 {
+		return user, oidcError("SSO 自動建立帳號的角色設定錯誤")
+	}
+	subject := idToken.Subject
+	user = models.User{
+		Username:     email,
+		PasswordHash: "", // 不可用密碼登入
+		RoleID:       roleID,
+		CompanyID:    cfg.JITCompanyID,
+		IsActive:     true,
+		DisplayName:  idToken.Name,
+		Email:        email,
+		OIDCSubject:  &subject,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return user, err
+	}
+	log.Printf("🆕 SSO 自動建立帳號 %s（公司 %d，角色 %s）", email, cfg.JITCompanyID, cfg.JITRole)
+	return user, nil
+}
+
+// oidcFail 記錄錯誤並回應；設定 FRONTEND_URL 時導回前端顯示錯誤
+func oidcFail(c *gin.Context, status int, message string, err error) {
+	log.Printf("⚠️ SSO 登入失敗: %s: %v", message, err)
+	frontend := strings.TrimRight(os.Getenv("FRONTEND_URL"), "/")
+	if frontend == "" {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+	fragment := url.Values{}
+	fragment.Set("error", message)
+	c.Redirect(http.StatusFound, frontend+"/sso-callback#"+fragment.Encode())
+}
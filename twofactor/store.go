@@ -0,0 +1,190 @@
+package twofactor
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"fastener-api/models"
+)
+
+// Issuer 顯示在驗證器 App 中的服務名稱
+const Issuer = "Fastener"
+
+// RecoveryCodeCount 每次產生的復原碼數量
+const RecoveryCodeCount = 10
+
+var (
+	ErrAlreadyEnabled = errors.New("已啟用雙因素驗證")
+	ErrNotEnrolled    = errors.New("尚未開始設定雙因素驗證")
+	ErrInvalidCode    = errors.New("驗證碼錯誤")
+)
+
+// Required 判斷使用者的主要角色或任一公司成員資格的角色是否要求雙因素驗證
+func Required(db *gorm.DB, user models.User) bool {
+	var count int64
+	db.Model(&models.Role{}).
+		Where("require_two_factor = true AND (id = ? OR id IN (?))", user.RoleID,
+			db.Model(&models.UserCompanyMembership{}).Select("role_id").Where("user_id = ?", user.ID)).
+		Count(&count)
+	return count > 0
+}
+
+// RoleRequired 判斷角色是否要求雙因素驗證；簽發 Token 前應以 Token 中的角色檢查
+func RoleRequired(db *gorm.DB, roleID uint) bool {
+	var required bool
+	db.Raw("SELECT require_two_factor FROM roles WHERE id = ?", roleID).Scan(&required)
+	return required
+}
+
+// Enroll 為使用者產生新的 TOTP 金鑰（尚未啟用），已啟用者需先停用
+func Enroll(db *gorm.DB, user *models.User) (models.TwoFactorEnrollment, error) {
+	if user.TOTPEnabled {
+		return models.TwoFactorEnrollment{}, ErrAlreadyEnabled
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	if err := db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":       secret,
+		"totp_last_counter": 0,
+	}).Error; err != nil {
+		return models.TwoFactorEnrollment{}, err
+	}
+	user.TOTPSecret = secret
+	return models.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: ProvisioningURI(Issuer, user.Username, secret),
+	}, nil
+}
+
+// Activate 以驗證器 App 的驗證碼確認設定並啟用，回傳新的復原碼（明文僅此一次）
+func Activate(db *gorm.DB, user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrNotEnrolled
+	}
+	counter, ok := Validate(user.TOTPSecret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_enabled":      true,
+			"totp_last_counter": counter,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastCounter = counter
+	return codes, nil
+}
+
+// Verify 以 TOTP 驗證碼或一次性復原碼驗證已啟用雙因素驗證的使用者
+func Verify(db *gorm.DB, user *models.User, code, recoveryCode string) bool {
+	if !user.TOTPEnabled {
+		return false
+	}
+	if code != "" {
+		counter, ok := Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastCounter)
+		if !ok {
+			return false
+		}
+		// 以條件更新確保同一組驗證碼不會被併發請求重複使用
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_counter < ?", user.ID, counter).
+			Update("totp_last_counter", counter)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return false
+		}
+		user.TOTPLastCounter = counter
+		return true
+	}
+	if recoveryCode == "" {
+		return false
+	}
+	var codes []models.UserRecoveryCode
+	db.Where("user_id = ? AND used_at IS NULL", user.ID).Find(&codes)
+	rc, ok := matchRecoveryCode(codes, recoveryCode)
+	if !ok {
+		return false
+	}
+	result := db.Model(&models.UserRecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", rc.ID).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// matchRecoveryCode 找出與輸入相符且尚未使用的復原碼
+func matchRecoveryCode(codes []models.UserRecoveryCode, recoveryCode string) (models.UserRecoveryCode, bool) {
+	normalized := []byte(NormalizeRecoveryCode(recoveryCode))
+	for _, rc := range codes {
+		if rc.UsedAt != nil {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(rc.CodeHash), normalized) == nil {
+			return rc, true
+		}
+	}
+	return models.UserRecoveryCode{}, false
+}
+
+// RegenerateRecoveryCodes 作廢舊的復原碼並產生新的一組
+func RegenerateRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable 停用雙因素驗證並清除金鑰與復原碼
+func Disable(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":       "",
+			"totp_enabled":      false,
+			"totp_last_counter": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	codes, err := GenerateRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]models.UserRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, models.UserRecoveryCode{UserID: userID, CodeHash: string(hash)})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,134 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"fastener-api/models"
+)
+
+// RFC 6238 附錄 B 的 SHA1 測試金鑰 "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidate(t *testing.T) {
+	at := time.Unix(1111111109, 0) // RFC 6238：驗證碼 07081804，取後 6 碼
+	counter := at.Unix() / period
+
+	tests := []struct {
+		name        string
+		code        string
+		t           time.Time
+		lastCounter int64
+		wantOK      bool
+		wantCounter int64
+	}{
+		{"目前區間", "081804", at, 0, true, counter},
+		{"前後空白", " 081804 ", at, 0, true, counter},
+		{"前一個區間內仍有效", "081804", at.Add(period * time.Second), 0, true, counter},
+		{"後一個區間內仍有效", "081804", at.Add(-period * time.Second), 0, true, counter},
+		{"超出容許區間", "081804", at.Add(2 * period * time.Second), 0, false, 0},
+		{"同一區間重放", "081804", at, counter, false, 0},
+		{"較早的區間重放", "081804", at.Add(period * time.Second), counter, false, 0},
+		{"上次使用更早的區間", "081804", at, counter - 1, true, counter},
+		{"驗證碼錯誤", "081805", at, 0, false, 0},
+		{"長度不符", "07081804", at, 0, false, 0},
+		{"空白", "", at, 0, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(rfcSecret, tt.code, tt.t, tt.lastCounter)
+			if ok != tt.wantOK || got != tt.wantCounter {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", got, ok, tt.wantCounter, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	if _, ok := Validate("not base32!", "081804", time.Unix(1111111109, 0), 0); ok {
+		t.Error("Validate() accepted an invalid secret")
+	}
+}
+
+func TestGenerateRFCVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := generate(key, tt.unix/period); got != tt.want {
+			t.Errorf("generate(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || code != NormalizeRecoveryCode(code) {
+			t.Errorf("unexpected recovery code format %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestMatchRecoveryCode(t *testing.T) {
+	hash := func(code string) string {
+		h, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(h)
+	}
+	used := time.Now()
+	codes := []models.UserRecoveryCode{
+		{ID: 1, CodeHash: hash("abcde-fghij")},
+		{ID: 2, CodeHash: hash("klmno-pqrst"), UsedAt: &used},
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		wantID uint
+		wantOK bool
+	}{
+		{"未使用的復原碼", "abcde-fghij", 1, true},
+		{"忽略大小寫與空白", "  ABCDE-FGHIJ ", 1, true},
+		{"已使用的復原碼不可再用", "klmno-pqrst", 0, false},
+		{"不存在的復原碼", "zzzzz-zzzzz", 0, false},
+		{"空白", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc, ok := matchRecoveryCode(codes, tt.input)
+			if ok != tt.wantOK || rc.ID != tt.wantID {
+				t.Errorf("matchRecoveryCode(%q) = (%d, %v), want (%d, %v)", tt.input, rc.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	if got := NormalizeRecoveryCode(" AbCdE-12345\n"); got != "abcde-12345" {
+		t.Errorf("NormalizeRecoveryCode() = %q", got)
+	}
+}